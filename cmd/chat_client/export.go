@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatconsumers/recorder"
+)
+
+// exportFormats are the values accepted by the --format flag of the export command.
+var exportFormats = []string{"text", "csv", "json"}
+
+// exportCommand converts recordings to a readable or a spreadsheet friendly format.
+func exportCommand(args []string) int {
+	flags := newFlagSet("export")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chat_client export [flags] <recording>...")
+		flags.PrintDefaults()
+	}
+	format := flags.String("format", "text", "output format, "+strings.Join(exportFormats, ", "))
+	output := flags.String("output", "", "file to write (default the standard output)")
+	flags.Parse(args)
+
+	if !slices.Contains(exportFormats, *format) {
+		fmt.Fprintf(os.Stderr, "Unknown format %q, use %s\n", *format, strings.Join(exportFormats, ", "))
+		return 2
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error creating output:", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+
+	buffered := bufio.NewWriter(w)
+	err := export(buffered, *format, flags.Args())
+	if flushErr := buffered.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error exporting:", err)
+		return 1
+	}
+	return 0
+}
+
+// export writes the records of the recordings in the given format, in the order of the files.
+func export(w io.Writer, format string, paths []string) error {
+	var write func(record recorder.Record) error
+	finish := func() error { return nil }
+
+	switch format {
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"time", "provider", "channel", "type", "author", "content"}); err != nil {
+			return err
+		}
+		write = func(record recorder.Record) error {
+			return writer.Write(csvRow(record))
+		}
+		finish = func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+	case "json":
+		if _, err := fmt.Fprint(w, "["); err != nil {
+			return err
+		}
+		separator := "\n"
+		write = func(record recorder.Record) error {
+			line, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Fprintf(w, "%s%s", separator, line)
+			separator = ",\n"
+			return err
+		}
+		finish = func() error {
+			_, err := fmt.Fprint(w, "\n]\n")
+			return err
+		}
+	default:
+		write = func(record recorder.Record) error {
+			_, err := fmt.Fprintln(w, textLine(record))
+			return err
+		}
+	}
+
+	for _, path := range paths {
+		if err := recorder.ReadFile(path, write); err != nil {
+			return err
+		}
+	}
+	return finish()
+}
+
+// textLine formats a record like the console consumer, prefixed with its time.
+func textLine(record recorder.Record) string {
+	at := record.Time().Local().Format(time.DateTime)
+	switch {
+	case record.Message != nil:
+		return fmt.Sprintf("%s [%s] %s: %s", at, record.Message.Provider, record.Message.AuthorName, record.Message.Content)
+	case record.Event != nil:
+		return fmt.Sprintf("%s [%s] * %s", at, record.Event.Provider, record.Event.Describe())
+	case record.Moderation != nil:
+		return fmt.Sprintf("%s [%s] - %s", at, record.Moderation.Provider, record.Moderation.Describe())
+	default:
+		return at
+	}
+}
+
+// csvRow returns the time, provider, channel, type, author and content columns of a record.
+// The type is "message" for chat messages, the event kind or the moderation action otherwise.
+func csvRow(record recorder.Record) []string {
+	at := record.Time().Format(time.RFC3339Nano)
+	switch {
+	case record.Message != nil:
+		m := record.Message
+		return []string{at, m.Provider, m.Channel, "message", m.AuthorName, m.Content}
+	case record.Event != nil:
+		e := record.Event
+		return []string{at, e.Provider, e.Channel, string(e.Kind), e.AuthorName, e.Describe()}
+	case record.Moderation != nil:
+		e := record.Moderation
+		return []string{at, e.Provider, e.Channel, string(e.Action), e.AuthorName, e.Describe()}
+	default:
+		return []string{at, "", "", "", "", ""}
+	}
+}
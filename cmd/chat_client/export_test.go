@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const recording = `{"ID":"msg-1","Provider":"Twitch","Channel":"streamer","Timestamp":"2025-03-20T18:30:15Z","Content":"hello, chat","AuthorName":"Viewer"}
+{"Event":{"ID":"raid-1","Provider":"Twitch","Timestamp":"2025-03-20T18:30:16Z","Kind":"raid","AuthorName":"Raider","Viewers":42}}
+{"Moderation":{"Provider":"Twitch","Timestamp":"2025-03-20T18:30:17Z","Action":"ban","AuthorName":"Troll"}}
+`
+
+func writeRecording(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "chat.jsonl")
+	assert.NoError(t, os.WriteFile(path, []byte(recording), 0o644))
+	return path
+}
+
+func TestExport_CSV(t *testing.T) {
+	var output bytes.Buffer
+	assert.NoError(t, export(&output, "csv", []string{writeRecording(t)}))
+
+	assert.Equal(t, strings.Join([]string{
+		"time,provider,channel,type,author,content",
+		`2025-03-20T18:30:15Z,Twitch,streamer,message,Viewer,"hello, chat"`,
+		"2025-03-20T18:30:16Z,Twitch,,raid,Raider,Raider is raiding with 42 viewers",
+		"2025-03-20T18:30:17Z,Twitch,,ban,Troll,Troll was banned",
+	}, "\n")+"\n", output.String())
+}
+
+func TestExport_Text(t *testing.T) {
+	var output bytes.Buffer
+	assert.NoError(t, export(&output, "text", []string{writeRecording(t)}))
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasSuffix(lines[0], "[Twitch] Viewer: hello, chat"))
+	assert.True(t, strings.HasSuffix(lines[1], "[Twitch] * Raider is raiding with 42 viewers"))
+	assert.True(t, strings.HasSuffix(lines[2], "[Twitch] - Troll was banned"))
+}
+
+func TestExport_JSON(t *testing.T) {
+	var output bytes.Buffer
+	assert.NoError(t, export(&output, "json", []string{writeRecording(t)}))
+
+	assert.True(t, strings.HasPrefix(output.String(), "[\n{"))
+	assert.Contains(t, output.String(), `{"Event":{"ID":"raid-1"`)
+	assert.True(t, strings.HasSuffix(output.String(), "}\n]\n"))
+}
+
+func TestExport_MissingFile(t *testing.T) {
+	var output bytes.Buffer
+	assert.Error(t, export(&output, "text", []string{filepath.Join(t.TempDir(), "missing.jsonl")}))
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/config"
+)
+
+// consumerNames are the values accepted by the --consumer flag.
+var consumerNames = []string{"console", "simplepage", "recorder"}
+
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("chat_client "+name, flag.ExitOnError)
+}
+
+// configFlags are the flags of the commands that load the configuration. They override the
+// values of the configuration file and of the environment.
+type configFlags struct {
+	path           string
+	twitchChannel  string
+	youtubeChannel string
+	port           int
+	// consumers are the only consumers enabled when set
+	consumers []string
+	// override applies the options of the command after the flags, when set
+	override func(cfg *config.Config)
+}
+
+func addConfigFlags(flags *flag.FlagSet) *configFlags {
+	f := &configFlags{}
+	flags.StringVar(&f.path, "config", "", "path to the YAML configuration file (default "+config.DefaultPath+" when it exists)")
+	flags.StringVar(&f.twitchChannel, "twitch-channel", "", "channel of the first Twitch provider, which is added when there is none")
+	flags.StringVar(&f.youtubeChannel, "youtube-channel", "", "channel ID of the first Youtube provider, which is added when there is none")
+	flags.IntVar(&f.port, "port", 0, "port of the web server")
+	flags.Func("consumer", "consumer to enable, "+strings.Join(consumerNames, " or ")+", repeat it to enable several (only these are enabled)", func(value string) error {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if !slices.Contains(consumerNames, value) {
+			return fmt.Errorf("unknown consumer %q, use %s", value, strings.Join(consumerNames, " or "))
+		}
+		f.consumers = append(f.consumers, value)
+		return nil
+	})
+	return f
+}
+
+// load reads the configuration and applies the flags, the result is not validated.
+func (f *configFlags) load() (*config.Config, error) {
+	cfg, err := config.Load(f.path)
+	if err != nil {
+		return nil, err
+	}
+	f.apply(cfg)
+	return cfg, nil
+}
+
+func (f *configFlags) apply(cfg *config.Config) {
+	if f.twitchChannel != "" {
+		mainProvider(cfg, "twitch", "Twitch", "Tw").Twitch.Channel = f.twitchChannel
+	}
+	if f.youtubeChannel != "" {
+		mainProvider(cfg, "youtube", "Youtube", "Yt").Youtube.ChannelId = f.youtubeChannel
+	}
+	if f.port != 0 {
+		cfg.Server.Port = f.port
+	}
+	if len(f.consumers) > 0 {
+		cfg.Consumers.Console.Enabled = slices.Contains(f.consumers, "console")
+		cfg.Consumers.SimplePage.Enabled = slices.Contains(f.consumers, "simplepage")
+		cfg.Consumers.Recorder.Enabled = slices.Contains(f.consumers, "recorder")
+	}
+	if f.override != nil {
+		f.override(cfg)
+	}
+}
+
+// mainProvider returns the first provider of the type, adding it when there is none.
+func mainProvider(cfg *config.Config, providerType, name, shortName string) *config.ProviderConfig {
+	index := slices.IndexFunc(cfg.Providers, func(p config.ProviderConfig) bool { return strings.EqualFold(p.Type, providerType) })
+	if index < 0 {
+		cfg.Providers = append(cfg.Providers, config.ProviderConfig{Type: providerType, Name: name, ShortName: shortName})
+		index = len(cfg.Providers) - 1
+	}
+	return &cfg.Providers[index]
+}
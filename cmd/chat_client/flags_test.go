@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func parseConfigFlags(args ...string) (*configFlags, error) {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	configFlags := addConfigFlags(flags)
+	return configFlags, flags.Parse(args)
+}
+
+func TestConfigFlags_Apply(t *testing.T) {
+	configFlags, err := parseConfigFlags("--twitch-channel", "raider", "--youtube-channel", "UC123", "--port", "9090", "--consumer", "console")
+	assert.NoError(t, err)
+
+	cfg := &config.Config{
+		Providers: []config.ProviderConfig{
+			{Type: "twitch", Name: "Main", Twitch: config.TwitchConfig{Channel: "streamer"}},
+			{Type: "twitch", Name: "Partner", Twitch: config.TwitchConfig{Channel: "partner"}},
+		},
+		Consumers: config.ConsumersConfig{SimplePage: config.SimplePageConfig{Enabled: true}},
+	}
+	configFlags.apply(cfg)
+
+	assert.Equal(t, "raider", cfg.Providers[0].Twitch.Channel)
+	assert.Equal(t, "partner", cfg.Providers[1].Twitch.Channel)
+	assert.Equal(t, config.ProviderConfig{Type: "youtube", Name: "Youtube", ShortName: "Yt", Youtube: config.YoutubeConfig{ChannelId: "UC123"}}, cfg.Providers[2])
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.True(t, cfg.Consumers.Console.Enabled)
+	assert.False(t, cfg.Consumers.SimplePage.Enabled)
+}
+
+func TestConfigFlags_Unset(t *testing.T) {
+	configFlags, err := parseConfigFlags()
+	assert.NoError(t, err)
+
+	cfg := config.Default()
+	cfg.Consumers.SimplePage.Enabled = true
+	configFlags.apply(cfg)
+
+	assert.Empty(t, cfg.Providers)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.True(t, cfg.Consumers.SimplePage.Enabled)
+}
+
+func TestConfigFlags_UnknownConsumer(t *testing.T) {
+	_, err := parseConfigFlags("--consumer", "printer")
+	assert.ErrorContains(t, err, `unknown consumer "printer", use console or simplepage or recorder`)
+}
+
+func TestDispatch_UnknownCommand(t *testing.T) {
+	assert.Equal(t, 2, dispatch([]string{"stream"}))
+}
+
+func TestConfigFlags_Override(t *testing.T) {
+	configFlags, err := parseConfigFlags("--consumer", "recorder")
+	assert.NoError(t, err)
+	configFlags.override = func(cfg *config.Config) { cfg.Consumers.Recorder.Directory = "chat-logs" }
+
+	cfg := config.Default()
+	cfg.Consumers.Console.Enabled = true
+	configFlags.apply(cfg)
+
+	assert.False(t, cfg.Consumers.Console.Enabled)
+	assert.True(t, cfg.Consumers.Recorder.Enabled)
+	assert.Equal(t, "chat-logs", cfg.Consumers.Recorder.Directory)
+}
@@ -2,83 +2,106 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-
-	"github.com/SergioCurto/ChatClient/config"
-	"github.com/SergioCurto/ChatClient/internal/aggregator"
-	"github.com/SergioCurto/ChatClient/internal/chatconsumers"
-	"github.com/SergioCurto/ChatClient/internal/chatproviders"
+	"runtime/debug"
+	"strings"
 )
 
-func main() {
-	fmt.Println("Chat client application started.")
+// version is set when building a release with -ldflags "-X main.version=v1.2.3"
+var version = "dev"
 
-	// Get Config
-	cfg := config.GetConfig()
+// command is a subcommand of the command line, run returns the exit code.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) int
+}
 
-	agg := aggregator.NewAggregator(cfg)
+func commands() []command {
+	return []command{
+		{name: "run", description: "Connect to the chat providers and forward the chat to the consumers (default)", run: runCommand},
+		{name: "record", description: "Record the chat to JSON Lines files", run: recordCommand},
+		{name: "replay", description: "Play a recording to the consumers, to try them offline", run: replayCommand},
+		{name: "export", description: "Convert recordings to text, CSV or JSON", run: exportCommand},
+		{name: "validate", description: "Check the configuration and report every problem", run: validateCommand},
+		{name: "version", description: "Print the version", run: versionCommand},
+	}
+}
 
-	// Create and add providers configured
-	chatProviderFactory := chatproviders.NewConcreteChatProviderFactory()
+func main() {
+	os.Exit(dispatch(os.Args[1:]))
+}
 
-	if cfg.ConnectTwitch {
-		fmt.Println("Creating and enabling Twitch chat provider")
-		twitchProvider, err := chatProviderFactory.CreateProvider(chatproviders.Twitch)
-		if err != nil {
-			log.Fatal("Error creating Twitch provider: ", err)
-		}
-		agg.AddProvider(twitchProvider)
+// dispatch runs the command named by the first argument. Without a command, or when the
+// arguments start with a flag, the run command is used so existing scripts keep working.
+func dispatch(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return runCommand(args)
 	}
 
-	if cfg.ConnectYoutube {
-		fmt.Println("Creating and enabling Youtube chat provider")
-		youtubeProvider, err := chatProviderFactory.CreateProvider(chatproviders.Youtube)
-		if err != nil {
-			log.Fatal("Error creating Youtube provider: ", err)
-		}
-		agg.AddProvider(youtubeProvider)
+	name, args := args[0], args[1:]
+	if name == "help" {
+		usage(os.Stdout)
+		return 0
 	}
-
-	// Create and add consumers configured
-	consumerFactory := chatconsumers.NewConcreteChatConsumerFactory()
-
-	if cfg.ChatOutput {
-		fmt.Println("Creating and enabling Console consumer")
-
-		consumer, err := consumerFactory.CreateConsumer(chatconsumers.Console)
-		if err != nil {
-			log.Fatal("Error creating Console consumer: ", err)
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd.run(args)
 		}
-		agg.AddConsumer(consumer)
 	}
 
-	if cfg.WebpageOutput {
-		fmt.Println("Creating and enabling SimplePage consumer")
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return 2
+}
 
-		consumer, err := consumerFactory.CreateConsumer(chatconsumers.SimplePage)
-		if err != nil {
-			log.Fatal("Error creating SimplePage consumer: ", err)
-		}
-		agg.AddConsumer(consumer)
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: chat_client <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.description)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "chat_client <command> -h" to list the flags of a command.`)
+}
 
-	// Start the aggregator
-	err := agg.Start()
+// validateCommand checks the configuration and reports every problem.
+func validateCommand(args []string) int {
+	flags := newFlagSet("validate")
+	configFlags := addConfigFlags(flags)
+	flags.Parse(args)
 
+	cfg, err := configFlags.load()
+	if err == nil {
+		err = cfg.Validate()
+	}
 	if err != nil {
-		log.Fatal("Error starting aggregator: ", err)
+		fmt.Fprintln(os.Stderr, "Invalid configuration:")
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintln(os.Stderr, "  -", line)
+		}
+		return 1
 	}
 
-	// Handle CTRL+C to gracefully shutdown
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	fmt.Println("Configuration is valid.")
+	return 0
+}
 
-	<-sigs
-	fmt.Println("Shutting down...")
-	agg.Stop()
+// versionCommand prints the version, with the commit when the binary was built from a repository.
+func versionCommand(args []string) int {
+	newFlagSet("version").Parse(args)
 
-	fmt.Println("Chat aggregation ended.")
+	details := []string{}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				details = append(details, "commit "+setting.Value)
+			}
+		}
+		details = append(details, info.GoVersion)
+	}
+	fmt.Printf("chat_client %s (%s)\n", version, strings.Join(details, ", "))
+	return 0
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/aggregator"
+)
+
+// runCommand connects the providers and forwards the chat to the consumers until interrupted.
+func runCommand(args []string) int {
+	flags := newFlagSet("run")
+	configFlags := addConfigFlags(flags)
+	flags.Parse(args)
+
+	return runChat(configFlags)
+}
+
+// recordCommand runs the chat client recording the chat, only the recorder is enabled unless
+// other consumers are selected with --consumer.
+func recordCommand(args []string) int {
+	flags := newFlagSet("record")
+	configFlags := addConfigFlags(flags)
+	directory := flags.String("dir", "", "directory of the recordings (default consumers.recorder.directory)")
+	compress := flags.Bool("compress", false, "write gzip compressed recordings")
+	flags.Parse(args)
+
+	configFlags.override = func(cfg *config.Config) {
+		cfg.Consumers.Recorder.Enabled = true
+		if *directory != "" {
+			cfg.Consumers.Recorder.Directory = *directory
+		}
+		if *compress {
+			cfg.Consumers.Recorder.Compress = true
+		}
+	}
+	if len(configFlags.consumers) == 0 {
+		configFlags.consumers = []string{"recorder"}
+	}
+
+	return runChat(configFlags)
+}
+
+// replayCommand plays a recording to the configured consumers instead of connecting to the
+// configured providers, to try the consumers offline.
+func replayCommand(args []string) int {
+	flags := newFlagSet("replay")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: chat_client replay [flags] <recording>")
+		flags.PrintDefaults()
+	}
+	configFlags := addConfigFlags(flags)
+	speed := flags.Float64("speed", 1, "playback speed, 2 plays twice as fast")
+	loop := flags.Bool("loop", false, "play the recording again once it ends")
+	start := flags.Duration("start", 0, "skip the beginning of the recording (e.g. 10m)")
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	replay := config.ReplayConfig{File: flags.Arg(0), Speed: *speed, Loop: *loop, Start: *start}
+	configFlags.override = func(cfg *config.Config) {
+		cfg.Providers = []config.ProviderConfig{{Type: "replay", Name: "Replay", ShortName: "Re", Replay: replay}}
+	}
+
+	return runChat(configFlags)
+}
+
+// runChat loads the configuration, then forwards the chat until interrupted, applying the
+// configuration changes while running.
+func runChat(configFlags *configFlags) int {
+	fmt.Println("Chat client application started.")
+
+	cfg, err := configFlags.load()
+	if err != nil {
+		log.Println("Error loading configuration: ", err)
+		return 1
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Println("Invalid configuration, run the validate command for details:\n", err)
+		return 1
+	}
+
+	// Handle CTRL+C to gracefully shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Create the providers and consumers configured
+	agg := aggregator.NewAggregator(cfg)
+	if err := agg.ApplyConfig(ctx, cfg); err != nil {
+		log.Println("Error creating providers and consumers: ", err)
+		return 1
+	}
+
+	// Start the aggregator
+	if err := agg.Start(ctx); err != nil {
+		log.Println("Error starting aggregator: ", err)
+		return 1
+	}
+
+	// Apply the configuration changes without restarting, on file changes and SIGHUP.
+	// The flags are applied again so they keep overriding the file.
+	watching := make(chan struct{})
+	go func() {
+		defer close(watching)
+		config.Watch(ctx, configFlags.path, config.WatchInterval, func() {
+			cfg, err := configFlags.load()
+			if err == nil {
+				err = cfg.Validate()
+			}
+			if err != nil {
+				log.Println("Ignoring configuration change, run the validate command for details:\n", err)
+				return
+			}
+			fmt.Println("Configuration changed, applying it...")
+			if err := agg.ApplyConfig(ctx, cfg); err != nil {
+				log.Println("Error applying configuration: ", err)
+			}
+		})
+	}()
+
+	<-ctx.Done()
+	fmt.Println("Shutting down...")
+	// Wait for a reload in progress, the aggregator is not reconfigured while it stops
+	<-watching
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Aggregator.ShutdownTimeout)
+	defer cancel()
+	if err := agg.Stop(shutdownCtx); err != nil {
+		log.Println("Error during shutdown: ", err)
+	}
+
+	fmt.Println("Chat aggregation ended.")
+	return 0
+}
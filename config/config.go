@@ -1,88 +1,261 @@
 package config
 
 import (
-	"log"
+	"bytes"
+	"errors"
+	"fmt"
 	"os"
-	"strconv"
-	"sync"
+	"regexp"
+	"strings"
+	"time"
 
-	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultPath is the configuration file loaded when no path is given and the file exists.
+const DefaultPath = "config.yaml"
+
+// Config is the whole application configuration. It is read from a YAML file, then the
+// .env file and the environment variables override the values of the file.
 type Config struct {
-	ConnectTwitch               bool
-	TwitchChannel               string
-	ConnectYoutube              bool
-	YoutubeApiKey               string
-	YoutubeChannelId            string
-	YoutubeQueriesPerDay        int
-	ChatOutput                  bool
-	WebpageOutput               bool
-	WebpageOutputPort           int
-	WebpageOuputShortenProvider bool
-	WebpageOutputHideProvider   bool
-}
-
-var config *Config
-var once sync.Once
-
-func GetConfig() *Config {
-	once.Do(func() {
-		//load .env file if it exists
-		if err := godotenv.Load(); err != nil {
-			log.Fatal("No .env file found")
-		}
+	Providers  []ProviderConfig `yaml:"providers"`
+	Consumers  ConsumersConfig  `yaml:"consumers"`
+	Filters    FiltersConfig    `yaml:"filters"`
+	Server     ServerConfig     `yaml:"server"`
+	History    HistoryConfig    `yaml:"history"`
+	Aggregator AggregatorConfig `yaml:"aggregator"`
 
-		connectTwitch, _ := strconv.ParseBool(os.Getenv("CONNECT_TWITCH"))
+	// unknownKeys are the file keys and environment variables that were not recognized,
+	// they are reported by Validate
+	unknownKeys []string
+}
 
-		defaultYoutubeQueriesPerDay := 10000
-		connectYoutube, _ := strconv.ParseBool(os.Getenv("CONNECT_YOUTUBE"))
-		youtubeQueriesPerDay, err := strconv.Atoi(os.Getenv("YOUTUBE_QUERIES_PER_DAY"))
+// ProviderConfig configures one provider instance, several instances of the same type can run at once.
+type ProviderConfig struct {
+	// Type is the kind of provider: twitch, youtube or replay
+	Type string `yaml:"type"`
+	// Name identifies the instance and labels its messages, it must be unique
+	Name      string        `yaml:"name"`
+	ShortName string        `yaml:"short_name"`
+	Twitch    TwitchConfig  `yaml:"twitch"`
+	Youtube   YoutubeConfig `yaml:"youtube"`
+	Replay    ReplayConfig  `yaml:"replay"`
+}
 
-		if err != nil && connectYoutube {
-			log.Println("No number of Youtube queries per day found, using default limit:", defaultYoutubeQueriesPerDay)
-			youtubeQueriesPerDay = defaultYoutubeQueriesPerDay
-		}
+// DisplayName returns the name of the instance, or the name of its type when it has none.
+func (p ProviderConfig) DisplayName() string {
+	if p.Name != "" {
+		return p.Name
+	}
+	switch strings.ToLower(p.Type) {
+	case "twitch":
+		return "Twitch"
+	case "youtube":
+		return "Youtube"
+	case "replay":
+		return "Replay"
+	default:
+		return p.Type
+	}
+}
 
-		outputChat, _ := strconv.ParseBool(os.Getenv("OUTPUT_CHAT"))
-		webpageOutput, _ := strconv.ParseBool(os.Getenv("OUTPUT_WEBPAGE"))
-		webpageOutputPort, err := strconv.Atoi(os.Getenv("OUTPUT_WEBPAGE_PORT"))
+type TwitchConfig struct {
+	Channel    string `yaml:"channel"`
+	Username   string `yaml:"username"`
+	OAuthToken string `yaml:"oauth_token"`
+}
 
-		if (err != nil || webpageOutputPort == 0) && webpageOutput {
-			webpageOutputPort = 8080
-		}
+type YoutubeConfig struct {
+	ApiKey            string `yaml:"api_key"`
+	ChannelId         string `yaml:"channel_id"`
+	QueriesPerDay     int    `yaml:"queries_per_day"`
+	OAuthClientId     string `yaml:"oauth_client_id"`
+	OAuthClientSecret string `yaml:"oauth_client_secret"`
+	OAuthRefreshToken string `yaml:"oauth_refresh_token"`
+}
 
-		webpageOuputShortenProvider, err := strconv.ParseBool(os.Getenv("OUTPUT_WEBPAGE_SHORTEN_PROVIDER"))
+// ReplayConfig configures the playback of a chat recording, to test the consumers offline.
+type ReplayConfig struct {
+	// File is the JSON Lines recording played, see the recorder consumer
+	File string `yaml:"file"`
+	// Speed multiplies the pace of the recording, 2 plays twice as fast. 0 plays at the original pace
+	Speed float64 `yaml:"speed"`
+	// Loop plays the recording again once it ends
+	Loop bool `yaml:"loop"`
+	// Start skips the beginning of the recording, it is an offset from the first line
+	Start time.Duration `yaml:"start"`
+}
 
-		if err != nil && webpageOutput {
-			webpageOuputShortenProvider = false
-		}
+type ConsumersConfig struct {
+	Console    ConsoleConfig    `yaml:"console"`
+	SimplePage SimplePageConfig `yaml:"simplepage"`
+	Recorder   RecorderConfig   `yaml:"recorder"`
+}
+
+type ConsoleConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// SimplePageConfig holds the display options of the simple page, it is served by the HTTP server.
+type SimplePageConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	ShortenProvider bool `yaml:"shorten_provider"`
+	// HideProvider wins over ShortenProvider when both are set
+	HideProvider bool `yaml:"hide_provider"`
+	// Theme is the look of the page when its URL does not select one with ?theme=
+	Theme string `yaml:"theme"`
+	// ThemesDir holds the user themes, a sub directory for each theme
+	ThemesDir string `yaml:"themes_dir"`
+}
+
+// RecorderConfig configures the recording of the chat to JSON Lines files.
+type RecorderConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Directory is where the recordings are written, it is created when missing
+	Directory string `yaml:"directory"`
+	// Rotation starts a new file for every session (each start) or for every day
+	Rotation string `yaml:"rotation"`
+	// MaxSizeMB starts a new part once a file reaches the size, 0 disables it
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// Compress writes gzip compressed files
+	Compress bool `yaml:"compress"`
+	// Sync is when the lines are flushed to disk: always (after every line), interval or never
+	// (left to the operating system)
+	Sync         string        `yaml:"sync"`
+	SyncInterval time.Duration `yaml:"sync_interval"`
+}
+
+// FiltersConfig drops chat messages before they reach the consumers.
+type FiltersConfig struct {
+	// IgnoreAuthors lists author names (e.g. bots) whose messages are dropped, case insensitive
+	IgnoreAuthors []string `yaml:"ignore_authors"`
+	// IgnoreCommands drops the messages starting with !
+	IgnoreCommands bool `yaml:"ignore_commands"`
+}
+
+// ServerConfig configures the HTTP server of the web consumers.
+type ServerConfig struct {
+	Port int `yaml:"port"`
+	// ModerationToken enables the moderation commands of the page, moderation is disabled when empty
+	ModerationToken string `yaml:"moderation_token"`
+}
+
+// HistoryConfig configures the chat history database, used by the simple page to restore its
+// history after a restart and to search the chat.
+type HistoryConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Path is the SQLite database file, it is created when missing
+	Path string `yaml:"path"`
+	// Retention deletes the messages older than the duration, 0 keeps every message
+	Retention time.Duration `yaml:"retention"`
+}
 
-		webpageOutputHideProvider, err := strconv.ParseBool(os.Getenv("OUTPUT_WEBPAGE_HIDE_PROVIDER"))
+// AggregatorConfig configures the delivery queues, the provider reconnection and the shutdown.
+// Zero values use the aggregator defaults.
+type AggregatorConfig struct {
+	QueueSize         int           `yaml:"queue_size"`
+	QueueOverflow     string        `yaml:"queue_overflow"`
+	RetryMax          int           `yaml:"retry_max"`
+	RetryInitialDelay time.Duration `yaml:"retry_initial_delay"`
+	RetryMaxDelay     time.Duration `yaml:"retry_max_delay"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout"`
+}
+
+// Default returns the configuration used for the values that are not set.
+func Default() *Config {
+	return &Config{
+		Consumers: ConsumersConfig{
+			SimplePage: SimplePageConfig{
+				Theme: "classic",
+			},
+			Recorder: RecorderConfig{
+				Directory:    "recordings",
+				Rotation:     "session",
+				Sync:         "interval",
+				SyncInterval: time.Second,
+			},
+		},
+		Server: ServerConfig{
+			Port: 8080,
+		},
+		History: HistoryConfig{
+			Path: "history.db",
+		},
+		Aggregator: AggregatorConfig{
+			ShutdownTimeout: 10 * time.Second,
+		},
+	}
+}
 
-		if err != nil && webpageOutput {
-			webpageOutputHideProvider = false
+// Load reads the configuration file at path and then applies the .env file and the environment
+// variables on top of it. When path is empty the DefaultPath file is read if it exists, so the
+// application can still be configured with the environment only.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	path = resolvePath(path)
+	if path != "" {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading config file: %w", err)
+		}
+		if err := parse(content, cfg); err != nil {
+			return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
 		}
+		for i, key := range cfg.unknownKeys {
+			cfg.unknownKeys[i] = path + " " + key
+		}
+	}
+
+	if err := loadDotenv(); err != nil {
+		return nil, fmt.Errorf("error reading .env file: %w", err)
+	}
+
+	if err := applyEnv(cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
 
-		defaultWebpageOutputPort := 8080
-		if err != nil && webpageOutput {
-			log.Println("No port specified for webpage output, using default:", defaultWebpageOutputPort)
-			webpageOutputPort = defaultWebpageOutputPort
+// resolvePath returns the configuration file to read, DefaultPath when none is given and it exists.
+func resolvePath(path string) string {
+	if path == "" {
+		if _, err := os.Stat(DefaultPath); err == nil {
+			return DefaultPath
 		}
+	}
+	return path
+}
+
+// unknownFieldPattern matches the errors reported by the YAML decoder for keys without a field
+var unknownFieldPattern = regexp.MustCompile(`^line (\d+): field (.+) not found in type`)
+
+// parse decodes a YAML configuration over the values already in cfg.
+// Unknown keys do not stop the decoding, they are kept for Validate.
+func parse(content []byte, cfg *Config) error {
+	if len(bytes.TrimSpace(content)) == 0 {
+		return nil
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	err := decoder.Decode(cfg)
 
-		config = &Config{
-			ConnectTwitch:               connectTwitch,
-			TwitchChannel:               os.Getenv("TWITCH_CHANNEL"),
-			ConnectYoutube:              connectYoutube,
-			YoutubeApiKey:               os.Getenv("YOUTUBE_API_KEY"),
-			YoutubeChannelId:            os.Getenv("YOUTUBE_CHANNEL_ID"),
-			YoutubeQueriesPerDay:        youtubeQueriesPerDay,
-			ChatOutput:                  outputChat,
-			WebpageOutput:               webpageOutput,
-			WebpageOutputPort:           webpageOutputPort,
-			WebpageOuputShortenProvider: webpageOuputShortenProvider,
-			WebpageOutputHideProvider:   webpageOutputHideProvider,
+	var typeError *yaml.TypeError
+	if !errors.As(err, &typeError) {
+		return err
+	}
+	var others []string
+	for _, message := range typeError.Errors {
+		if match := unknownFieldPattern.FindStringSubmatch(message); match != nil {
+			cfg.unknownKeys = append(cfg.unknownKeys, fmt.Sprintf("line %s: unknown key %q", match[1], match[2]))
+		} else {
+			others = append(others, message)
 		}
-	})
-	return config
+	}
+	if len(others) > 0 {
+		return &yaml.TypeError{Errors: others}
+	}
+	return nil
 }
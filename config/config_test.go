@@ -0,0 +1,209 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_File(t *testing.T) {
+	path := writeConfig(t, `
+providers:
+  - type: twitch
+    name: Twitch
+    short_name: Tw
+    twitch:
+      channel: streamer
+  - type: twitch
+    name: Partner
+    twitch:
+      channel: partner
+  - type: youtube
+    name: Youtube
+    youtube:
+      channel_id: UC123
+      api_key: key
+consumers:
+  console:
+    enabled: true
+  simplepage:
+    enabled: true
+    shorten_provider: true
+filters:
+  ignore_authors: [Nightbot]
+server:
+  port: 9090
+aggregator:
+  queue_size: 64
+  retry_max_delay: 30s
+`)
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Providers, 3)
+	assert.Equal(t, ProviderConfig{Type: "twitch", Name: "Partner", Twitch: TwitchConfig{Channel: "partner"}}, cfg.Providers[1])
+	assert.Equal(t, "key", cfg.Providers[2].Youtube.ApiKey)
+	assert.True(t, cfg.Consumers.Console.Enabled)
+	assert.True(t, cfg.Consumers.SimplePage.ShortenProvider)
+	assert.Equal(t, []string{"Nightbot"}, cfg.Filters.IgnoreAuthors)
+	assert.Equal(t, 9090, cfg.Server.Port)
+	assert.Equal(t, 64, cfg.Aggregator.QueueSize)
+	assert.Equal(t, 30*time.Second, cfg.Aggregator.RetryMaxDelay)
+	// Values missing from the file keep their defaults
+	assert.Equal(t, 10*time.Second, cfg.Aggregator.ShutdownTimeout)
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	path := writeConfig(t, `
+providers:
+  - type: twitch
+    name: Main
+    twitch:
+      channel: streamer
+  - type: twitch
+    name: Partner
+    twitch:
+      channel: partner
+server:
+  port: 9090
+`)
+	t.Setenv("OUTPUT_WEBPAGE_PORT", "8181")
+	t.Setenv("TWITCH_CHANNEL", "other_streamer")
+	t.Setenv("TWITCH_USERNAME", "bot")
+	t.Setenv("TWITCH_OAUTH_TOKEN", "token")
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 8181, cfg.Server.Port)
+	assert.Equal(t, TwitchConfig{Channel: "other_streamer", Username: "bot", OAuthToken: "token"}, cfg.Providers[0].Twitch)
+	// Credentials are shared with the other instances
+	assert.Equal(t, TwitchConfig{Channel: "partner", Username: "bot", OAuthToken: "token"}, cfg.Providers[1].Twitch)
+}
+
+func TestLoad_EnvOnly(t *testing.T) {
+	t.Setenv("CONNECT_TWITCH", "true")
+	t.Setenv("TWITCH_CHANNEL", "streamer")
+	t.Setenv("CONNECT_YOUTUBE", "true")
+	t.Setenv("YOUTUBE_CHANNEL_ID", "UC123")
+	t.Setenv("YOUTUBE_API_KEY", "key")
+	t.Setenv("PROVIDERS", "partner")
+	t.Setenv("PROVIDER_PARTNER_TYPE", "Twitch")
+	t.Setenv("PROVIDER_PARTNER_NAME", "Partner")
+	t.Setenv("PROVIDER_PARTNER_SHORT_NAME", "Pa")
+	t.Setenv("PROVIDER_PARTNER_CHANNEL", "partner")
+	t.Setenv("OUTPUT_CHAT", "true")
+	t.Setenv("SHUTDOWN_TIMEOUT", "5s")
+
+	cfg, err := Load("")
+
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Providers, 3)
+	assert.Equal(t, "Twitch", cfg.Providers[0].Name)
+	assert.Equal(t, "streamer", cfg.Providers[0].Twitch.Channel)
+	assert.Equal(t, "Youtube", cfg.Providers[1].Name)
+	assert.Equal(t, YoutubeConfig{ChannelId: "UC123", ApiKey: "key"}, cfg.Providers[1].Youtube)
+	assert.Equal(t, "twitch", cfg.Providers[2].Type)
+	assert.Equal(t, "Partner", cfg.Providers[2].Name)
+	assert.Equal(t, "Pa", cfg.Providers[2].ShortName)
+	assert.Equal(t, "partner", cfg.Providers[2].Twitch.Channel)
+	assert.True(t, cfg.Consumers.Console.Enabled)
+	assert.Equal(t, 5*time.Second, cfg.Aggregator.ShutdownTimeout)
+	assert.Equal(t, 8080, cfg.Server.Port)
+}
+
+func TestLoad_DotenvReload(t *testing.T) {
+	t.Chdir(t.TempDir())
+	// The variables are restored after the test, the .env file sets the unset ones
+	t.Setenv("OUTPUT_WEBPAGE_PORT", "8181")
+	for _, name := range []string{"OUTPUT_WEBPAGE_THEME", "OUTPUT_WEBPAGE_MODERATION_TOKEN"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+	writeDotenv := func(content string) {
+		if err := os.WriteFile(".env", []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	writeDotenv("OUTPUT_WEBPAGE_PORT=9000\nOUTPUT_WEBPAGE_THEME=dark\nOUTPUT_WEBPAGE_MODERATION_TOKEN=secret\n")
+	cfg, err := Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, 8181, cfg.Server.Port)
+	assert.Equal(t, "dark", cfg.Consumers.SimplePage.Theme)
+	assert.Equal(t, "secret", cfg.Server.ModerationToken)
+
+	// A reload applies the edits of the file, the environment still takes precedence
+	writeDotenv("OUTPUT_WEBPAGE_PORT=9000\nOUTPUT_WEBPAGE_THEME=light\n")
+	cfg, err = Load("")
+	assert.NoError(t, err)
+	assert.Equal(t, 8181, cfg.Server.Port)
+	assert.Equal(t, "light", cfg.Consumers.SimplePage.Theme)
+	assert.Empty(t, cfg.Server.ModerationToken)
+}
+
+func TestLoad_EnvReplay(t *testing.T) {
+	t.Setenv("PROVIDERS", "demo")
+	t.Setenv("PROVIDER_DEMO_TYPE", "replay")
+	t.Setenv("PROVIDER_DEMO_FILE", "recordings/chat.jsonl")
+	t.Setenv("PROVIDER_DEMO_SPEED", "2.5")
+	t.Setenv("PROVIDER_DEMO_LOOP", "true")
+	t.Setenv("PROVIDER_DEMO_START", "90s")
+
+	cfg, err := Load("")
+
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Providers, 1)
+	assert.Equal(t, "demo", cfg.Providers[0].Name)
+	assert.Equal(t, ReplayConfig{File: "recordings/chat.jsonl", Speed: 2.5, Loop: true, Start: 90 * time.Second}, cfg.Providers[0].Replay)
+}
+
+func TestLoad_ConnectFalseRemovesProviders(t *testing.T) {
+	path := writeConfig(t, `
+providers:
+  - type: twitch
+    name: Twitch
+  - type: youtube
+    name: Youtube
+`)
+	t.Setenv("CONNECT_TWITCH", "false")
+
+	cfg, err := Load(path)
+
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Providers, 1)
+	assert.Equal(t, "Youtube", cfg.Providers[0].Name)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "error reading config file")
+
+	_, err = Load(writeConfig(t, "server: [not a map"))
+	assert.ErrorContains(t, err, "error parsing config file")
+
+	t.Setenv("OUTPUT_WEBPAGE_PORT", "eighty")
+	t.Setenv("SHUTDOWN_TIMEOUT", "10")
+	_, err = Load("")
+	assert.EqualError(t, err, "OUTPUT_WEBPAGE_PORT: invalid number \"eighty\"\nSHUTDOWN_TIMEOUT: invalid duration \"10\"")
+}
+
+func TestLoad_Example(t *testing.T) {
+	cfg, err := Load(filepath.Join("..", "config.example.yaml"))
+
+	assert.NoError(t, err)
+	assert.Len(t, cfg.Providers, 3)
+	assert.True(t, cfg.Consumers.SimplePage.Enabled)
+}
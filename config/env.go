@@ -0,0 +1,352 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+var (
+	// dotenvKeys are the variables set from the .env file, they are set again when it is reloaded
+	dotenvKeys = make(map[string]bool)
+	dotenvMux  sync.Mutex
+)
+
+// loadDotenv sets the variables of the optional .env file. Variables already set in the
+// environment take precedence, except the ones set by a previous load, so a reload applies the
+// changes of the file and unsets the variables removed from it.
+func loadDotenv() error {
+	values, err := godotenv.Read()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	dotenvMux.Lock()
+	defer dotenvMux.Unlock()
+	for key := range dotenvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotenvKeys, key)
+		}
+	}
+	for key, value := range values {
+		if _, ok := os.LookupEnv(key); ok && !dotenvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotenvKeys[key] = true
+	}
+	return nil
+}
+
+// applyEnv overrides the configuration with the environment variables that are set.
+// Invalid values are reported together.
+func applyEnv(cfg *Config) error {
+	env := envReader{}
+
+	env.bool("OUTPUT_CHAT", &cfg.Consumers.Console.Enabled)
+	env.bool("OUTPUT_WEBPAGE", &cfg.Consumers.SimplePage.Enabled)
+	env.bool("OUTPUT_WEBPAGE_SHORTEN_PROVIDER", &cfg.Consumers.SimplePage.ShortenProvider)
+	env.bool("OUTPUT_WEBPAGE_HIDE_PROVIDER", &cfg.Consumers.SimplePage.HideProvider)
+	env.string("OUTPUT_WEBPAGE_THEME", &cfg.Consumers.SimplePage.Theme)
+	env.string("OUTPUT_WEBPAGE_THEMES_DIR", &cfg.Consumers.SimplePage.ThemesDir)
+
+	env.bool("OUTPUT_RECORDING", &cfg.Consumers.Recorder.Enabled)
+	env.string("OUTPUT_RECORDING_DIRECTORY", &cfg.Consumers.Recorder.Directory)
+	env.string("OUTPUT_RECORDING_ROTATION", &cfg.Consumers.Recorder.Rotation)
+	env.int("OUTPUT_RECORDING_MAX_SIZE_MB", &cfg.Consumers.Recorder.MaxSizeMB)
+	env.bool("OUTPUT_RECORDING_COMPRESS", &cfg.Consumers.Recorder.Compress)
+	env.string("OUTPUT_RECORDING_SYNC", &cfg.Consumers.Recorder.Sync)
+	env.duration("OUTPUT_RECORDING_SYNC_INTERVAL", &cfg.Consumers.Recorder.SyncInterval)
+
+	env.int("OUTPUT_WEBPAGE_PORT", &cfg.Server.Port)
+	env.string("OUTPUT_WEBPAGE_MODERATION_TOKEN", &cfg.Server.ModerationToken)
+
+	env.bool("HISTORY_ENABLED", &cfg.History.Enabled)
+	env.string("HISTORY_PATH", &cfg.History.Path)
+	env.duration("HISTORY_RETENTION", &cfg.History.Retention)
+
+	env.list("FILTER_IGNORE_AUTHORS", &cfg.Filters.IgnoreAuthors)
+	env.bool("FILTER_IGNORE_COMMANDS", &cfg.Filters.IgnoreCommands)
+
+	env.int("CONSUMER_QUEUE_SIZE", &cfg.Aggregator.QueueSize)
+	env.string("CONSUMER_QUEUE_OVERFLOW", &cfg.Aggregator.QueueOverflow)
+	env.int("PROVIDER_RETRY_MAX", &cfg.Aggregator.RetryMax)
+	env.duration("PROVIDER_RETRY_INITIAL_DELAY", &cfg.Aggregator.RetryInitialDelay)
+	env.duration("PROVIDER_RETRY_MAX_DELAY", &cfg.Aggregator.RetryMaxDelay)
+	env.duration("SHUTDOWN_TIMEOUT", &cfg.Aggregator.ShutdownTimeout)
+
+	cfg.Providers = env.providers(cfg.Providers)
+
+	cfg.unknownKeys = append(cfg.unknownKeys, env.unknown()...)
+
+	return errors.Join(env.errs...)
+}
+
+// envPrefixes are the prefixes of the variables read by applyEnv, other variables with these
+// prefixes are reported as unknown since they are usually typos.
+var envPrefixes = []string{"CONNECT_", "TWITCH_", "YOUTUBE_", "OUTPUT_", "CONSUMER_", "PROVIDER_", "PROVIDERS", "FILTER_", "HISTORY_", "SHUTDOWN_"}
+
+// envHints suggests the right name for known mistakes
+var envHints = map[string]string{
+	"OUTPUT_SIMPLEPAGE":      "OUTPUT_WEBPAGE",
+	"OUTPUT_SIMPLEPAGE_PORT": "OUTPUT_WEBPAGE_PORT",
+	"OUTPUT_CONSOLE":         "OUTPUT_CHAT",
+}
+
+// unknown returns a message for every set variable that has a known prefix but was never read.
+func (e *envReader) unknown() []string {
+	var unknown []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if e.known[name] || !slices.ContainsFunc(envPrefixes, func(prefix string) bool { return strings.HasPrefix(name, prefix) }) {
+			continue
+		}
+		message := "unknown environment variable " + name
+		if hint, ok := envHints[name]; ok {
+			message += ", did you mean " + hint + "?"
+		} else if strings.HasPrefix(name, "PROVIDER_") {
+			message += ", is its provider ID listed in PROVIDERS?"
+		}
+		unknown = append(unknown, message)
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// envProvider describes the main instance of a provider type, configured by the legacy variables.
+type envProvider struct {
+	providerType string
+	name         string
+	shortName    string
+	connect      string
+}
+
+var (
+	envTwitch  = envProvider{providerType: "twitch", name: "Twitch", shortName: "Tw", connect: "CONNECT_TWITCH"}
+	envYoutube = envProvider{providerType: "youtube", name: "Youtube", shortName: "Yt", connect: "CONNECT_YOUTUBE"}
+)
+
+// providers applies the provider variables:
+//   - CONNECT_TWITCH and CONNECT_YOUTUBE add the main instance of the type when true, or remove every
+//     instance of the type when false.
+//   - TWITCH_* and YOUTUBE_* configure the main instance, which is the first instance of the type.
+//     Credentials are also shared with the other instances of the type that have none.
+//   - PROVIDERS lists instance IDs configured with PROVIDER_<ID>_* variables. An instance with the
+//     same name is updated, otherwise a new instance is added.
+func (e *envReader) providers(providers []ProviderConfig) []ProviderConfig {
+	for _, main := range []envProvider{envTwitch, envYoutube} {
+		var connect bool
+		if !e.bool(main.connect, &connect) {
+			continue
+		}
+		isType := func(p ProviderConfig) bool { return strings.EqualFold(p.Type, main.providerType) }
+		if !connect {
+			providers = slices.DeleteFunc(providers, isType)
+		} else if !slices.ContainsFunc(providers, isType) {
+			providers = append(providers, ProviderConfig{Type: main.providerType, Name: main.name, ShortName: main.shortName})
+		}
+	}
+
+	var twitchShared TwitchConfig
+	e.string("TWITCH_USERNAME", &twitchShared.Username)
+	e.string("TWITCH_OAUTH_TOKEN", &twitchShared.OAuthToken)
+
+	var youtubeShared YoutubeConfig
+	e.string("YOUTUBE_API_KEY", &youtubeShared.ApiKey)
+	e.int("YOUTUBE_QUERIES_PER_DAY", &youtubeShared.QueriesPerDay)
+	e.string("YOUTUBE_OAUTH_CLIENT_ID", &youtubeShared.OAuthClientId)
+	e.string("YOUTUBE_OAUTH_CLIENT_SECRET", &youtubeShared.OAuthClientSecret)
+	e.string("YOUTUBE_OAUTH_REFRESH_TOKEN", &youtubeShared.OAuthRefreshToken)
+
+	// The main instance variables are only read when there is a main instance, they are still known
+	e.declare("TWITCH_CHANNEL", "YOUTUBE_CHANNEL_ID")
+
+	mainTwitch, mainYoutube := true, true
+	for i := range providers {
+		provider := &providers[i]
+		switch strings.ToLower(provider.Type) {
+		case envTwitch.providerType:
+			if mainTwitch {
+				e.string("TWITCH_CHANNEL", &provider.Twitch.Channel)
+				mainTwitch = false
+				provider.Twitch.Username = firstSet(twitchShared.Username, provider.Twitch.Username)
+				provider.Twitch.OAuthToken = firstSet(twitchShared.OAuthToken, provider.Twitch.OAuthToken)
+			} else if provider.Twitch.Username == "" && provider.Twitch.OAuthToken == "" {
+				provider.Twitch.Username = twitchShared.Username
+				provider.Twitch.OAuthToken = twitchShared.OAuthToken
+			}
+		case envYoutube.providerType:
+			if mainYoutube {
+				e.string("YOUTUBE_CHANNEL_ID", &provider.Youtube.ChannelId)
+				mainYoutube = false
+				provider.Youtube.ApiKey = firstSet(youtubeShared.ApiKey, provider.Youtube.ApiKey)
+				if youtubeShared.QueriesPerDay > 0 {
+					provider.Youtube.QueriesPerDay = youtubeShared.QueriesPerDay
+				}
+				provider.Youtube.OAuthClientId = firstSet(youtubeShared.OAuthClientId, provider.Youtube.OAuthClientId)
+				provider.Youtube.OAuthClientSecret = firstSet(youtubeShared.OAuthClientSecret, provider.Youtube.OAuthClientSecret)
+				provider.Youtube.OAuthRefreshToken = firstSet(youtubeShared.OAuthRefreshToken, provider.Youtube.OAuthRefreshToken)
+			} else {
+				provider.Youtube.ApiKey = firstSet(provider.Youtube.ApiKey, youtubeShared.ApiKey)
+				if provider.Youtube.OAuthClientId == "" && provider.Youtube.OAuthRefreshToken == "" {
+					provider.Youtube.OAuthClientId = youtubeShared.OAuthClientId
+					provider.Youtube.OAuthClientSecret = youtubeShared.OAuthClientSecret
+					provider.Youtube.OAuthRefreshToken = youtubeShared.OAuthRefreshToken
+				}
+			}
+		}
+	}
+
+	var ids []string
+	e.list("PROVIDERS", &ids)
+	for _, id := range ids {
+		prefix := "PROVIDER_" + strings.ToUpper(id) + "_"
+
+		name := id
+		e.string(prefix+"NAME", &name)
+		index := slices.IndexFunc(providers, func(p ProviderConfig) bool { return p.Name == name })
+		if index < 0 {
+			providers = append(providers, ProviderConfig{
+				Name: name,
+				// Extra instances share the credentials of the main instances unless they set their own
+				Twitch:  twitchShared,
+				Youtube: youtubeShared,
+			})
+			index = len(providers) - 1
+		}
+		provider := &providers[index]
+
+		e.string(prefix+"TYPE", &provider.Type)
+		provider.Type = strings.ToLower(provider.Type)
+		e.string(prefix+"SHORT_NAME", &provider.ShortName)
+		e.string(prefix+"CHANNEL", &provider.Twitch.Channel)
+		e.string(prefix+"CHANNEL", &provider.Youtube.ChannelId)
+		e.string(prefix+"USERNAME", &provider.Twitch.Username)
+		e.string(prefix+"OAUTH_TOKEN", &provider.Twitch.OAuthToken)
+		e.string(prefix+"API_KEY", &provider.Youtube.ApiKey)
+		e.int(prefix+"QUERIES_PER_DAY", &provider.Youtube.QueriesPerDay)
+		e.string(prefix+"FILE", &provider.Replay.File)
+		e.float(prefix+"SPEED", &provider.Replay.Speed)
+		e.bool(prefix+"LOOP", &provider.Replay.Loop)
+		e.duration(prefix+"START", &provider.Replay.Start)
+	}
+
+	return providers
+}
+
+// envReader reads typed environment variables, keeping the errors of the invalid ones and the
+// names of the variables read. Every method returns whether the variable was set and valid.
+type envReader struct {
+	errs  []error
+	known map[string]bool
+}
+
+// declare marks variables as known without reading them.
+func (e *envReader) declare(names ...string) {
+	if e.known == nil {
+		e.known = make(map[string]bool)
+	}
+	for _, name := range names {
+		e.known[name] = true
+	}
+}
+
+func (e *envReader) string(name string, target *string) bool {
+	e.declare(name)
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return false
+	}
+	*target = value
+	return true
+}
+
+func (e *envReader) bool(name string, target *bool) bool {
+	var value string
+	if !e.string(name, &value) {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid boolean %q", name, value))
+		return false
+	}
+	*target = parsed
+	return true
+}
+
+func (e *envReader) int(name string, target *int) bool {
+	var value string
+	if !e.string(name, &value) {
+		return false
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid number %q", name, value))
+		return false
+	}
+	*target = parsed
+	return true
+}
+
+func (e *envReader) float(name string, target *float64) bool {
+	var value string
+	if !e.string(name, &value) {
+		return false
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid number %q", name, value))
+		return false
+	}
+	*target = parsed
+	return true
+}
+
+func (e *envReader) duration(name string, target *time.Duration) bool {
+	var value string
+	if !e.string(name, &value) {
+		return false
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		e.errs = append(e.errs, fmt.Errorf("%s: invalid duration %q", name, value))
+		return false
+	}
+	*target = parsed
+	return true
+}
+
+// list reads a comma separated list, ignoring empty items.
+func (e *envReader) list(name string, target *[]string) bool {
+	var value string
+	if !e.string(name, &value) {
+		return false
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*target = items
+	return true
+}
+
+func firstSet(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/internal/themes"
+)
+
+var (
+	// queueOverflowPolicies are the values accepted by aggregator.queue_overflow.
+	queueOverflowPolicies = []string{"drop-oldest", "drop-newest", "block"}
+	// recorderRotations are the values accepted by consumers.recorder.rotation.
+	recorderRotations = []string{"session", "day"}
+	// recorderSyncPolicies are the values accepted by consumers.recorder.sync.
+	recorderSyncPolicies = []string{"always", "interval", "never"}
+)
+
+// Validate checks the whole configuration and returns every problem found, joined in a single
+// error, so misconfigurations are fixed at once before the aggregator starts.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	for _, key := range c.unknownKeys {
+		add("%s", key)
+	}
+
+	if len(c.Providers) == 0 {
+		add("no providers configured")
+	}
+	names := make(map[string]int)
+	for i, provider := range c.Providers {
+		name := provider.DisplayName()
+		field := fmt.Sprintf("providers[%d] (%s)", i, name)
+
+		if previous, ok := names[name]; ok {
+			add("%s: name is already used by providers[%d], provider names must be unique", field, previous)
+		} else {
+			names[name] = i
+		}
+
+		switch strings.ToLower(provider.Type) {
+		case "twitch":
+			errs = append(errs, validateTwitch(field, provider.Twitch)...)
+		case "youtube":
+			errs = append(errs, validateYoutube(field, provider.Youtube)...)
+		case "replay":
+			errs = append(errs, validateReplay(field, provider.Replay)...)
+		case "":
+			add("%s: missing type, use twitch, youtube or replay", field)
+		default:
+			add("%s: unknown type %q, use twitch, youtube or replay", field, provider.Type)
+		}
+	}
+
+	if !c.Consumers.Console.Enabled && !c.Consumers.SimplePage.Enabled && !c.Consumers.Recorder.Enabled {
+		add("no consumers enabled, enable consumers.console, consumers.simplepage or consumers.recorder")
+	}
+	if c.Consumers.SimplePage.Enabled {
+		if c.Server.Port < 1 || c.Server.Port > 65535 {
+			add("server.port: %d is not a valid port, use a value between 1 and 65535", c.Server.Port)
+		}
+		errs = append(errs, validateThemes(c.Consumers.SimplePage)...)
+	} else if c.Server.ModerationToken != "" {
+		add("server.moderation_token: is set but consumers.simplepage is not enabled")
+	}
+
+	if c.Consumers.Recorder.Enabled {
+		errs = append(errs, validateRecorder(c.Consumers.Recorder)...)
+	}
+
+	if c.History.Enabled {
+		if strings.TrimSpace(c.History.Path) == "" {
+			add("history.path: missing database path")
+		}
+		if !c.Consumers.SimplePage.Enabled {
+			add("history: is enabled but consumers.simplepage, which uses it, is not enabled")
+		}
+	}
+	if c.History.Retention < 0 {
+		add("history.retention: must not be negative, use 0 to keep every message")
+	}
+
+	for i, author := range c.Filters.IgnoreAuthors {
+		if strings.TrimSpace(author) == "" {
+			add("filters.ignore_authors[%d]: empty author name", i)
+		}
+	}
+
+	aggregator := c.Aggregator
+	if aggregator.QueueSize < 0 {
+		add("aggregator.queue_size: must not be negative")
+	}
+	if aggregator.QueueOverflow != "" && !slices.Contains(queueOverflowPolicies, strings.ToLower(strings.TrimSpace(aggregator.QueueOverflow))) {
+		add("aggregator.queue_overflow: unknown policy %q, use one of %s", aggregator.QueueOverflow, strings.Join(queueOverflowPolicies, ", "))
+	}
+	if aggregator.RetryMax < 0 {
+		add("aggregator.retry_max: must not be negative, use 0 to retry forever")
+	}
+	if aggregator.RetryInitialDelay < 0 || aggregator.RetryMaxDelay < 0 {
+		add("aggregator.retry_initial_delay and retry_max_delay: must not be negative")
+	} else if aggregator.RetryMaxDelay > 0 && aggregator.RetryInitialDelay > aggregator.RetryMaxDelay {
+		add("aggregator.retry_initial_delay: %s is longer than retry_max_delay %s", aggregator.RetryInitialDelay, aggregator.RetryMaxDelay)
+	}
+	if aggregator.ShutdownTimeout <= 0 {
+		add("aggregator.shutdown_timeout: must be positive")
+	}
+
+	return errors.Join(errs...)
+}
+
+func validateRecorder(recorder RecorderConfig) []error {
+	var errs []error
+	if strings.TrimSpace(recorder.Directory) == "" {
+		errs = append(errs, errors.New("consumers.recorder.directory: missing directory"))
+	}
+	if !slices.Contains(recorderRotations, recorder.Rotation) {
+		errs = append(errs, fmt.Errorf("consumers.recorder.rotation: unknown rotation %q, use one of %s", recorder.Rotation, strings.Join(recorderRotations, ", ")))
+	}
+	if recorder.MaxSizeMB < 0 {
+		errs = append(errs, errors.New("consumers.recorder.max_size_mb: must not be negative, use 0 to disable it"))
+	}
+	if !slices.Contains(recorderSyncPolicies, recorder.Sync) {
+		errs = append(errs, fmt.Errorf("consumers.recorder.sync: unknown policy %q, use one of %s", recorder.Sync, strings.Join(recorderSyncPolicies, ", ")))
+	} else if recorder.Sync == "interval" && recorder.SyncInterval <= 0 {
+		errs = append(errs, errors.New("consumers.recorder.sync_interval: must be positive"))
+	}
+	return errs
+}
+
+func validateThemes(simplePage SimplePageConfig) []error {
+	var errs []error
+	if simplePage.ThemesDir != "" {
+		if info, err := os.Stat(simplePage.ThemesDir); err != nil {
+			errs = append(errs, fmt.Errorf("consumers.simplepage.themes_dir: %w", err))
+		} else if !info.IsDir() {
+			errs = append(errs, fmt.Errorf("consumers.simplepage.themes_dir: %s is not a directory", simplePage.ThemesDir))
+		}
+	}
+
+	theme := simplePage.Theme
+	builtin := themes.Builtin()
+	if theme == "" || slices.Contains(builtin, theme) {
+		return errs
+	}
+	if !themes.NamePattern.MatchString(theme) {
+		return append(errs, fmt.Errorf("consumers.simplepage.theme: invalid name %q, use letters, digits, - and _", theme))
+	}
+	if simplePage.ThemesDir != "" {
+		if info, err := os.Stat(filepath.Join(simplePage.ThemesDir, theme)); err == nil && info.IsDir() {
+			return errs
+		}
+	}
+	return append(errs, fmt.Errorf("consumers.simplepage.theme: unknown theme %q, use one of %s or a directory of themes_dir", theme, strings.Join(builtin, ", ")))
+}
+
+func validateReplay(field string, replay ReplayConfig) []error {
+	var errs []error
+	if replay.File == "" {
+		errs = append(errs, fmt.Errorf("%s: missing replay.file", field))
+	} else if _, err := os.Stat(replay.File); err != nil {
+		errs = append(errs, fmt.Errorf("%s: replay.file: %w", field, err))
+	}
+	if replay.Speed < 0 {
+		errs = append(errs, fmt.Errorf("%s: replay.speed must not be negative", field))
+	}
+	if replay.Start < 0 {
+		errs = append(errs, fmt.Errorf("%s: replay.start must not be negative", field))
+	}
+	return errs
+}
+
+func validateTwitch(field string, twitch TwitchConfig) []error {
+	var errs []error
+	if twitch.Channel == "" {
+		errs = append(errs, fmt.Errorf("%s: missing twitch.channel", field))
+	}
+	if (twitch.Username == "") != (twitch.OAuthToken == "") {
+		errs = append(errs, fmt.Errorf("%s: twitch.username and twitch.oauth_token must be set together", field))
+	}
+	return errs
+}
+
+func validateYoutube(field string, youtube YoutubeConfig) []error {
+	var errs []error
+	if youtube.ChannelId == "" {
+		errs = append(errs, fmt.Errorf("%s: missing youtube.channel_id", field))
+	} else if strings.HasPrefix(youtube.ChannelId, "@") {
+		errs = append(errs, fmt.Errorf("%s: youtube.channel_id %q is a handle, use the channel id", field, youtube.ChannelId))
+	}
+
+	oauthValues := []string{youtube.OAuthClientId, youtube.OAuthClientSecret, youtube.OAuthRefreshToken}
+	oauthSet := len(slices.DeleteFunc(slices.Clone(oauthValues), func(v string) bool { return v == "" }))
+	if oauthSet > 0 && oauthSet < len(oauthValues) {
+		errs = append(errs, fmt.Errorf("%s: youtube.oauth_client_id, oauth_client_secret and oauth_refresh_token must be set together", field))
+	}
+	if youtube.ApiKey == "" && oauthSet < len(oauthValues) {
+		errs = append(errs, fmt.Errorf("%s: missing youtube.api_key or OAuth credentials", field))
+	}
+	if youtube.QueriesPerDay < 0 {
+		errs = append(errs, fmt.Errorf("%s: youtube.queries_per_day must not be negative", field))
+	}
+	return errs
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func validConfig() *Config {
+	cfg := Default()
+	cfg.Providers = []ProviderConfig{
+		{Type: "twitch", Name: "Twitch", Twitch: TwitchConfig{Channel: "streamer"}},
+		{Type: "youtube", Name: "Youtube", Youtube: YoutubeConfig{ChannelId: "UC123", ApiKey: "key"}},
+	}
+	cfg.Consumers.Console.Enabled = true
+	return cfg
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+}
+
+func TestConfig_Validate_ReportsEveryProblem(t *testing.T) {
+	cfg := validConfig()
+	cfg.Providers = append(cfg.Providers,
+		ProviderConfig{Type: "twitch", Name: "Twitch", Twitch: TwitchConfig{Username: "bot"}},
+		ProviderConfig{Type: "youtube", Name: "Partner", Youtube: YoutubeConfig{ChannelId: "@partner", OAuthClientId: "client"}},
+		ProviderConfig{Type: "kick", Name: "Kick"},
+	)
+	cfg.Consumers.Console.Enabled = false
+	cfg.Server.ModerationToken = "secret"
+	cfg.Aggregator.QueueOverflow = "drop-everything"
+	cfg.Aggregator.RetryInitialDelay = cfg.Aggregator.ShutdownTimeout
+	cfg.Aggregator.RetryMaxDelay = 1
+	cfg.unknownKeys = []string{"config.yaml line 3: unknown key \"prot\""}
+
+	err := cfg.Validate()
+
+	assert.Equal(t, []string{
+		`config.yaml line 3: unknown key "prot"`,
+		"providers[2] (Twitch): name is already used by providers[0], provider names must be unique",
+		"providers[2] (Twitch): missing twitch.channel",
+		"providers[2] (Twitch): twitch.username and twitch.oauth_token must be set together",
+		`providers[3] (Partner): youtube.channel_id "@partner" is a handle, use the channel id`,
+		"providers[3] (Partner): youtube.oauth_client_id, oauth_client_secret and oauth_refresh_token must be set together",
+		"providers[3] (Partner): missing youtube.api_key or OAuth credentials",
+		`providers[4] (Kick): unknown type "kick", use twitch, youtube or replay`,
+		"no consumers enabled, enable consumers.console, consumers.simplepage or consumers.recorder",
+		"server.moderation_token: is set but consumers.simplepage is not enabled",
+		`aggregator.queue_overflow: unknown policy "drop-everything", use one of drop-oldest, drop-newest, block`,
+		"aggregator.retry_initial_delay: 10s is longer than retry_max_delay 1ns",
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestConfig_Validate_Replay(t *testing.T) {
+	cfg := validConfig()
+	cfg.Providers = append(cfg.Providers,
+		ProviderConfig{Type: "replay", Name: "Empty", Replay: ReplayConfig{Speed: -1, Start: -1}},
+		ProviderConfig{Type: "replay", Name: "Missing", Replay: ReplayConfig{File: filepath.Join(t.TempDir(), "missing.jsonl")}},
+	)
+
+	err := cfg.Validate()
+
+	assert.Equal(t, []string{
+		"providers[2] (Empty): missing replay.file",
+		"providers[2] (Empty): replay.speed must not be negative",
+		"providers[2] (Empty): replay.start must not be negative",
+	}, strings.Split(err.Error(), "\n")[:3])
+	assert.ErrorContains(t, err, "providers[3] (Missing): replay.file: stat ")
+}
+
+func TestConfig_Validate_History(t *testing.T) {
+	cfg := validConfig()
+	cfg.History = HistoryConfig{Enabled: true, Retention: -time.Hour}
+
+	err := cfg.Validate()
+
+	assert.Equal(t, []string{
+		"history.path: missing database path",
+		"history: is enabled but consumers.simplepage, which uses it, is not enabled",
+		"history.retention: must not be negative, use 0 to keep every message",
+	}, strings.Split(err.Error(), "\n"))
+}
+
+func TestConfig_Validate_SimplePage(t *testing.T) {
+	cfg := validConfig()
+	// Hiding the provider wins over shortening it
+	cfg.Consumers.SimplePage = SimplePageConfig{Enabled: true, ShortenProvider: true, HideProvider: true}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Server.Port = 70000
+	assert.EqualError(t, cfg.Validate(), "server.port: 70000 is not a valid port, use a value between 1 and 65535")
+}
+
+func TestConfig_Validate_Themes(t *testing.T) {
+	cfg := validConfig()
+	cfg.Consumers.SimplePage = SimplePageConfig{Enabled: true, Theme: "neon"}
+	assert.EqualError(t, cfg.Validate(), `consumers.simplepage.theme: unknown theme "neon", use one of bubbles, classic, marquee, ticker or a directory of themes_dir`)
+
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "neon"), 0o755))
+	cfg.Consumers.SimplePage.ThemesDir = dir
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Consumers.SimplePage.Theme = "../neon"
+	assert.EqualError(t, cfg.Validate(), `consumers.simplepage.theme: invalid name "../neon", use letters, digits, - and _`)
+
+	cfg.Consumers.SimplePage = SimplePageConfig{Enabled: true, Theme: "bubbles", ThemesDir: filepath.Join(dir, "missing")}
+	err := cfg.Validate()
+	assert.ErrorContains(t, err, "consumers.simplepage.themes_dir: stat ")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestConfig_Validate_Recorder(t *testing.T) {
+	cfg := validConfig()
+	cfg.Consumers.Recorder = RecorderConfig{Enabled: true, Rotation: "hourly", MaxSizeMB: -1, Sync: "interval"}
+
+	err := cfg.Validate()
+
+	assert.Equal(t, []string{
+		"consumers.recorder.directory: missing directory",
+		`consumers.recorder.rotation: unknown rotation "hourly", use one of session, day`,
+		"consumers.recorder.max_size_mb: must not be negative, use 0 to disable it",
+		"consumers.recorder.sync_interval: must be positive",
+	}, strings.Split(err.Error(), "\n"))
+
+	// The default recorder options are valid
+	cfg.Consumers.Recorder = Default().Consumers.Recorder
+	cfg.Consumers.Recorder.Enabled = true
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestConfig_Validate_NoProviders(t *testing.T) {
+	cfg := validConfig()
+	cfg.Providers = nil
+
+	assert.EqualError(t, cfg.Validate(), "no providers configured")
+}
+
+func TestLoad_UnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+providers:
+  - type: twitch
+    name: Twitch
+    twitch:
+      chanel: streamer
+consumers:
+  console:
+    enabled: true
+server:
+  prot: 8080
+`)
+	t.Setenv("OUTPUT_SIMPLEPAGE", "true")
+
+	cfg, err := Load(path)
+	assert.NoError(t, err)
+
+	err = cfg.Validate()
+	assert.ErrorContains(t, err, path+` line 6: unknown key "chanel"`)
+	assert.ErrorContains(t, err, path+` line 11: unknown key "prot"`)
+	assert.ErrorContains(t, err, "unknown environment variable OUTPUT_SIMPLEPAGE, did you mean OUTPUT_WEBPAGE?")
+	assert.ErrorContains(t, err, "providers[0] (Twitch): missing twitch.channel")
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// WatchInterval is how often Watch checks the configuration file for changes.
+const WatchInterval = 2 * time.Second
+
+// Watch calls changed when the file at path is modified or when the process receives SIGHUP,
+// until ctx is done. The caller reloads and validates the configuration, so command line
+// overrides can be applied again. The path is resolved like Load does, when there is no file
+// only SIGHUP triggers a reload.
+func Watch(ctx context.Context, path string, interval time.Duration, changed func()) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	path = resolvePath(path)
+	last := fileVersion(path)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			last = fileVersion(path)
+			changed()
+		case <-ticker.C:
+			if path == "" {
+				continue
+			}
+			// A missing file is usually an editor replacing it, the change is picked up once it is back
+			version := fileVersion(path)
+			if version.IsZero() || version.Equal(last) {
+				continue
+			}
+			last = version
+			changed()
+		}
+	}
+}
+
+// fileVersion returns the modification time of the file, or the zero time when it can not be read.
+func fileVersion(path string) time.Time {
+	if path == "" {
+		return time.Time{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
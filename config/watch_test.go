@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	path := writeConfig(t, "")
+	modified := time.Now().Add(-time.Hour)
+	touch := func() {
+		modified = modified.Add(time.Second)
+		assert.NoError(t, os.Chtimes(path, modified, modified))
+	}
+	touch()
+
+	changed := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go Watch(ctx, path, 5*time.Millisecond, func() { changed <- struct{}{} })
+
+	// The watcher may not have seen the first version yet, so the file is changed until it notices
+	assert.Eventually(t, func() bool {
+		touch()
+		select {
+		case <-changed:
+			return true
+		case <-time.After(20 * time.Millisecond):
+			return false
+		}
+	}, time.Second, time.Millisecond)
+
+	// Forget a change noticed late by a previous attempt
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-changed:
+	default:
+	}
+
+	// A file that disappears is not a change
+	assert.NoError(t, os.Remove(path))
+	select {
+	case <-changed:
+		t.Fatal("a missing file must not trigger a reload")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("SIGHUP can not be sent on Windows")
+	}
+	process, err := os.FindProcess(os.Getpid())
+	assert.NoError(t, err)
+	assert.NoError(t, process.Signal(syscall.SIGHUP))
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Fatal("SIGHUP did not trigger a reload")
+	}
+}
@@ -1,8 +1,10 @@
 package aggregator
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"sync"
 
 	"github.com/SergioCurto/ChatClient/config"
@@ -12,101 +14,601 @@ import (
 )
 
 type Aggregator struct {
-	providers []chatproviders.ChatProvider
-	consumers []chatconsumers.ChatConsumer
-	messages  chan chatmodels.ChatMessage
-	cfg       *config.Config
-	stop      chan struct{}
-	wg        sync.WaitGroup
+	// mux guards the providers, the consumers and the settings that can change while running
+	mux        sync.RWMutex
+	providers  []*providerEntry
+	consumers  []*consumerQueue
+	messages   chan chatmodels.ChatMessage
+	events     chan chatmodels.ChatEvent
+	moderation chan chatmodels.ModerationEvent
+	statuses   chan chatmodels.ProviderStatus
+	cfg        *config.Config
+	backoff    Backoff
+	filter     messageFilter
+
+	// lifecycleMux serializes Start, Stop and ApplyConfig
+	lifecycleMux sync.Mutex
+	// ctx is the parent of the provider supervisors and cancel stops them, they are only set while running
+	ctx    context.Context
+	cancel context.CancelFunc
+	// drain is closed once the providers are stopped to flush the consumer queues
+	drain      chan struct{}
+	running    []*consumerQueue
+	dispatchWg sync.WaitGroup
+
+	// The factories create the providers and consumers described by ApplyConfig
+	providerFactory chatproviders.ChatProviderFactory
+	consumerFactory chatconsumers.ChatConsumerFactory
+	// configured holds the consumers created by ApplyConfig, by type
+	configured map[chatconsumers.ChatConsumerType]*consumerQueue
+
+	providerStatus map[string]chatmodels.ProviderStatus
+	statusMux      sync.Mutex
+}
+
+// providerEntry is a registered provider and the supervisor keeping it connected.
+type providerEntry struct {
+	provider chatproviders.ChatProvider
+	// config is the instance the provider was created from, nil when it was added directly
+	config *config.ProviderConfig
+	// cancel stops the supervisor and done is closed when it returns, they are set while running
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 func NewAggregator(cfg *config.Config) *Aggregator {
 	return &Aggregator{
-		messages: make(chan chatmodels.ChatMessage),
-		cfg:      cfg,
+		messages:        make(chan chatmodels.ChatMessage),
+		events:          make(chan chatmodels.ChatEvent),
+		moderation:      make(chan chatmodels.ModerationEvent),
+		statuses:        make(chan chatmodels.ProviderStatus),
+		cfg:             cfg,
+		backoff:         NewBackoff(cfg.Aggregator.RetryInitialDelay, cfg.Aggregator.RetryMaxDelay, cfg.Aggregator.RetryMax),
+		filter:          newMessageFilter(cfg.Filters),
+		providerFactory: chatproviders.NewConcreteChatProviderFactory(),
+		consumerFactory: chatconsumers.NewConcreteChatConsumerFactory(),
+		configured:      make(map[chatconsumers.ChatConsumerType]*consumerQueue),
+		providerStatus:  make(map[string]chatmodels.ProviderStatus),
+	}
+}
+
+// AddProvider registers a provider, provider names must be unique. When the aggregator is running
+// the provider is connected at once and supervised until it is removed or the aggregator stops.
+func (a *Aggregator) AddProvider(provider chatproviders.ChatProvider) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
+	return a.addProvider(&providerEntry{provider: provider})
+}
+
+// RemoveProvider unregisters the provider with the given name. When the aggregator is running
+// the provider is disconnected, waiting for it until ctx is done.
+func (a *Aggregator) RemoveProvider(ctx context.Context, name string) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
+
+	a.mux.RLock()
+	index := slices.IndexFunc(a.providers, func(e *providerEntry) bool { return e.provider.GetName() == name })
+	var entry *providerEntry
+	if index >= 0 {
+		entry = a.providers[index]
+	}
+	a.mux.RUnlock()
+	if entry == nil {
+		return fmt.Errorf("unknown provider: %s", name)
 	}
+	return a.removeProvider(ctx, entry)
 }
 
-func (a *Aggregator) AddProvider(provider chatproviders.ChatProvider) {
-	a.providers = append(a.providers, provider)
+// AddConsumer registers a consumer using the queue options from the configuration.
+// When the aggregator is running the consumer is started at once and receives the messages
+// published from then on.
+func (a *Aggregator) AddConsumer(consumer chatconsumers.ChatConsumer) error {
+	return a.AddConsumerWithQueue(consumer, a.defaultQueueOptions())
 }
 
-func (a *Aggregator) AddConsumer(consumer chatconsumers.ChatConsumer) {
-	a.consumers = append(a.consumers, consumer)
+// AddConsumerWithQueue registers a consumer with its own delivery queue options, consumer names
+// must be unique. When the aggregator is running the consumer is started at once.
+func (a *Aggregator) AddConsumerWithQueue(consumer chatconsumers.ChatConsumer, options QueueOptions) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
+	return a.addConsumer(a.ctx, newConsumerQueue(consumer, options))
 }
 
-func (a *Aggregator) Start() error {
-	if a.stop != nil {
+// RemoveConsumer unregisters the consumer with the given name. When the aggregator is running
+// the messages already queued are delivered and the consumer is stopped, until ctx is done.
+func (a *Aggregator) RemoveConsumer(ctx context.Context, name string) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
+
+	a.mux.RLock()
+	index := slices.IndexFunc(a.consumers, func(q *consumerQueue) bool { return q.consumer.GetName() == name })
+	var queue *consumerQueue
+	if index >= 0 {
+		queue = a.consumers[index]
+	}
+	a.mux.RUnlock()
+	if queue == nil {
+		return fmt.Errorf("unknown consumer: %s", name)
+	}
+
+	// A configured consumer removed by hand is created again by the next ApplyConfig
+	for consumerType, configured := range a.configured {
+		if configured == queue {
+			delete(a.configured, consumerType)
+		}
+	}
+	return a.removeConsumer(ctx, queue)
+}
+
+func (a *Aggregator) defaultQueueOptions() QueueOptions {
+	cfg := a.config()
+	policy, err := ParseOverflowPolicy(cfg.Aggregator.QueueOverflow)
+	if err != nil {
+		fmt.Println("Using default consumer queue overflow policy:", err)
+	}
+	return QueueOptions{
+		Size:   cfg.Aggregator.QueueSize,
+		Policy: policy,
+	}
+}
+
+// config returns the configuration currently applied.
+func (a *Aggregator) config() *config.Config {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	return a.cfg
+}
+
+// Start starts the consumers and then connects the providers.
+// Providers run until Stop is called or ctx is cancelled.
+func (a *Aggregator) Start(ctx context.Context) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
+
+	if a.cancel != nil {
 		return errors.New("aggregator already started")
 	}
 
-	if (len(a.providers) == 0) || (len(a.consumers) == 0) {
+	a.mux.Lock()
+	providers := slices.Clone(a.providers)
+	consumers := slices.Clone(a.consumers)
+	a.running = nil
+	a.mux.Unlock()
+
+	if (len(providers) == 0) || (len(consumers) == 0) {
 		return errors.New("no providers or consumers added")
 	}
 
-	a.messages = make(chan chatmodels.ChatMessage)
+	fmt.Println("Starting consumers...")
+	for _, queue := range consumers {
+		if err := a.startConsumer(ctx, queue); err != nil {
+			fmt.Println("Ignoring consumer with error during start:", queue.consumer.GetName(), err)
+		}
+	}
+
+	a.mux.RLock()
+	started := len(a.running)
+	a.mux.RUnlock()
+	if started == 0 {
+		return errors.New("no consumers started")
+	}
+	fmt.Println("Consumers started")
 
-	a.stop = make(chan struct{})
+	a.drain = make(chan struct{})
+	a.dispatchWg.Add(1)
+	go a.dispatch(a.drain)
 
-	for _, provider := range a.providers {
-		a.wg.Add(1)
-		go func(p chatproviders.ChatProvider) {
-			defer a.wg.Done()
+	a.ctx, a.cancel = context.WithCancel(ctx)
+	for _, entry := range providers {
+		a.startProvider(entry)
+	}
 
-			err := p.Connect(a.cfg)
-			if err != nil {
-				fmt.Println("Error connecting to provider:", p.GetName(), err)
-				return
-			}
-			err = p.Listen(a.messages)
-			if err != nil {
-				fmt.Println("Error on provider:", p.GetName(), err)
-			}
-			<-a.stop // Wait for the stop signal before disconnecting
-			fmt.Println("Stopping provider:", p.GetName())
-			p.Disconnect()
-		}(provider)
+	return nil
+}
+
+// addProvider registers a provider and starts it when the aggregator is running.
+// The caller must hold lifecycleMux, like for the other registration helpers.
+func (a *Aggregator) addProvider(entry *providerEntry) error {
+	a.mux.Lock()
+	name := entry.provider.GetName()
+	if slices.ContainsFunc(a.providers, func(e *providerEntry) bool { return e.provider.GetName() == name }) {
+		a.mux.Unlock()
+		return fmt.Errorf("provider %s is already added", name)
+	}
+	a.providers = append(a.providers, entry)
+	a.mux.Unlock()
+
+	if a.cancel != nil {
+		a.startProvider(entry)
+	}
+	return nil
+}
+
+// removeProvider unregisters a provider and stops it when it is running.
+func (a *Aggregator) removeProvider(ctx context.Context, entry *providerEntry) error {
+	a.mux.Lock()
+	a.providers = slices.DeleteFunc(a.providers, func(e *providerEntry) bool { return e == entry })
+	a.mux.Unlock()
+
+	return a.stopProvider(ctx, entry)
+}
+
+// addConsumer registers a consumer and starts it when the aggregator is running.
+func (a *Aggregator) addConsumer(ctx context.Context, queue *consumerQueue) error {
+	a.mux.Lock()
+	name := queue.consumer.GetName()
+	if slices.ContainsFunc(a.consumers, func(q *consumerQueue) bool { return q.consumer.GetName() == name }) {
+		a.mux.Unlock()
+		return fmt.Errorf("consumer %s is already added", name)
+	}
+	a.consumers = append(a.consumers, queue)
+	a.mux.Unlock()
+
+	if a.cancel == nil {
+		return nil
+	}
+	if err := a.startConsumer(ctx, queue); err != nil {
+		a.mux.Lock()
+		a.consumers = slices.DeleteFunc(a.consumers, func(q *consumerQueue) bool { return q == queue })
+		a.mux.Unlock()
+		return err
+	}
+	return nil
+}
+
+// removeConsumer unregisters a consumer and stops it when it is running.
+func (a *Aggregator) removeConsumer(ctx context.Context, queue *consumerQueue) error {
+	a.mux.Lock()
+	a.consumers = slices.DeleteFunc(a.consumers, func(q *consumerQueue) bool { return q == queue })
+	running := slices.Contains(a.running, queue)
+	a.mux.Unlock()
+
+	if !running {
+		return nil
+	}
+	return a.stopConsumer(ctx, queue)
+}
+
+// startProvider launches the supervisor of a provider, the aggregator must be running.
+func (a *Aggregator) startProvider(entry *providerEntry) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	entry.cancel = cancel
+	entry.done = make(chan struct{})
+	go func(done chan struct{}) {
+		defer close(done)
+		a.superviseProvider(ctx, entry.provider)
+	}(entry.done)
+}
+
+// waitProvider waits for the supervisor of a provider to return, or for ctx to end.
+func waitProvider(ctx context.Context, entry *providerEntry) error {
+	if entry.done == nil {
+		return nil
+	}
+	select {
+	case <-entry.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopProvider disconnects a running provider and lets the consumers know it is gone.
+func (a *Aggregator) stopProvider(ctx context.Context, entry *providerEntry) error {
+	if entry.cancel == nil {
+		return nil
+	}
+	entry.cancel()
+	if err := waitProvider(ctx, entry); err != nil {
+		return err
+	}
+	entry.cancel, entry.done = nil, nil
+
+	a.publishStatus(ctx, entry.provider, chatmodels.ProviderStatus{State: chatmodels.ProviderDisconnected})
+	a.statusMux.Lock()
+	delete(a.providerStatus, entry.provider.GetName())
+	a.statusMux.Unlock()
+	return nil
+}
+
+// startConsumer starts a consumer and its delivery queue, it receives messages from then on.
+func (a *Aggregator) startConsumer(ctx context.Context, queue *consumerQueue) error {
+	if consumer, ok := queue.consumer.(chatconsumers.ModeratingConsumer); ok {
+		consumer.SetModerator(a.Moderate)
 	}
+	if consumer, ok := queue.consumer.(chatconsumers.MonitoringConsumer); ok {
+		consumer.SetMonitor(a.GetProviderStatuses, a.GetConsumerHealth)
+	}
+	if err := queue.consumer.Start(ctx, a.config()); err != nil {
+		return err
+	}
+	queue.start()
+
+	a.mux.Lock()
+	a.running = append(a.running, queue)
+	a.mux.Unlock()
+	return nil
+}
 
-	a.wg.Add(1)
+// stopConsumer stops delivering to a running consumer, waits for its queue to empty and stops it.
+func (a *Aggregator) stopConsumer(ctx context.Context, queue *consumerQueue) error {
+	// Release the fan-out first in case it is blocked on this queue, close waits for its push
+	queue.abort()
+
+	a.mux.Lock()
+	a.running = slices.DeleteFunc(a.running, func(q *consumerQueue) bool { return q == queue })
+	a.mux.Unlock()
+
+	closed := make(chan struct{})
 	go func() {
-		defer a.wg.Done()
-
-		fmt.Println("Starting consumers...")
-		for _, consumer := range a.consumers {
-			go func() {
-				err := consumer.Start(a.cfg)
-				if err != nil {
-					fmt.Println("Ignoring consumer with error during start:", consumer.GetName(), err)
+		queue.close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-ctx.Done():
+		return fmt.Errorf("error delivering pending messages: %w", ctx.Err())
+	}
+
+	return queue.consumer.Stop(ctx)
+}
+
+// dispatch forwards messages, events, moderation and statuses to the consumer queues until drain is closed.
+// Each consumer has its own ordered queue, so a slow consumer only delays itself.
+func (a *Aggregator) dispatch(drain <-chan struct{}) {
+	defer a.dispatchWg.Done()
+
+	// The queues are pushed to without holding the lock, a blocked queue must not stall the
+	// registrations and the provider supervision
+	push := func(item any) {
+		a.mux.RLock()
+		queues := slices.Clone(a.running)
+		a.mux.RUnlock()
+		for _, queue := range queues {
+			queue.push(item)
+		}
+	}
+	pushMessage := func(msg chatmodels.ChatMessage) {
+		a.mux.RLock()
+		allowed := a.filter.allows(msg)
+		a.mux.RUnlock()
+		if allowed {
+			push(msg)
+		}
+	}
+
+	for {
+		select {
+		case msg := <-a.messages:
+			pushMessage(msg)
+		case event := <-a.events:
+			push(event)
+		case moderation := <-a.moderation:
+			push(moderation)
+		case status := <-a.statuses:
+			push(status)
+		case <-drain:
+			// Providers are stopped, forward anything still pending and wait for the queues to empty
+			for {
+				select {
+				case msg := <-a.messages:
+					pushMessage(msg)
+				case event := <-a.events:
+					push(event)
+				case moderation := <-a.moderation:
+					push(moderation)
+				case status := <-a.statuses:
+					push(status)
+				default:
+					a.mux.RLock()
+					queues := slices.Clone(a.running)
+					a.mux.RUnlock()
+					for _, queue := range queues {
+						queue.close()
+					}
+					return
 				}
-			}()
+			}
 		}
+	}
+}
 
-		fmt.Println("Consumers started")
+// Stop shuts down the aggregator in order: providers are stopped, pending messages are
+// delivered and then the consumers are stopped. It returns an error if any step does not
+// complete before the ctx deadline.
+func (a *Aggregator) Stop(ctx context.Context) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
 
-		for msg := range a.messages {
-			for _, consumer := range a.consumers {
-				go consumer.Consume(msg)
-			}
+	if a.cancel == nil {
+		return nil
+	}
+	a.cancel()
+	a.cancel = nil
+
+	a.mux.RLock()
+	providers := slices.Clone(a.providers)
+	running := slices.Clone(a.running)
+	a.mux.RUnlock()
+
+	var errs []error
+
+	for _, entry := range providers {
+		if err := waitProvider(ctx, entry); err != nil {
+			errs = append(errs, fmt.Errorf("error stopping providers: %w", err))
+			break
+		}
+		entry.cancel, entry.done = nil, nil
+	}
+
+	close(a.drain)
+	if err := waitContext(ctx, &a.dispatchWg); err != nil {
+		for _, queue := range running {
+			queue.abort() // Release the fan-out if it is blocked on a full queue
+		}
+		errs = append(errs, fmt.Errorf("error delivering pending messages: %w", err))
+	}
+	// The consumers are stopped below, so removing them afterwards does not stop them again
+	a.mux.Lock()
+	a.running = nil
+	a.mux.Unlock()
+
+	for _, queue := range running {
+		if err := queue.consumer.Stop(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error stopping consumer %s: %w", queue.consumer.GetName(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Broadcast sends a message to every provider able to send chat.
+// Providers that fail do not stop the others, their errors are returned together.
+func (a *Aggregator) Broadcast(ctx context.Context, text string) error {
+	var errs []error
+	sent := 0
+	for _, provider := range a.getProviders() {
+		sender, ok := provider.(chatproviders.ChatSender)
+		if !ok {
+			continue
+		}
+		sent++
+		if err := sender.SendMessage(ctx, text); err != nil {
+			errs = append(errs, fmt.Errorf("error sending to %s: %w", provider.GetName(), err))
+		}
+	}
+
+	if sent == 0 {
+		return errors.New("no providers able to send messages")
+	}
+	return errors.Join(errs...)
+}
+
+// SendTo sends a message to the provider with the given name.
+func (a *Aggregator) SendTo(ctx context.Context, providerName string, text string) error {
+	provider, err := a.findProvider(providerName)
+	if err != nil {
+		return err
+	}
+	sender, ok := provider.(chatproviders.ChatSender)
+	if !ok {
+		return fmt.Errorf("provider %s can not send messages", providerName)
+	}
+	return sender.SendMessage(ctx, text)
+}
+
+// Moderate applies a moderation request with the provider named in the request.
+func (a *Aggregator) Moderate(ctx context.Context, request chatmodels.ModerationRequest) error {
+	provider, err := a.findProvider(request.Provider)
+	if err != nil {
+		return err
+	}
+	moderator, ok := provider.(chatproviders.ChatModerator)
+	if !ok {
+		return fmt.Errorf("provider %s can not moderate", request.Provider)
+	}
+
+	switch request.Action {
+	case chatmodels.ModerationDeleteMessage:
+		if request.MessageID == "" {
+			return errors.New("missing message ID to delete")
+		}
+		return moderator.DeleteMessage(ctx, request.MessageID)
+	case chatmodels.ModerationTimeout:
+		if request.AuthorID == "" {
+			return errors.New("missing user ID to time out")
+		}
+		return moderator.TimeoutUser(ctx, request.AuthorID, request.Duration, request.Reason)
+	case chatmodels.ModerationBan:
+		if request.AuthorID == "" {
+			return errors.New("missing user ID to ban")
 		}
+		return moderator.BanUser(ctx, request.AuthorID, request.Reason)
+	case chatmodels.ModerationUnban:
+		if request.AuthorID == "" {
+			return errors.New("missing user ID to unban")
+		}
+		return moderator.UnbanUser(ctx, request.AuthorID)
+	default:
+		return fmt.Errorf("unsupported moderation action: %q", request.Action)
+	}
+}
+
+func (a *Aggregator) findProvider(name string) (chatproviders.ChatProvider, error) {
+	for _, provider := range a.getProviders() {
+		if provider.GetName() == name {
+			return provider, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown provider: %s", name)
+}
+
+// waitContext waits for the wait group or returns the context error if it ends first.
+func waitContext(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
 	}()
 
-	return nil
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (a *Aggregator) Stop() {
-	if a.stop != nil {
-		close(a.stop)
-		a.stop = nil
-		close(a.messages) // Close the messages channel to evict the consumers
-		a.wg.Wait()
+// getProviders returns a snapshot of the registered providers.
+func (a *Aggregator) getProviders() []chatproviders.ChatProvider {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	providers := make([]chatproviders.ChatProvider, 0, len(a.providers))
+	for _, entry := range a.providers {
+		providers = append(providers, entry.provider)
 	}
+	return providers
 }
 
 func (a *Aggregator) GetProvidersCount() int {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
 	return len(a.providers)
 }
 
 func (a *Aggregator) GetConsumersCount() int {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
 	return len(a.consumers)
 }
+
+// GetConsumerStats returns a snapshot of the delivery queue of every consumer.
+func (a *Aggregator) GetConsumerStats() []ConsumerStats {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	stats := make([]ConsumerStats, 0, len(a.consumers))
+	for _, queue := range a.consumers {
+		stats = append(stats, queue.stats())
+	}
+	return stats
+}
+
+// GetConsumerHealth returns the delivery state of every consumer.
+func (a *Aggregator) GetConsumerHealth() []chatmodels.ConsumerHealth {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	health := make([]chatmodels.ConsumerHealth, 0, len(a.consumers))
+	for _, queue := range a.consumers {
+		stats := queue.stats()
+		health = append(health, chatmodels.ConsumerHealth{
+			Consumer:  stats.Name,
+			Running:   slices.Contains(a.running, queue),
+			Policy:    stats.Policy.String(),
+			Capacity:  stats.Capacity,
+			Queued:    stats.Queued,
+			Delivered: stats.Delivered,
+			Dropped:   stats.Dropped,
+		})
+	}
+	return health
+}
@@ -1,7 +1,11 @@
 package aggregator
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -21,33 +25,66 @@ type MockChatProvider struct {
 	Connected     bool
 	Disconnected  bool
 	ListenCalled  bool
-	stopListen    chan struct{}
+	ConnectCount  int
+	mux           sync.Mutex
 }
 
-func (m *MockChatProvider) Connect(cfx *config.Config) error {
+func (m *MockChatProvider) Connect(ctx context.Context) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
 	m.Connected = true
+	m.ConnectCount++
 	return m.ConnectErr
 }
 
 func (m *MockChatProvider) Disconnect() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
 	m.Disconnected = true
-	close(m.stopListen)
 	return m.DisconnectErr
 }
 
-func (m *MockChatProvider) Listen(messages chan<- chatmodels.ChatMessage) error {
-	m.stopListen = make(chan struct{})
+func (m *MockChatProvider) Listen(ctx context.Context, messages chan<- chatmodels.ChatMessage) error {
+	m.mux.Lock()
 	m.ListenCalled = true
-	go func() {
-		for _, msg := range m.Messages {
-			select {
-			case messages <- msg:
-			case <-m.stopListen:
-				return
-			}
+	m.mux.Unlock()
+
+	if m.ListenErr != nil {
+		return m.ListenErr
+	}
+	for _, msg := range m.Messages {
+		select {
+		case messages <- msg:
+		case <-ctx.Done():
+			return nil
 		}
-	}()
-	return m.ListenErr
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (m *MockChatProvider) GetConnectCount() int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.ConnectCount
+}
+
+func (m *MockChatProvider) IsConnected() bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.Connected
+}
+
+func (m *MockChatProvider) IsDisconnected() bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.Disconnected
+}
+
+func (m *MockChatProvider) IsListenCalled() bool {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.ListenCalled
 }
 
 func (m *MockChatProvider) GetName() string {
@@ -64,18 +101,42 @@ type MockChatConsumer struct {
 	ConsumedCount    int
 	ConsumedMessages []chatmodels.ChatMessage
 	StartCalled      bool
+	StopCalled       bool
+	StopCount        int
+	StartErr         error
+	mux              sync.Mutex
 }
 
-func (m *MockChatConsumer) Start(cfg *config.Config) error {
+func (m *MockChatConsumer) Start(ctx context.Context, cfg *config.Config) error {
 	m.StartCalled = true
+	return m.StartErr
+}
+
+func (m *MockChatConsumer) Stop(ctx context.Context) error {
+	m.StopCalled = true
+	m.StopCount++
 	return nil
 }
 
 func (m *MockChatConsumer) Consume(message chatmodels.ChatMessage) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
 	m.ConsumedCount++
 	m.ConsumedMessages = append(m.ConsumedMessages, message)
 }
 
+func (m *MockChatConsumer) GetConsumedCount() int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.ConsumedCount
+}
+
+func (m *MockChatConsumer) GetConsumedMessages() []chatmodels.ChatMessage {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return append([]chatmodels.ChatMessage(nil), m.ConsumedMessages...)
+}
+
 func (m *MockChatConsumer) GetName() string {
 	return m.Name
 }
@@ -88,28 +149,135 @@ func TestNewAggregator(t *testing.T) {
 	assert.Equal(t, cfg, agg.cfg)
 	assert.Empty(t, agg.providers)
 	assert.Empty(t, agg.consumers)
-	assert.Nil(t, agg.stop)
+	assert.Nil(t, agg.cancel)
 }
 
 func TestAggregator_AddProvider(t *testing.T) {
 	agg := NewAggregator(&config.Config{})
 	provider := &MockChatProvider{Name: "TestProvider", ShortName: "TP"}
-	agg.AddProvider(provider)
+	assert.NoError(t, agg.AddProvider(provider))
+	assert.Len(t, agg.providers, 1)
+	assert.Equal(t, provider, agg.providers[0].provider)
+
+	assert.EqualError(t, agg.AddProvider(&MockChatProvider{Name: "TestProvider"}), "provider TestProvider is already added")
 	assert.Len(t, agg.providers, 1)
-	assert.Equal(t, provider, agg.providers[0])
 }
 
 func TestAggregator_AddConsumer(t *testing.T) {
 	agg := NewAggregator(&config.Config{})
 	consumer := &MockChatConsumer{Name: "TestConsumer"}
-	agg.AddConsumer(consumer)
+	assert.NoError(t, agg.AddConsumer(consumer))
+	assert.Len(t, agg.consumers, 1)
+	assert.Equal(t, consumer, agg.consumers[0].consumer)
+
+	assert.EqualError(t, agg.AddConsumer(&MockChatConsumer{Name: "TestConsumer"}), "consumer TestConsumer is already added")
 	assert.Len(t, agg.consumers, 1)
-	assert.Equal(t, consumer, agg.consumers[0])
+}
+
+// CountingChatConsumer counts the messages it receives, the count can be read while running
+type CountingChatConsumer struct {
+	MockChatConsumer
+	count atomic.Int64
+}
+
+func (c *CountingChatConsumer) Consume(message chatmodels.ChatMessage) {
+	c.count.Add(1)
+}
+
+func TestAggregator_AddAndRemoveProvider_WhileRunning(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	consumer := &CountingChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Counter"}}
+	assert.NoError(t, agg.AddProvider(&MockChatProvider{Name: "Main"}))
+	assert.NoError(t, agg.AddConsumer(consumer))
+	assert.NoError(t, agg.Start(context.Background()))
+
+	// A raid target joins for a while and then leaves
+	raid := &MockChatProvider{Name: "Raid", Messages: []chatmodels.ChatMessage{{Provider: "Raid", Content: "hello"}}}
+	assert.NoError(t, agg.AddProvider(raid))
+	assert.Eventually(t, func() bool { return consumer.count.Load() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, agg.GetProvidersCount())
+
+	assert.NoError(t, agg.RemoveProvider(context.Background(), "Raid"))
+	assert.True(t, raid.Disconnected)
+	assert.Equal(t, 1, agg.GetProvidersCount())
+	assert.Len(t, agg.GetProviderStatuses(), 1)
+	assert.EqualError(t, agg.RemoveProvider(context.Background(), "Raid"), "unknown provider: Raid")
+
+	assert.NoError(t, agg.Stop(context.Background()))
+}
+
+func TestAggregator_AddAndRemoveConsumer_WhileRunning(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	provider := &MockChatProvider{Name: "Main"}
+	assert.NoError(t, agg.AddProvider(provider))
+	assert.NoError(t, agg.AddConsumer(&MockChatConsumer{Name: "First"}))
+	assert.NoError(t, agg.Start(context.Background()))
+
+	late := &CountingChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Late"}}
+	assert.NoError(t, agg.AddConsumer(late))
+	assert.True(t, late.StartCalled)
+	assert.Equal(t, 2, agg.GetConsumersCount())
+
+	// Consumers that fail to start are not kept
+	assert.EqualError(t, agg.AddConsumer(&MockChatConsumer{Name: "Broken", StartErr: errors.New("port in use")}), "port in use")
+	assert.Equal(t, 2, agg.GetConsumersCount())
+
+	assert.NoError(t, agg.RemoveConsumer(context.Background(), "Late"))
+	assert.True(t, late.StopCalled)
+	assert.Equal(t, 1, agg.GetConsumersCount())
+	assert.EqualError(t, agg.RemoveConsumer(context.Background(), "Late"), "unknown consumer: Late")
+
+	assert.NoError(t, agg.Stop(context.Background()))
+}
+
+func TestAggregator_RemoveConsumer_AfterStop(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	consumer := &MockChatConsumer{Name: "C"}
+	assert.NoError(t, agg.AddProvider(&MockChatProvider{Name: "Main"}))
+	assert.NoError(t, agg.AddConsumer(consumer))
+	assert.NoError(t, agg.Start(context.Background()))
+	assert.NoError(t, agg.Stop(context.Background()))
+
+	// Stop already stopped the consumer and closed its queue
+	assert.NoError(t, agg.RemoveConsumer(context.Background(), "C"))
+	assert.Equal(t, 1, consumer.StopCount)
+	assert.Equal(t, 0, agg.GetConsumersCount())
+}
+
+func TestAggregator_RuntimeChanges_Concurrent(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	assert.NoError(t, agg.AddProvider(&MockChatProvider{Name: "Main"}))
+	assert.NoError(t, agg.AddConsumer(&CountingChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Counter"}}))
+	assert.NoError(t, agg.Start(context.Background()))
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			name := fmt.Sprint("Provider", i)
+			messages := make([]chatmodels.ChatMessage, 20)
+			for round := range 5 {
+				assert.NoError(t, agg.AddProvider(&MockChatProvider{Name: name, Messages: messages}))
+				consumer := fmt.Sprint("Consumer", i, round)
+				assert.NoError(t, agg.AddConsumer(&CountingChatConsumer{MockChatConsumer: MockChatConsumer{Name: consumer}}))
+				agg.GetProviderStatuses()
+				agg.GetConsumerStats()
+				assert.NoError(t, agg.RemoveConsumer(context.Background(), consumer))
+				assert.NoError(t, agg.RemoveProvider(context.Background(), name))
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, agg.GetProvidersCount())
+	assert.Equal(t, 1, agg.GetConsumersCount())
+	assert.NoError(t, agg.Stop(context.Background()))
 }
 
 func TestAggregator_Start_NoProvidersOrConsumers(t *testing.T) {
 	agg := NewAggregator(&config.Config{})
-	err := agg.Start()
+	err := agg.Start(context.Background())
 	assert.Error(t, err)
 	assert.Equal(t, "no providers or consumers added", err.Error())
 }
@@ -119,11 +287,11 @@ func TestAggregator_Start_ProviderConnectError(t *testing.T) {
 	provider := &MockChatProvider{Name: "TestProvider", ConnectErr: errors.New("connect error")}
 	agg.AddProvider(provider)
 	agg.AddConsumer(&MockChatConsumer{Name: "TestConsumer"})
-	err := agg.Start()
+	err := agg.Start(context.Background())
 	assert.NoError(t, err)
 	time.Sleep(200 * time.Millisecond) // Allow goroutines to run
-	assert.True(t, provider.Connected)
-	agg.Stop()
+	assert.True(t, provider.IsConnected())
+	assert.NoError(t, agg.Stop(context.Background()))
 }
 
 func TestAggregator_Start_ProviderListenError(t *testing.T) {
@@ -131,12 +299,12 @@ func TestAggregator_Start_ProviderListenError(t *testing.T) {
 	provider := &MockChatProvider{Name: "TestProvider", ListenErr: errors.New("listen error")}
 	agg.AddProvider(provider)
 	agg.AddConsumer(&MockChatConsumer{Name: "TestConsumer"})
-	err := agg.Start()
+	err := agg.Start(context.Background())
 	assert.NoError(t, err)
 	time.Sleep(200 * time.Millisecond) // Allow goroutines to run
-	assert.True(t, provider.Connected)
-	assert.True(t, provider.ListenCalled)
-	agg.Stop()
+	assert.True(t, provider.IsConnected())
+	assert.True(t, provider.IsListenCalled())
+	assert.NoError(t, agg.Stop(context.Background()))
 }
 
 func TestAggregator_Start_Success(t *testing.T) {
@@ -145,23 +313,23 @@ func TestAggregator_Start_Success(t *testing.T) {
 	consumer := &MockChatConsumer{Name: "TestConsumer"}
 	agg.AddProvider(provider)
 	agg.AddConsumer(consumer)
-	err := agg.Start()
+	err := agg.Start(context.Background())
 	assert.NoError(t, err)
 	time.Sleep(200 * time.Millisecond) // Allow goroutines to run
-	assert.True(t, provider.Connected)
-	assert.True(t, provider.ListenCalled)
-	assert.Equal(t, 1, consumer.ConsumedCount)
+	assert.True(t, provider.IsConnected())
+	assert.True(t, provider.IsListenCalled())
+	assert.Equal(t, 1, consumer.GetConsumedCount())
 	assert.True(t, consumer.StartCalled)
-	assert.Equal(t, "Test Message", consumer.ConsumedMessages[0].Content)
-	agg.Stop()
-	assert.True(t, provider.Disconnected)
-	assert.Nil(t, agg.stop)
+	assert.Equal(t, "Test Message", consumer.GetConsumedMessages()[0].Content)
+	assert.NoError(t, agg.Stop(context.Background()))
+	assert.True(t, provider.IsDisconnected())
+	assert.Nil(t, agg.cancel)
 }
 
 func TestAggregator_Stop_NotStarted(t *testing.T) {
 	agg := NewAggregator(&config.Config{})
-	agg.Stop()
-	assert.Nil(t, agg.stop)
+	assert.NoError(t, agg.Stop(context.Background()))
+	assert.Nil(t, agg.cancel)
 }
 
 func TestAggregator_GetProvidersCount(t *testing.T) {
@@ -191,28 +359,299 @@ func TestAggregator_MultipleProvidersAndConsumers(t *testing.T) {
 	agg.AddConsumer(consumer1)
 	agg.AddConsumer(consumer2)
 
-	err := agg.Start()
+	err := agg.Start(context.Background())
 	assert.NoError(t, err)
 
 	time.Sleep(200 * time.Millisecond)
 
-	assert.True(t, provider1.Connected)
-	assert.True(t, provider2.Connected)
-	assert.True(t, provider1.ListenCalled)
-	assert.True(t, provider2.ListenCalled)
+	assert.True(t, provider1.IsConnected())
+	assert.True(t, provider2.IsConnected())
+	assert.True(t, provider1.IsListenCalled())
+	assert.True(t, provider2.IsListenCalled())
 
 	assert.True(t, consumer1.StartCalled)
 	assert.True(t, consumer2.StartCalled)
-	assert.Equal(t, 2, consumer1.ConsumedCount)
-	assert.Equal(t, 2, consumer2.ConsumedCount)
+	assert.Equal(t, 2, consumer1.GetConsumedCount())
+	assert.Equal(t, 2, consumer2.GetConsumedCount())
 
-	assert.Contains(t, consumer1.ConsumedMessages, chatmodels.ChatMessage{Provider: "Provider1", ProviderShortName: "P1", Content: "Message from Provider1"})
-	assert.Contains(t, consumer1.ConsumedMessages, chatmodels.ChatMessage{Provider: "Provider2", ProviderShortName: "P2", Content: "Message from Provider2"})
-	assert.Contains(t, consumer2.ConsumedMessages, chatmodels.ChatMessage{Provider: "Provider1", ProviderShortName: "P1", Content: "Message from Provider1"})
-	assert.Contains(t, consumer2.ConsumedMessages, chatmodels.ChatMessage{Provider: "Provider2", ProviderShortName: "P2", Content: "Message from Provider2"})
+	assert.Contains(t, consumer1.GetConsumedMessages(), chatmodels.ChatMessage{Provider: "Provider1", ProviderShortName: "P1", Content: "Message from Provider1"})
+	assert.Contains(t, consumer1.GetConsumedMessages(), chatmodels.ChatMessage{Provider: "Provider2", ProviderShortName: "P2", Content: "Message from Provider2"})
+	assert.Contains(t, consumer2.GetConsumedMessages(), chatmodels.ChatMessage{Provider: "Provider1", ProviderShortName: "P1", Content: "Message from Provider1"})
+	assert.Contains(t, consumer2.GetConsumedMessages(), chatmodels.ChatMessage{Provider: "Provider2", ProviderShortName: "P2", Content: "Message from Provider2"})
 
-	agg.Stop()
+	assert.NoError(t, agg.Stop(context.Background()))
 	time.Sleep(200 * time.Millisecond)
-	assert.True(t, provider1.Disconnected)
-	assert.True(t, provider2.Disconnected)
+	assert.True(t, provider1.IsDisconnected())
+	assert.True(t, provider2.IsDisconnected())
+}
+
+func TestAggregator_Stop_DeliversPendingMessagesBeforeStoppingConsumers(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	messages := make([]chatmodels.ChatMessage, 0, 50)
+	for i := 0; i < 50; i++ {
+		messages = append(messages, chatmodels.ChatMessage{Provider: "P", Content: "msg"})
+	}
+	provider := &MockChatProvider{Name: "Provider", Messages: messages}
+	consumer := NewBlockingChatConsumer("Slow")
+	agg.AddProvider(provider)
+	agg.AddConsumerWithQueue(consumer, QueueOptions{Size: 100, Policy: OverflowBlock})
+
+	assert.NoError(t, agg.Start(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+	close(consumer.release)
+
+	assert.NoError(t, agg.Stop(context.Background()))
+	assert.Len(t, consumer.consumed, 50)
+	assert.True(t, provider.IsDisconnected())
+}
+
+func TestAggregator_BlockedQueueDoesNotBlockChanges(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	provider := &MockChatProvider{Name: "Main", Messages: []chatmodels.ChatMessage{{Content: "1"}, {Content: "2"}, {Content: "3"}}}
+	stuck := NewBlockingChatConsumer("Stuck")
+	agg.AddProvider(provider)
+	agg.AddConsumerWithQueue(stuck, QueueOptions{Size: 1, Policy: OverflowBlock})
+	assert.NoError(t, agg.Start(context.Background()))
+
+	// One message is being consumed, one is queued and the fan-out waits to queue the last one
+	assert.Eventually(t, func() bool { return agg.GetConsumerStats()[0].Queued == 1 }, time.Second, 5*time.Millisecond)
+	changed := make(chan struct{})
+	go func() {
+		defer close(changed)
+		assert.NoError(t, agg.AddProvider(&MockChatProvider{Name: "Raid"}))
+		assert.NoError(t, agg.AddConsumer(&MockChatConsumer{Name: "Late"}))
+	}()
+	select {
+	case <-changed:
+	case <-time.After(time.Second):
+		t.Error("the changes wait for the blocked queue")
+	}
+
+	close(stuck.release)
+	<-changed
+	assert.Equal(t, 2, agg.GetProvidersCount())
+	assert.NoError(t, agg.Stop(context.Background()))
+	assert.Len(t, stuck.consumed, 3)
+}
+
+func TestAggregator_Stop_DeadlineExceeded(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	provider := &MockChatProvider{Name: "Provider", Messages: []chatmodels.ChatMessage{{Content: "stuck"}}}
+	consumer := NewBlockingChatConsumer("Stuck")
+	agg.AddProvider(provider)
+	agg.AddConsumer(consumer)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := agg.Stop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	close(consumer.release)
+}
+
+func TestAggregator_Start_IgnoresConsumerStartError(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	provider := &MockChatProvider{Name: "Provider", Messages: []chatmodels.ChatMessage{{Content: "Test Message"}}}
+	broken := &MockChatConsumer{Name: "Broken", StartErr: errors.New("port in use")}
+	working := &MockChatConsumer{Name: "Working"}
+	agg.AddProvider(provider)
+	agg.AddConsumer(broken)
+	agg.AddConsumer(working)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, agg.Stop(context.Background()))
+
+	assert.Equal(t, 0, broken.GetConsumedCount())
+	assert.False(t, broken.StopCalled)
+	assert.Equal(t, 1, working.GetConsumedCount())
+	assert.True(t, working.StopCalled)
+}
+
+func TestAggregator_Start_AllConsumersFail(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	agg.AddProvider(&MockChatProvider{Name: "Provider"})
+	agg.AddConsumer(&MockChatConsumer{Name: "Broken", StartErr: errors.New("port in use")})
+
+	err := agg.Start(context.Background())
+	assert.EqualError(t, err, "no consumers started")
+	assert.Nil(t, agg.cancel)
+}
+
+// MockEventProvider publishes events next to its chat messages
+type MockEventProvider struct {
+	MockChatProvider
+	Events     []chatmodels.ChatEvent
+	eventsChan chan<- chatmodels.ChatEvent
+}
+
+func (m *MockEventProvider) SetEventsChannel(events chan<- chatmodels.ChatEvent) {
+	m.eventsChan = events
+}
+
+func (m *MockEventProvider) Listen(ctx context.Context, messages chan<- chatmodels.ChatMessage) error {
+	for _, event := range m.Events {
+		m.eventsChan <- event
+	}
+	return m.MockChatProvider.Listen(ctx, messages)
+}
+
+// MockEventConsumer records the events it receives
+type MockEventConsumer struct {
+	MockChatConsumer
+	ConsumedEvents []chatmodels.ChatEvent
+}
+
+func (m *MockEventConsumer) ConsumeEvent(event chatmodels.ChatEvent) {
+	m.ConsumedEvents = append(m.ConsumedEvents, event)
+}
+
+func TestAggregator_RoutesEventsToEventConsumers(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	provider := &MockEventProvider{
+		MockChatProvider: MockChatProvider{Name: "Provider", Messages: []chatmodels.ChatMessage{{Content: "after the raid"}}},
+		Events:           []chatmodels.ChatEvent{{Kind: chatmodels.EventRaid, AuthorName: "Partner", Viewers: 10}},
+	}
+	eventConsumer := &MockEventConsumer{MockChatConsumer: MockChatConsumer{Name: "Events"}}
+	chatConsumer := &MockChatConsumer{Name: "Chat"}
+	agg.AddProvider(provider)
+	agg.AddConsumer(eventConsumer)
+	agg.AddConsumer(chatConsumer)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	time.Sleep(100 * time.Millisecond)
+	assert.NoError(t, agg.Stop(context.Background()))
+
+	assert.Len(t, eventConsumer.ConsumedEvents, 1)
+	assert.Equal(t, chatmodels.EventRaid, eventConsumer.ConsumedEvents[0].Kind)
+	assert.Equal(t, 1, eventConsumer.GetConsumedCount())
+	assert.Equal(t, 1, chatConsumer.GetConsumedCount())
+}
+
+// MockSenderProvider records the messages sent through it
+type MockSenderProvider struct {
+	MockChatProvider
+	SendErr error
+	Sent    []string
+}
+
+func (m *MockSenderProvider) SendMessage(ctx context.Context, text string) error {
+	if m.SendErr != nil {
+		return m.SendErr
+	}
+	m.Sent = append(m.Sent, text)
+	return nil
+}
+
+func TestAggregator_Broadcast(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	twitch := &MockSenderProvider{MockChatProvider: MockChatProvider{Name: "Twitch"}}
+	youtube := &MockSenderProvider{MockChatProvider: MockChatProvider{Name: "Youtube"}, SendErr: errors.New("quota exceeded")}
+	readOnly := &MockChatProvider{Name: "ReadOnly"}
+	agg.AddProvider(twitch)
+	agg.AddProvider(youtube)
+	agg.AddProvider(readOnly)
+
+	err := agg.Broadcast(context.Background(), "Stream starting!")
+
+	assert.EqualError(t, err, "error sending to Youtube: quota exceeded")
+	assert.Equal(t, []string{"Stream starting!"}, twitch.Sent)
+}
+
+func TestAggregator_Broadcast_NoSenders(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	agg.AddProvider(&MockChatProvider{Name: "ReadOnly"})
+
+	err := agg.Broadcast(context.Background(), "Hello")
+	assert.EqualError(t, err, "no providers able to send messages")
+}
+
+func TestAggregator_SendTo(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	twitch := &MockSenderProvider{MockChatProvider: MockChatProvider{Name: "Twitch"}}
+	youtube := &MockSenderProvider{MockChatProvider: MockChatProvider{Name: "Youtube"}}
+	agg.AddProvider(twitch)
+	agg.AddProvider(youtube)
+	agg.AddProvider(&MockChatProvider{Name: "ReadOnly"})
+
+	assert.NoError(t, agg.SendTo(context.Background(), "Youtube", "Only here"))
+	assert.Empty(t, twitch.Sent)
+	assert.Equal(t, []string{"Only here"}, youtube.Sent)
+
+	assert.EqualError(t, agg.SendTo(context.Background(), "ReadOnly", "Hello"), "provider ReadOnly can not send messages")
+	assert.EqualError(t, agg.SendTo(context.Background(), "Missing", "Hello"), "unknown provider: Missing")
+}
+
+// MockModeratorProvider records the moderation calls made through it
+type MockModeratorProvider struct {
+	MockChatProvider
+	Calls []string
+}
+
+func (m *MockModeratorProvider) DeleteMessage(ctx context.Context, messageID string) error {
+	m.Calls = append(m.Calls, "delete "+messageID)
+	return nil
+}
+
+func (m *MockModeratorProvider) TimeoutUser(ctx context.Context, userID string, duration time.Duration, reason string) error {
+	m.Calls = append(m.Calls, "timeout "+userID+" "+duration.String()+" "+reason)
+	return nil
+}
+
+func (m *MockModeratorProvider) BanUser(ctx context.Context, userID string, reason string) error {
+	m.Calls = append(m.Calls, "ban "+userID+" "+reason)
+	return nil
+}
+
+func (m *MockModeratorProvider) UnbanUser(ctx context.Context, userID string) error {
+	m.Calls = append(m.Calls, "unban "+userID)
+	return nil
+}
+
+func TestAggregator_Moderate(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	provider := &MockModeratorProvider{MockChatProvider: MockChatProvider{Name: "Twitch"}}
+	agg.AddProvider(provider)
+	agg.AddProvider(&MockChatProvider{Name: "ReadOnly"})
+	ctx := context.Background()
+
+	assert.NoError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Twitch", Action: chatmodels.ModerationDeleteMessage, MessageID: "abc"}))
+	assert.NoError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Twitch", Action: chatmodels.ModerationTimeout, AuthorID: "42", Duration: time.Minute, Reason: "spam"}))
+	assert.NoError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Twitch", Action: chatmodels.ModerationBan, AuthorID: "42", Reason: "spam"}))
+	assert.NoError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Twitch", Action: chatmodels.ModerationUnban, AuthorID: "42"}))
+	assert.Equal(t, []string{"delete abc", "timeout 42 1m0s spam", "ban 42 spam", "unban 42"}, provider.Calls)
+
+	assert.EqualError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Twitch", Action: chatmodels.ModerationBan}), "missing user ID to ban")
+	assert.EqualError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Twitch", Action: chatmodels.ModerationClearChat}), `unsupported moderation action: "clear_chat"`)
+	assert.EqualError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "ReadOnly", Action: chatmodels.ModerationBan, AuthorID: "42"}), "provider ReadOnly can not moderate")
+	assert.EqualError(t, agg.Moderate(ctx, chatmodels.ModerationRequest{Provider: "Missing", Action: chatmodels.ModerationBan, AuthorID: "42"}), "unknown provider: Missing")
+}
+
+type MockMonitoringConsumer struct {
+	MockChatConsumer
+	ConsumerHealth func() []chatmodels.ConsumerHealth
+}
+
+func (m *MockMonitoringConsumer) SetMonitor(providerStatuses func() []chatmodels.ProviderStatus, consumerHealth func() []chatmodels.ConsumerHealth) {
+	m.ConsumerHealth = consumerHealth
+}
+
+func TestAggregator_SetsMonitor(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	consumer := &MockMonitoringConsumer{MockChatConsumer: MockChatConsumer{Name: "Monitoring"}}
+	assert.NoError(t, agg.AddConsumer(consumer))
+	assert.NoError(t, agg.AddConsumer(&MockChatConsumer{Name: "Failing", StartErr: errors.New("start failed")}))
+	assert.NoError(t, agg.AddProvider(&MockChatProvider{Name: "TestProvider", ShortName: "TP"}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, agg.Start(ctx))
+	defer agg.Stop(context.Background())
+
+	assert.Equal(t, []chatmodels.ConsumerHealth{
+		{Consumer: "Monitoring", Running: true, Policy: "drop-oldest", Capacity: DefaultQueueSize},
+		{Consumer: "Failing", Running: false, Policy: "drop-oldest", Capacity: DefaultQueueSize},
+	}, consumer.ConsumerHealth())
 }
@@ -0,0 +1,74 @@
+package aggregator
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	DefaultRetryInitialDelay = time.Second
+	DefaultRetryMaxDelay     = 2 * time.Minute
+	// DefaultRetryResetAfter is how long a connection must stay up to be considered healthy
+	DefaultRetryResetAfter = time.Minute
+)
+
+// Backoff computes jittered exponential delays between provider reconnection attempts.
+type Backoff struct {
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	Multiplier   float64
+	// Jitter is the fraction of the delay that is randomized, between 0 and 1
+	Jitter float64
+	// MaxRetries is the number of consecutive failed attempts before giving up, 0 retries forever
+	MaxRetries int
+	// ResetAfter is how long a connection must stay up before its failures are forgotten, so a
+	// provider that connects and then fails right away keeps backing off, 0 never forgets them
+	ResetAfter time.Duration
+}
+
+// NewBackoff creates a Backoff filling unset values with the defaults.
+func NewBackoff(initialDelay, maxDelay time.Duration, maxRetries int) Backoff {
+	if initialDelay <= 0 {
+		initialDelay = DefaultRetryInitialDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryMaxDelay
+	}
+	if maxDelay < initialDelay {
+		maxDelay = initialDelay
+	}
+	return Backoff{
+		InitialDelay: initialDelay,
+		MaxDelay:     maxDelay,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxRetries:   maxRetries,
+		ResetAfter:   DefaultRetryResetAfter,
+	}
+}
+
+// Delay returns the wait before the given attempt, starting at 1.
+func (b Backoff) Delay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(b.InitialDelay) * math.Pow(b.Multiplier, float64(attempt-1))
+	if delay > float64(b.MaxDelay) {
+		delay = float64(b.MaxDelay)
+	}
+	if b.Jitter > 0 {
+		delay += delay * b.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// Exhausted reports whether no more attempts should be made after the given number of failures.
+func (b Backoff) Exhausted(failures int) bool {
+	return b.MaxRetries > 0 && failures > b.MaxRetries
+}
+
+// Healthy reports whether a connection that stayed up for the given time resets the failures.
+func (b Backoff) Healthy(uptime time.Duration) bool {
+	return b.ResetAfter > 0 && uptime >= b.ResetAfter
+}
@@ -0,0 +1,47 @@
+package aggregator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewBackoff_Defaults(t *testing.T) {
+	backoff := NewBackoff(0, 0, 0)
+	assert.Equal(t, DefaultRetryInitialDelay, backoff.InitialDelay)
+	assert.Equal(t, DefaultRetryMaxDelay, backoff.MaxDelay)
+	assert.False(t, backoff.Exhausted(1000))
+	assert.Equal(t, DefaultRetryResetAfter, backoff.ResetAfter)
+}
+
+func TestBackoff_DelayGrowsAndCaps(t *testing.T) {
+	backoff := Backoff{InitialDelay: time.Second, MaxDelay: 10 * time.Second, Multiplier: 2}
+	assert.Equal(t, time.Second, backoff.Delay(1))
+	assert.Equal(t, 2*time.Second, backoff.Delay(2))
+	assert.Equal(t, 8*time.Second, backoff.Delay(4))
+	assert.Equal(t, 10*time.Second, backoff.Delay(5))
+	assert.Equal(t, 10*time.Second, backoff.Delay(50))
+}
+
+func TestBackoff_DelayJitter(t *testing.T) {
+	backoff := Backoff{InitialDelay: time.Second, MaxDelay: time.Minute, Multiplier: 2, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		delay := backoff.Delay(2)
+		assert.GreaterOrEqual(t, delay, time.Second)
+		assert.LessOrEqual(t, delay, 3*time.Second)
+	}
+}
+
+func TestBackoff_Exhausted(t *testing.T) {
+	backoff := NewBackoff(time.Second, time.Minute, 3)
+	assert.False(t, backoff.Exhausted(3))
+	assert.True(t, backoff.Exhausted(4))
+}
+
+func TestBackoff_Healthy(t *testing.T) {
+	backoff := Backoff{ResetAfter: time.Minute}
+	assert.False(t, backoff.Healthy(time.Second))
+	assert.True(t, backoff.Healthy(time.Minute))
+	assert.False(t, Backoff{}.Healthy(time.Hour))
+}
@@ -0,0 +1,36 @@
+package aggregator
+
+import (
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+// messageFilter drops the chat messages excluded by the filters configuration.
+type messageFilter struct {
+	ignoreAuthors  map[string]bool
+	ignoreCommands bool
+}
+
+func newMessageFilter(cfg config.FiltersConfig) messageFilter {
+	filter := messageFilter{
+		ignoreAuthors:  make(map[string]bool, len(cfg.IgnoreAuthors)),
+		ignoreCommands: cfg.IgnoreCommands,
+	}
+	for _, author := range cfg.IgnoreAuthors {
+		filter.ignoreAuthors[strings.ToLower(author)] = true
+	}
+	return filter
+}
+
+// allows reports whether the message is forwarded to the consumers.
+func (f messageFilter) allows(message chatmodels.ChatMessage) bool {
+	if f.ignoreAuthors[strings.ToLower(message.AuthorName)] {
+		return false
+	}
+	if f.ignoreCommands && message.IsCommand() {
+		return false
+	}
+	return true
+}
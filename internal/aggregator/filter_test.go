@@ -0,0 +1,23 @@
+package aggregator
+
+import (
+	"testing"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageFilter(t *testing.T) {
+	filter := newMessageFilter(config.FiltersConfig{IgnoreAuthors: []string{"Nightbot"}, IgnoreCommands: true})
+
+	assert.True(t, filter.allows(chatmodels.ChatMessage{AuthorName: "Viewer", Content: "hello!"}))
+	assert.False(t, filter.allows(chatmodels.ChatMessage{AuthorName: "nightbot", Content: "Follow the channel"}))
+	assert.False(t, filter.allows(chatmodels.ChatMessage{AuthorName: "Viewer", Content: " !uptime"}))
+}
+
+func TestMessageFilter_Empty(t *testing.T) {
+	filter := newMessageFilter(config.FiltersConfig{})
+
+	assert.True(t, filter.allows(chatmodels.ChatMessage{AuthorName: "Nightbot", Content: "!uptime"}))
+}
@@ -0,0 +1,210 @@
+package aggregator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SergioCurto/ChatClient/internal/chatconsumers"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+// DefaultQueueSize is the number of messages buffered per consumer when no size is configured.
+const DefaultQueueSize = 256
+
+// OverflowPolicy decides what happens when a consumer queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropOldest discards the oldest queued message to make room for the new one.
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowDropNewest discards the incoming message and keeps the queue untouched.
+	OverflowDropNewest
+	// OverflowBlock waits until the consumer frees a slot, slowing down the fan-out.
+	OverflowBlock
+)
+
+// String returns the configuration name of the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowDropOldest:
+		return "drop-oldest"
+	case OverflowDropNewest:
+		return "drop-newest"
+	case OverflowBlock:
+		return "block"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// ParseOverflowPolicy converts a configuration value into an OverflowPolicy.
+// An empty value returns the default policy (drop-oldest).
+func ParseOverflowPolicy(value string) (OverflowPolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "drop-oldest":
+		return OverflowDropOldest, nil
+	case "drop-newest":
+		return OverflowDropNewest, nil
+	case "block":
+		return OverflowBlock, nil
+	default:
+		return OverflowDropOldest, fmt.Errorf("unknown overflow policy: %q", value)
+	}
+}
+
+// QueueOptions configures the delivery queue of a single consumer.
+type QueueOptions struct {
+	Size   int
+	Policy OverflowPolicy
+}
+
+// ConsumerStats is a snapshot of the delivery queue of a consumer.
+type ConsumerStats struct {
+	Name      string
+	Policy    OverflowPolicy
+	Capacity  int
+	Queued    int
+	Delivered uint64
+	Dropped   uint64
+}
+
+// consumerQueue delivers messages to a single consumer, in order, from its own goroutine.
+type consumerQueue struct {
+	consumer chatconsumers.ChatConsumer
+	options  QueueOptions
+
+	// items holds chat messages, events, moderation and provider statuses, in the order they were published
+	items chan any
+	done  chan struct{}
+	wg    sync.WaitGroup
+	// closeOnce and abortOnce let a queue be closed and aborted by both Stop and a removal
+	closeOnce sync.Once
+	abortOnce sync.Once
+	// pushMux makes close wait for the push in progress, closed drops the items pushed after it
+	pushMux sync.Mutex
+	closed  bool
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+}
+
+func newConsumerQueue(consumer chatconsumers.ChatConsumer, options QueueOptions) *consumerQueue {
+	if options.Size <= 0 {
+		options.Size = DefaultQueueSize
+	}
+	return &consumerQueue{
+		consumer: consumer,
+		options:  options,
+	}
+}
+
+// start creates the queue buffer and launches the delivery goroutine.
+func (q *consumerQueue) start() {
+	q.items = make(chan any, q.options.Size)
+	q.done = make(chan struct{})
+	q.closeOnce = sync.Once{}
+	q.abortOnce = sync.Once{}
+	q.closed = false
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		for item := range q.items {
+			q.deliver(item)
+			q.delivered.Add(1)
+		}
+	}()
+}
+
+// deliver hands an item to the consumer, skipping optional items the consumer did not opt into.
+func (q *consumerQueue) deliver(item any) {
+	switch v := item.(type) {
+	case chatmodels.ChatMessage:
+		q.consumer.Consume(v)
+	case chatmodels.ChatEvent:
+		if consumer, ok := q.consumer.(chatconsumers.ChatEventConsumer); ok {
+			consumer.ConsumeEvent(v)
+		}
+	case chatmodels.ModerationEvent:
+		if consumer, ok := q.consumer.(chatconsumers.ModerationConsumer); ok {
+			consumer.ConsumeModeration(v)
+		}
+	case chatmodels.ProviderStatus:
+		if consumer, ok := q.consumer.(chatconsumers.StatusConsumer); ok {
+			consumer.ConsumeStatus(v)
+		}
+	}
+}
+
+// push enqueues an item applying the overflow policy when the queue is full.
+// The items pushed once the queue is closed are dropped.
+func (q *consumerQueue) push(item any) {
+	q.pushMux.Lock()
+	defer q.pushMux.Unlock()
+	if q.closed {
+		q.dropped.Add(1)
+		return
+	}
+
+	switch q.options.Policy {
+	case OverflowBlock:
+		select {
+		case q.items <- item:
+		case <-q.done:
+			q.dropped.Add(1)
+		}
+	case OverflowDropNewest:
+		select {
+		case q.items <- item:
+		default:
+			q.dropped.Add(1)
+		}
+	default:
+		for {
+			select {
+			case q.items <- item:
+				return
+			default:
+			}
+			// Queue is full, evict the oldest message and try again
+			select {
+			case <-q.items:
+				q.dropped.Add(1)
+			default:
+			}
+		}
+	}
+}
+
+// abort releases any push blocked on a full queue, so close does not wait for it.
+func (q *consumerQueue) abort() {
+	q.abortOnce.Do(func() { close(q.done) })
+}
+
+// close stops accepting messages and waits for the queued ones to be delivered.
+func (q *consumerQueue) close() {
+	q.closeOnce.Do(func() {
+		q.pushMux.Lock()
+		q.closed = true
+		close(q.items)
+		q.pushMux.Unlock()
+	})
+	q.wg.Wait()
+}
+
+func (q *consumerQueue) stats() ConsumerStats {
+	queued := 0
+	if q.items != nil {
+		queued = len(q.items)
+	}
+	return ConsumerStats{
+		Name:      q.consumer.GetName(),
+		Policy:    q.options.Policy,
+		Capacity:  q.options.Size,
+		Queued:    queued,
+		Delivered: q.delivered.Load(),
+		Dropped:   q.dropped.Load(),
+	}
+}
@@ -0,0 +1,178 @@
+package aggregator
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+// BlockingChatConsumer holds every message until it is released
+type BlockingChatConsumer struct {
+	Name     string
+	release  chan struct{}
+	consumed chan chatmodels.ChatMessage
+}
+
+func NewBlockingChatConsumer(name string) *BlockingChatConsumer {
+	return &BlockingChatConsumer{
+		Name:     name,
+		release:  make(chan struct{}),
+		consumed: make(chan chatmodels.ChatMessage, 100),
+	}
+}
+
+func (b *BlockingChatConsumer) Start(ctx context.Context, cfg *config.Config) error {
+	return nil
+}
+
+func (b *BlockingChatConsumer) Stop(ctx context.Context) error {
+	return nil
+}
+
+func (b *BlockingChatConsumer) Consume(message chatmodels.ChatMessage) {
+	<-b.release
+	b.consumed <- message
+}
+
+func (b *BlockingChatConsumer) GetName() string {
+	return b.Name
+}
+
+func contents(ch chan chatmodels.ChatMessage, count int) []string {
+	result := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		result = append(result, (<-ch).Content)
+	}
+	return result
+}
+
+func TestParseOverflowPolicy(t *testing.T) {
+	policy, err := ParseOverflowPolicy("")
+	assert.NoError(t, err)
+	assert.Equal(t, OverflowDropOldest, policy)
+
+	policy, err = ParseOverflowPolicy("Drop-Newest")
+	assert.NoError(t, err)
+	assert.Equal(t, OverflowDropNewest, policy)
+
+	policy, err = ParseOverflowPolicy("block")
+	assert.NoError(t, err)
+	assert.Equal(t, OverflowBlock, policy)
+
+	_, err = ParseOverflowPolicy("nope")
+	assert.Error(t, err)
+}
+
+func TestConsumerQueue_PreservesOrder(t *testing.T) {
+	consumer := NewBlockingChatConsumer("Ordered")
+	close(consumer.release)
+	queue := newConsumerQueue(consumer, QueueOptions{Size: 4, Policy: OverflowBlock})
+	queue.start()
+
+	for _, content := range []string{"1", "2", "3", "4", "5", "6"} {
+		queue.push(chatmodels.ChatMessage{Content: content})
+	}
+	queue.close()
+
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6"}, contents(consumer.consumed, 6))
+	assert.Equal(t, uint64(6), queue.stats().Delivered)
+	assert.Equal(t, uint64(0), queue.stats().Dropped)
+}
+
+func TestConsumerQueue_DropOldest(t *testing.T) {
+	consumer := NewBlockingChatConsumer("Slow")
+	queue := newConsumerQueue(consumer, QueueOptions{Size: 2, Policy: OverflowDropOldest})
+	queue.start()
+
+	// The first message is picked up by the delivery goroutine and held by the consumer
+	queue.push(chatmodels.ChatMessage{Content: "held"})
+	assert.Eventually(t, func() bool { return queue.stats().Queued == 0 }, time.Second, time.Millisecond)
+
+	for _, content := range []string{"1", "2", "3", "4"} {
+		queue.push(chatmodels.ChatMessage{Content: content})
+	}
+	close(consumer.release)
+	queue.close()
+
+	assert.Equal(t, []string{"held", "3", "4"}, contents(consumer.consumed, 3))
+	assert.Equal(t, uint64(2), queue.stats().Dropped)
+}
+
+func TestConsumerQueue_DropNewest(t *testing.T) {
+	consumer := NewBlockingChatConsumer("Slow")
+	queue := newConsumerQueue(consumer, QueueOptions{Size: 2, Policy: OverflowDropNewest})
+	queue.start()
+
+	queue.push(chatmodels.ChatMessage{Content: "held"})
+	assert.Eventually(t, func() bool { return queue.stats().Queued == 0 }, time.Second, time.Millisecond)
+
+	for _, content := range []string{"1", "2", "3", "4"} {
+		queue.push(chatmodels.ChatMessage{Content: content})
+	}
+	close(consumer.release)
+	queue.close()
+
+	assert.Equal(t, []string{"held", "1", "2"}, contents(consumer.consumed, 3))
+	assert.Equal(t, uint64(2), queue.stats().Dropped)
+}
+
+func TestConsumerQueue_BlockReleasedOnAbort(t *testing.T) {
+	consumer := NewBlockingChatConsumer("Stuck")
+	queue := newConsumerQueue(consumer, QueueOptions{Size: 1, Policy: OverflowBlock})
+	queue.start()
+
+	queue.push(chatmodels.ChatMessage{Content: "held"})
+	assert.Eventually(t, func() bool { return queue.stats().Queued == 0 }, time.Second, time.Millisecond)
+	queue.push(chatmodels.ChatMessage{Content: "queued"})
+
+	pushed := make(chan struct{})
+	go func() {
+		queue.push(chatmodels.ChatMessage{Content: "blocked"})
+		close(pushed)
+	}()
+
+	select {
+	case <-pushed:
+		t.Fatal("push should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	queue.abort()
+	<-pushed
+	assert.Equal(t, uint64(1), queue.stats().Dropped)
+
+	close(consumer.release)
+	queue.close()
+}
+
+func TestAggregator_SlowConsumerDoesNotStallOthers(t *testing.T) {
+	agg := NewAggregator(&config.Config{})
+	messages := make([]chatmodels.ChatMessage, 0, 20)
+	for i := 0; i < 20; i++ {
+		messages = append(messages, chatmodels.ChatMessage{Provider: "P", Content: "msg"})
+	}
+	provider := &MockChatProvider{Name: "Provider", Messages: messages}
+	slow := NewBlockingChatConsumer("Slow")
+	fast := &MockChatConsumer{Name: "Fast"}
+
+	agg.AddProvider(provider)
+	agg.AddConsumerWithQueue(slow, QueueOptions{Size: 5, Policy: OverflowDropNewest})
+	agg.AddConsumer(fast)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	time.Sleep(200 * time.Millisecond)
+
+	stats := agg.GetConsumerStats()
+	assert.Len(t, stats, 2)
+	assert.Equal(t, "Slow", stats[0].Name)
+	assert.GreaterOrEqual(t, stats[0].Dropped, uint64(14))
+	assert.Equal(t, "Fast", stats[1].Name)
+	assert.Equal(t, 20, fast.GetConsumedCount())
+
+	close(slow.release)
+	assert.NoError(t, agg.Stop(context.Background()))
+}
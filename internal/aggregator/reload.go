@@ -0,0 +1,144 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatconsumers"
+	"github.com/SergioCurto/ChatClient/internal/chatproviders"
+)
+
+// configuredConsumerTypes are the consumers that can be enabled in the configuration, in start order.
+var configuredConsumerTypes = []chatconsumers.ChatConsumerType{chatconsumers.Console, chatconsumers.SimplePage, chatconsumers.Recorder}
+
+// ApplyConfig brings the providers and consumers in line with cfg. Providers are matched by name:
+// new instances are added, missing ones are removed and the ones whose configuration changed are
+// reconnected. Consumers are enabled or disabled, and the running ones implementing
+// chatconsumers.ReconfigurableConsumer receive the new configuration.
+// Before Start and after Stop the changes are only registered, while running they take effect at once.
+// Providers and consumers added with AddProvider and AddConsumer are left untouched.
+func (a *Aggregator) ApplyConfig(ctx context.Context, cfg *config.Config) error {
+	a.lifecycleMux.Lock()
+	defer a.lifecycleMux.Unlock()
+
+	a.mux.Lock()
+	a.cfg = cfg
+	a.backoff = NewBackoff(cfg.Aggregator.RetryInitialDelay, cfg.Aggregator.RetryMaxDelay, cfg.Aggregator.RetryMax)
+	a.filter = newMessageFilter(cfg.Filters)
+	a.mux.Unlock()
+
+	errs := a.applyProviders(ctx, cfg.Providers)
+	errs = append(errs, a.applyConsumers(ctx, cfg)...)
+	return errors.Join(errs...)
+}
+
+func (a *Aggregator) applyProviders(ctx context.Context, instances []config.ProviderConfig) []error {
+	wanted := make(map[string]config.ProviderConfig, len(instances))
+	for _, instance := range instances {
+		wanted[instance.DisplayName()] = instance
+	}
+
+	a.mux.RLock()
+	var removed []*providerEntry
+	kept := make(map[string]bool)
+	for _, entry := range a.providers {
+		if entry.config == nil {
+			continue
+		}
+		name := entry.config.DisplayName()
+		if instance, ok := wanted[name]; ok && reflect.DeepEqual(instance, *entry.config) {
+			kept[name] = true
+			continue
+		}
+		removed = append(removed, entry)
+	}
+	a.mux.RUnlock()
+
+	var errs []error
+	// Changed providers are removed before being added again, so they never run twice
+	for _, entry := range removed {
+		fmt.Println("Removing provider:", entry.provider.GetName())
+		if err := a.removeProvider(ctx, entry); err != nil {
+			errs = append(errs, fmt.Errorf("error removing provider %s: %w", entry.provider.GetName(), err))
+		}
+	}
+
+	for _, instance := range instances {
+		if kept[instance.DisplayName()] {
+			continue
+		}
+		fmt.Printf("Creating and enabling %s chat provider %s\n", instance.Type, instance.DisplayName())
+		provider, err := a.createProvider(instance)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating provider %s: %w", instance.DisplayName(), err))
+			continue
+		}
+		if err := a.addProvider(&providerEntry{provider: provider, config: &instance}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (a *Aggregator) createProvider(instance config.ProviderConfig) (chatproviders.ChatProvider, error) {
+	providerType, err := chatproviders.ParseChatProviderType(instance.Type)
+	if err != nil {
+		return nil, err
+	}
+	return a.providerFactory.CreateProvider(providerType, instance)
+}
+
+func (a *Aggregator) applyConsumers(ctx context.Context, cfg *config.Config) []error {
+	enabled := map[chatconsumers.ChatConsumerType]bool{
+		chatconsumers.Console:    cfg.Consumers.Console.Enabled,
+		chatconsumers.SimplePage: cfg.Consumers.SimplePage.Enabled,
+		chatconsumers.Recorder:   cfg.Consumers.Recorder.Enabled,
+	}
+
+	var errs []error
+	for _, consumerType := range configuredConsumerTypes {
+		if queue, ok := a.configured[consumerType]; ok {
+			if enabled[consumerType] && a.reconfigureConsumer(queue, cfg) {
+				continue
+			}
+			fmt.Println("Removing consumer:", queue.consumer.GetName())
+			delete(a.configured, consumerType)
+			if err := a.removeConsumer(ctx, queue); err != nil {
+				errs = append(errs, fmt.Errorf("error removing consumer %s: %w", queue.consumer.GetName(), err))
+			}
+		}
+		if !enabled[consumerType] {
+			continue
+		}
+
+		consumer, err := a.consumerFactory.CreateConsumer(consumerType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error creating consumer: %w", err))
+			continue
+		}
+		fmt.Printf("Creating and enabling %s consumer\n", consumer.GetName())
+		queue := newConsumerQueue(consumer, a.defaultQueueOptions())
+		if err := a.addConsumer(ctx, queue); err != nil {
+			errs = append(errs, fmt.Errorf("error starting consumer %s: %w", consumer.GetName(), err))
+			continue
+		}
+		a.configured[consumerType] = queue
+	}
+	return errs
+}
+
+// reconfigureConsumer hands the new configuration to a consumer, it returns false when the
+// consumer has to be replaced to apply it.
+func (a *Aggregator) reconfigureConsumer(queue *consumerQueue, cfg *config.Config) bool {
+	if a.cancel == nil {
+		// Not started yet, the consumer receives the configuration on start
+		return true
+	}
+	if consumer, ok := queue.consumer.(chatconsumers.ReconfigurableConsumer); ok {
+		return consumer.UpdateConfig(cfg)
+	}
+	return true
+}
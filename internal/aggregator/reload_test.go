@@ -0,0 +1,195 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatconsumers"
+	"github.com/SergioCurto/ChatClient/internal/chatproviders"
+	"github.com/stretchr/testify/assert"
+)
+
+// MockProviderFactory creates MockChatProviders and keeps them by name
+type MockProviderFactory struct {
+	created map[string][]*MockChatProvider
+	mux     sync.Mutex
+}
+
+func (f *MockProviderFactory) CreateProvider(providerType chatproviders.ChatProviderType, instance config.ProviderConfig) (chatproviders.ChatProvider, error) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	provider := &MockChatProvider{Name: instance.DisplayName(), ShortName: instance.ShortName}
+	f.created[provider.Name] = append(f.created[provider.Name], provider)
+	return provider, nil
+}
+
+func (f *MockProviderFactory) Created(name string) []*MockChatProvider {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.created[name]
+}
+
+// ReconfigurableChatConsumer records the configurations it receives while running
+type ReconfigurableChatConsumer struct {
+	MockChatConsumer
+	Configs []*config.Config
+	// RestartOnUpdate makes UpdateConfig ask for a new consumer
+	RestartOnUpdate bool
+}
+
+func (r *ReconfigurableChatConsumer) UpdateConfig(cfg *config.Config) bool {
+	r.Configs = append(r.Configs, cfg)
+	return !r.RestartOnUpdate
+}
+
+// MockConsumerFactory creates ReconfigurableChatConsumers and keeps them by type
+type MockConsumerFactory struct {
+	created         map[chatconsumers.ChatConsumerType][]*ReconfigurableChatConsumer
+	restartOnUpdate bool
+}
+
+func (f *MockConsumerFactory) CreateConsumer(consumerType chatconsumers.ChatConsumerType) (chatconsumers.ChatConsumer, error) {
+	consumer := &ReconfigurableChatConsumer{MockChatConsumer: MockChatConsumer{Name: fmt.Sprint("Consumer", consumerType)}, RestartOnUpdate: f.restartOnUpdate}
+	f.created[consumerType] = append(f.created[consumerType], consumer)
+	return consumer, nil
+}
+
+func newReloadTestAggregator() (*Aggregator, *MockProviderFactory, *MockConsumerFactory) {
+	agg := newTestAggregator()
+	providers := &MockProviderFactory{created: make(map[string][]*MockChatProvider)}
+	consumers := &MockConsumerFactory{created: make(map[chatconsumers.ChatConsumerType][]*ReconfigurableChatConsumer)}
+	agg.providerFactory = providers
+	agg.consumerFactory = consumers
+	return agg, providers, consumers
+}
+
+func reloadConfig(providers ...config.ProviderConfig) *config.Config {
+	cfg := &config.Config{Providers: providers}
+	cfg.Consumers.Console.Enabled = true
+	return cfg
+}
+
+func TestAggregator_ApplyConfig_Providers(t *testing.T) {
+	agg, providers, _ := newReloadTestAggregator()
+	main := config.ProviderConfig{Type: "twitch", Name: "Twitch", Twitch: config.TwitchConfig{Channel: "streamer"}}
+	raid := config.ProviderConfig{Type: "twitch", Name: "Raid", Twitch: config.TwitchConfig{Channel: "raider"}}
+
+	assert.NoError(t, agg.ApplyConfig(context.Background(), reloadConfig(main)))
+	assert.NoError(t, agg.Start(context.Background()))
+	defer agg.Stop(context.Background())
+	twitch := providers.Created("Twitch")[0]
+	assert.Eventually(t, func() bool { return twitch.GetConnectCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	// A new instance joins while the first one stays connected
+	assert.NoError(t, agg.ApplyConfig(context.Background(), reloadConfig(main, raid)))
+	assert.Len(t, providers.Created("Raid"), 1)
+	assert.Eventually(t, func() bool { return providers.Created("Raid")[0].GetConnectCount() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 2, agg.GetProvidersCount())
+	assert.Len(t, providers.Created("Twitch"), 1)
+
+	// A changed instance is replaced and a missing one is removed
+	main.Twitch.Channel = "renamed"
+	assert.NoError(t, agg.ApplyConfig(context.Background(), reloadConfig(main)))
+	assert.Equal(t, 1, agg.GetProvidersCount())
+	assert.Len(t, providers.Created("Twitch"), 2)
+	assert.True(t, providers.Created("Raid")[0].Disconnected)
+	assert.True(t, twitch.Disconnected)
+	assert.Eventually(t, func() bool { return providers.Created("Twitch")[1].GetConnectCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	_, err := agg.findProvider("Raid")
+	assert.EqualError(t, err, "unknown provider: Raid")
+}
+
+func TestAggregator_ApplyConfig_Consumers(t *testing.T) {
+	agg, _, consumers := newReloadTestAggregator()
+	cfg := reloadConfig(config.ProviderConfig{Type: "twitch", Twitch: config.TwitchConfig{Channel: "streamer"}})
+
+	assert.NoError(t, agg.ApplyConfig(context.Background(), cfg))
+	assert.NoError(t, agg.Start(context.Background()))
+	defer agg.Stop(context.Background())
+	console := consumers.created[chatconsumers.Console][0]
+	assert.True(t, console.StartCalled)
+
+	// Enabling a consumer starts it, the running ones receive the new configuration
+	updated := reloadConfig(cfg.Providers...)
+	updated.Consumers.SimplePage.Enabled = true
+	assert.NoError(t, agg.ApplyConfig(context.Background(), updated))
+	assert.Len(t, consumers.created[chatconsumers.SimplePage], 1)
+	assert.True(t, consumers.created[chatconsumers.SimplePage][0].StartCalled)
+	assert.Equal(t, []*config.Config{updated}, console.Configs)
+	assert.Equal(t, 2, agg.GetConsumersCount())
+
+	// Disabling a consumer stops it
+	assert.NoError(t, agg.ApplyConfig(context.Background(), cfg))
+	assert.True(t, consumers.created[chatconsumers.SimplePage][0].StopCalled)
+	assert.False(t, console.StopCalled)
+	assert.Equal(t, 1, agg.GetConsumersCount())
+}
+
+func TestAggregator_ApplyConfig_ReplacesConsumer(t *testing.T) {
+	agg, _, consumers := newReloadTestAggregator()
+	consumers.restartOnUpdate = true
+	cfg := reloadConfig(config.ProviderConfig{Type: "twitch", Twitch: config.TwitchConfig{Channel: "streamer"}})
+
+	assert.NoError(t, agg.ApplyConfig(context.Background(), cfg))
+	assert.NoError(t, agg.Start(context.Background()))
+	defer agg.Stop(context.Background())
+
+	assert.NoError(t, agg.ApplyConfig(context.Background(), cfg))
+	created := consumers.created[chatconsumers.Console]
+	assert.Len(t, created, 2)
+	assert.True(t, created[0].StopCalled)
+	assert.True(t, created[1].StartCalled)
+	assert.Equal(t, 1, agg.GetConsumersCount())
+}
+
+func TestAggregator_ApplyConfig_BeforeStart(t *testing.T) {
+	agg, providers, consumers := newReloadTestAggregator()
+	manual := &MockChatProvider{Name: "Manual"}
+	agg.AddProvider(manual)
+
+	cfg := reloadConfig(config.ProviderConfig{Type: "youtube", Name: "Youtube"})
+	cfg.Filters.IgnoreCommands = true
+	assert.NoError(t, agg.ApplyConfig(context.Background(), cfg))
+
+	assert.Equal(t, 2, agg.GetProvidersCount())
+	assert.Equal(t, 1, agg.GetConsumersCount())
+	assert.Equal(t, 0, providers.Created("Youtube")[0].GetConnectCount())
+	assert.False(t, consumers.created[chatconsumers.Console][0].StartCalled)
+	assert.True(t, agg.filter.ignoreCommands)
+	assert.Same(t, cfg, agg.cfg)
+
+	// Providers added directly are not managed by the configuration
+	assert.NoError(t, agg.ApplyConfig(context.Background(), reloadConfig()))
+	assert.Equal(t, []chatproviders.ChatProvider{manual}, agg.getProviders())
+}
+
+func TestAggregator_ApplyConfig_AfterStop(t *testing.T) {
+	agg, _, consumers := newReloadTestAggregator()
+	cfg := reloadConfig(config.ProviderConfig{Type: "twitch", Twitch: config.TwitchConfig{Channel: "streamer"}})
+	assert.NoError(t, agg.ApplyConfig(context.Background(), cfg))
+	assert.NoError(t, agg.Start(context.Background()))
+	assert.NoError(t, agg.Stop(context.Background()))
+	console := consumers.created[chatconsumers.Console][0]
+
+	// A reload during the shutdown only registers the changes, like before Start
+	updated := reloadConfig()
+	updated.Consumers.Console.Enabled = false
+	updated.Consumers.SimplePage.Enabled = true
+	assert.NoError(t, agg.ApplyConfig(context.Background(), updated))
+	assert.Equal(t, 1, console.StopCount)
+	assert.False(t, consumers.created[chatconsumers.SimplePage][0].StartCalled)
+	assert.Equal(t, 0, agg.GetProvidersCount())
+	assert.Equal(t, 1, agg.GetConsumersCount())
+}
+
+func TestAggregator_ApplyConfig_UnknownProviderType(t *testing.T) {
+	agg, _, _ := newReloadTestAggregator()
+	err := agg.ApplyConfig(context.Background(), reloadConfig(config.ProviderConfig{Type: "irc", Name: "Irc"}))
+	assert.ErrorContains(t, err, "error creating provider Irc")
+	assert.Equal(t, 0, agg.GetProvidersCount())
+}
@@ -0,0 +1,114 @@
+package aggregator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/chatproviders"
+)
+
+// superviseProvider keeps a provider connected until ctx is done, reconnecting with backoff on failures.
+// A successful Connect does not reset the failures: some providers only reach the network in Listen,
+// so the failures are reset once the connection stayed up long enough to be healthy.
+func (a *Aggregator) superviseProvider(ctx context.Context, p chatproviders.ChatProvider) {
+	failures := 0
+	for {
+		a.publishStatus(ctx, p, chatmodels.ProviderStatus{State: chatmodels.ProviderConnecting, Attempt: failures})
+
+		err := p.Connect(ctx)
+		if err != nil {
+			fmt.Println("Error connecting to provider:", p.GetName(), err)
+		} else {
+			connected := time.Now()
+			a.publishStatus(ctx, p, chatmodels.ProviderStatus{State: chatmodels.ProviderConnected})
+
+			if eventProvider, ok := p.(chatproviders.ChatEventProvider); ok {
+				eventProvider.SetEventsChannel(a.events)
+			}
+			if moderationProvider, ok := p.(chatproviders.ModerationProvider); ok {
+				moderationProvider.SetModerationChannel(a.moderation)
+			}
+			err = p.Listen(ctx, a.messages)
+			if ctx.Err() != nil {
+				fmt.Println("Stopping provider:", p.GetName())
+			} else if err == nil {
+				err = fmt.Errorf("connection closed")
+			}
+			if err != nil {
+				fmt.Println("Error on provider:", p.GetName(), err)
+			}
+			p.Disconnect()
+			if a.getBackoff().Healthy(time.Since(connected)) {
+				failures = 0
+			}
+		}
+
+		if ctx.Err() != nil {
+			a.setStatus(p, chatmodels.ProviderStatus{State: chatmodels.ProviderDisconnected})
+			return
+		}
+
+		failures++
+		backoff := a.getBackoff()
+		if backoff.Exhausted(failures) {
+			a.publishStatus(ctx, p, chatmodels.ProviderStatus{State: chatmodels.ProviderFailed, Attempt: failures, Error: err.Error()})
+			fmt.Println("Giving up on provider:", p.GetName())
+			return
+		}
+
+		delay := backoff.Delay(failures)
+		a.publishStatus(ctx, p, chatmodels.ProviderStatus{State: chatmodels.ProviderReconnecting, Attempt: failures, RetryIn: delay, Error: err.Error()})
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			a.setStatus(p, chatmodels.ProviderStatus{State: chatmodels.ProviderDisconnected})
+			return
+		}
+	}
+}
+
+// publishStatus records the provider status and forwards it to the consumers.
+func (a *Aggregator) publishStatus(ctx context.Context, p chatproviders.ChatProvider, status chatmodels.ProviderStatus) {
+	status = a.setStatus(p, status)
+	select {
+	case a.statuses <- status:
+	case <-ctx.Done():
+	}
+}
+
+func (a *Aggregator) setStatus(p chatproviders.ChatProvider, status chatmodels.ProviderStatus) chatmodels.ProviderStatus {
+	status.Provider = p.GetName()
+	status.ProviderShortName = p.GetShortName()
+	status.Timestamp = time.Now()
+
+	a.statusMux.Lock()
+	a.providerStatus[status.Provider] = status
+	a.statusMux.Unlock()
+	return status
+}
+
+// GetProviderStatuses returns the last known status of every provider.
+func (a *Aggregator) GetProviderStatuses() []chatmodels.ProviderStatus {
+	providers := a.getProviders()
+
+	a.statusMux.Lock()
+	defer a.statusMux.Unlock()
+
+	statuses := make([]chatmodels.ProviderStatus, 0, len(providers))
+	for _, p := range providers {
+		if status, ok := a.providerStatus[p.GetName()]; ok {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+// getBackoff returns the reconnection settings currently applied.
+func (a *Aggregator) getBackoff() Backoff {
+	a.mux.RLock()
+	defer a.mux.RUnlock()
+	return a.backoff
+}
@@ -0,0 +1,137 @@
+package aggregator
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+// StatusChatConsumer records the provider statuses it receives
+type StatusChatConsumer struct {
+	MockChatConsumer
+	statuses []chatmodels.ProviderStatus
+	mux      sync.Mutex
+}
+
+func (s *StatusChatConsumer) ConsumeStatus(status chatmodels.ProviderStatus) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.statuses = append(s.statuses, status)
+}
+
+func (s *StatusChatConsumer) States() []chatmodels.ProviderState {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	states := make([]chatmodels.ProviderState, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		states = append(states, status.State)
+	}
+	return states
+}
+
+// Reconnecting returns the reconnection statuses received
+func (s *StatusChatConsumer) Reconnecting() []chatmodels.ProviderStatus {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var statuses []chatmodels.ProviderStatus
+	for _, status := range s.statuses {
+		if status.State == chatmodels.ProviderReconnecting {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
+
+func newTestAggregator() *Aggregator {
+	agg := NewAggregator(&config.Config{})
+	agg.backoff = Backoff{InitialDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond, Multiplier: 2, ResetAfter: time.Minute}
+	return agg
+}
+
+func TestAggregator_ReconnectsFailingProvider(t *testing.T) {
+	agg := newTestAggregator()
+	provider := &MockChatProvider{Name: "Flaky", ShortName: "F", ListenErr: errors.New("connection reset")}
+	consumer := &StatusChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Status"}}
+	agg.AddProvider(provider)
+	agg.AddConsumer(consumer)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	assert.Eventually(t, func() bool { return provider.GetConnectCount() >= 3 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, agg.Stop(context.Background()))
+
+	states := consumer.States()
+	assert.Equal(t, chatmodels.ProviderConnecting, states[0])
+	assert.Equal(t, chatmodels.ProviderConnected, states[1])
+	assert.Contains(t, states, chatmodels.ProviderReconnecting)
+	assert.Equal(t, chatmodels.ProviderDisconnected, agg.GetProviderStatuses()[0].State)
+}
+
+func TestAggregator_BacksOffWhenListenFails(t *testing.T) {
+	agg := newTestAggregator()
+	agg.backoff = Backoff{InitialDelay: 5 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2, MaxRetries: 3, ResetAfter: time.Minute}
+	// Connect succeeds but the connection fails right away, like a Twitch provider with a bad token
+	provider := &MockChatProvider{Name: "Flaky", ListenErr: errors.New("login authentication failed")}
+	consumer := &StatusChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Status"}}
+	agg.AddProvider(provider)
+	agg.AddConsumer(consumer)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		statuses := agg.GetProviderStatuses()
+		return len(statuses) == 1 && statuses[0].State == chatmodels.ProviderFailed
+	}, time.Second, 5*time.Millisecond)
+	assert.NoError(t, agg.Stop(context.Background()))
+
+	assert.Equal(t, 4, provider.GetConnectCount())
+	assert.Equal(t, "login authentication failed", agg.GetProviderStatuses()[0].Error)
+	reconnecting := consumer.Reconnecting()
+	if assert.Len(t, reconnecting, 3) {
+		for i, delay := range []time.Duration{5 * time.Millisecond, 10 * time.Millisecond, 20 * time.Millisecond} {
+			assert.Equal(t, i+1, reconnecting[i].Attempt)
+			assert.Equal(t, delay, reconnecting[i].RetryIn)
+		}
+	}
+}
+
+func TestAggregator_HealthyConnectionResetsFailures(t *testing.T) {
+	agg := newTestAggregator()
+	agg.backoff = Backoff{InitialDelay: 5 * time.Millisecond, MaxDelay: time.Second, Multiplier: 2, MaxRetries: 1, ResetAfter: time.Nanosecond}
+	provider := &MockChatProvider{Name: "Flaky", ListenErr: errors.New("connection reset")}
+	consumer := &StatusChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Status"}}
+	agg.AddProvider(provider)
+	agg.AddConsumer(consumer)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	assert.Eventually(t, func() bool { return provider.GetConnectCount() >= 4 }, time.Second, 5*time.Millisecond)
+	assert.NoError(t, agg.Stop(context.Background()))
+
+	assert.NotContains(t, consumer.States(), chatmodels.ProviderFailed)
+	for _, status := range consumer.Reconnecting() {
+		assert.Equal(t, 1, status.Attempt)
+		assert.Equal(t, 5*time.Millisecond, status.RetryIn)
+	}
+}
+
+func TestAggregator_GivesUpAfterMaxRetries(t *testing.T) {
+	agg := newTestAggregator()
+	agg.backoff.MaxRetries = 2
+	provider := &MockChatProvider{Name: "Broken", ConnectErr: errors.New("invalid channel")}
+	consumer := &StatusChatConsumer{MockChatConsumer: MockChatConsumer{Name: "Status"}}
+	agg.AddProvider(provider)
+	agg.AddConsumer(consumer)
+
+	assert.NoError(t, agg.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		statuses := agg.GetProviderStatuses()
+		return len(statuses) == 1 && statuses[0].State == chatmodels.ProviderFailed
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, 3, provider.GetConnectCount())
+	assert.Equal(t, "invalid channel", agg.GetProviderStatuses()[0].Error)
+	assert.NoError(t, agg.Stop(context.Background()))
+}
@@ -1,25 +1,66 @@
 package chatconsumers
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/SergioCurto/ChatClient/config"
 	"github.com/SergioCurto/ChatClient/internal/chatconsumers/console"
+	"github.com/SergioCurto/ChatClient/internal/chatconsumers/recorder"
 	"github.com/SergioCurto/ChatClient/internal/chatconsumers/simplepage"
 	"github.com/SergioCurto/ChatClient/internal/chatmodels"
 )
 
+// ChatConsumer receives the messages forwarded by the aggregator.
+// Start must return once the consumer is ready, the context only bounds the start up.
+// Stop releases the consumer resources and must return before the context deadline.
 type ChatConsumer interface {
 	Consume(message chatmodels.ChatMessage)
-	Start(cfg *config.Config) error
+	Start(ctx context.Context, cfg *config.Config) error
+	Stop(ctx context.Context) error
 	GetName() string
 }
 
+// StatusConsumer is implemented by consumers that want to be notified of provider connection changes.
+type StatusConsumer interface {
+	ConsumeStatus(status chatmodels.ProviderStatus)
+}
+
+// ChatEventConsumer is implemented by consumers that want to receive non-chat events.
+type ChatEventConsumer interface {
+	ConsumeEvent(event chatmodels.ChatEvent)
+}
+
+// ModerationConsumer is implemented by consumers that retract moderated messages.
+type ModerationConsumer interface {
+	ConsumeModeration(event chatmodels.ModerationEvent)
+}
+
+// ModeratingConsumer is implemented by consumers that let their users moderate the chat.
+// The aggregator sets the function applying the moderation requests before starting the consumer.
+type ModeratingConsumer interface {
+	SetModerator(moderate func(ctx context.Context, request chatmodels.ModerationRequest) error)
+}
+
+// MonitoringConsumer is implemented by consumers that report the state of the application.
+// The aggregator sets the functions returning the provider statuses and the consumer health
+// before starting the consumer.
+type MonitoringConsumer interface {
+	SetMonitor(providerStatuses func() []chatmodels.ProviderStatus, consumerHealth func() []chatmodels.ConsumerHealth)
+}
+
+// ReconfigurableConsumer is implemented by consumers that apply configuration changes while running.
+// UpdateConfig returns false when the change needs a restart, the aggregator then replaces the consumer.
+type ReconfigurableConsumer interface {
+	UpdateConfig(cfg *config.Config) bool
+}
+
 type ChatConsumerType int
 
 const (
 	Console ChatConsumerType = iota
 	SimplePage
+	Recorder
 )
 
 // ChatConsumerFactory is the factory interface for creating ChatConsumers.
@@ -43,6 +84,8 @@ func (f *ConcreteChatConsumerFactory) CreateConsumer(consumerType ChatConsumerTy
 		return console.NewConsoleConsumer(), nil
 	case SimplePage:
 		return simplepage.NewSimplePageConsumer(), nil
+	case Recorder:
+		return recorder.NewRecorderConsumer(), nil
 	default:
 		return nil, fmt.Errorf("unknown consumer type: %v", consumerType)
 	}
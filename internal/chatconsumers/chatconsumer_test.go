@@ -20,6 +20,12 @@ func TestConcreteChatConsumerFactory_CreateConsumer(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, consumer)
 	assert.Equal(t, "SimplePage", consumer.GetName())
+
+	// Test creating a Recorder consumer
+	consumer, err = factory.CreateConsumer(Recorder)
+	assert.NoError(t, err)
+	assert.NotNil(t, consumer)
+	assert.Equal(t, "Recorder", consumer.GetName())
 	
 	// Test creating an unknown consumer
 	consumer, err = factory.CreateConsumer(ChatConsumerType(999)) // Invalid consumer type
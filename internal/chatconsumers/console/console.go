@@ -1,7 +1,9 @@
 package console
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/SergioCurto/ChatClient/config"
 	"github.com/SergioCurto/ChatClient/internal/chatmodels"
@@ -18,15 +20,41 @@ func NewConsoleConsumer() *ConsoleConsumer {
 	}
 }
 
-func (c *ConsoleConsumer) Start(cfg *config.Config) error {
+func (c *ConsoleConsumer) Start(ctx context.Context, cfg *config.Config) error {
 	return nil
 }
 
-// Consume logs the message to the console.
+func (c *ConsoleConsumer) Stop(ctx context.Context) error {
+	return nil
+}
+
+// Consume logs the message to the console, emotes are shown by their text.
 func (c *ConsoleConsumer) Consume(message chatmodels.ChatMessage) {
 	fmt.Printf("[%s] %s: %s\n", message.Provider, message.AuthorName, message.Content)
 }
 
+// ConsumeEvent logs subscriptions, raids and paid messages to the console.
+func (c *ConsoleConsumer) ConsumeEvent(event chatmodels.ChatEvent) {
+	fmt.Printf("[%s] * %s\n", event.Provider, event.Describe())
+}
+
+// ConsumeModeration logs deleted messages, timeouts and bans to the console.
+func (c *ConsoleConsumer) ConsumeModeration(event chatmodels.ModerationEvent) {
+	fmt.Printf("[%s] - %s\n", event.Provider, event.Describe())
+}
+
+// ConsumeStatus logs provider connection changes to the console.
+func (c *ConsoleConsumer) ConsumeStatus(status chatmodels.ProviderStatus) {
+	switch status.State {
+	case chatmodels.ProviderReconnecting:
+		fmt.Printf("[%s] reconnecting in %s (attempt %d): %s\n", status.Provider, status.RetryIn.Round(time.Millisecond), status.Attempt, status.Error)
+	case chatmodels.ProviderFailed:
+		fmt.Printf("[%s] gave up reconnecting: %s\n", status.Provider, status.Error)
+	default:
+		fmt.Printf("[%s] %s\n", status.Provider, status.State)
+	}
+}
+
 // GetName returns the name of the consumer.
 func (c *ConsoleConsumer) GetName() string {
 	return c.Name
@@ -0,0 +1,172 @@
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+)
+
+// rotatingFile writes the lines of a recording to the current file. A new file is started for
+// every session or day, and a new part of it once it reaches the maximum size.
+type rotatingFile struct {
+	cfg config.RecorderConfig
+	// session is the start of the recording, it names the files of the session rotation
+	session time.Time
+	now     func() time.Time
+
+	file    *os.File
+	size    *countingWriter
+	gzip    *gzip.Writer
+	buffer  *bufio.Writer
+	path    string
+	day     string
+	part    int
+	maxSize int64
+}
+
+func newRotatingFile(cfg config.RecorderConfig, now func() time.Time) *rotatingFile {
+	return &rotatingFile{
+		cfg:     cfg,
+		session: now(),
+		now:     now,
+		maxSize: int64(cfg.MaxSizeMB) * 1024 * 1024,
+	}
+}
+
+// write appends a line, rotating the file first when needed.
+func (f *rotatingFile) write(line []byte) error {
+	if err := f.rotate(); err != nil {
+		return err
+	}
+	if _, err := f.buffer.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing %s: %w", f.path, err)
+	}
+	if f.cfg.Sync == "always" {
+		return f.sync()
+	}
+	return nil
+}
+
+// rotate opens the file of the current day or the next part when the current file is full.
+func (f *rotatingFile) rotate() error {
+	day := f.now().Format(time.DateOnly)
+	switch {
+	case f.file == nil:
+	case f.cfg.Rotation == "day" && day != f.day:
+		f.part = 0
+	case f.maxSize > 0 && f.size.written >= f.maxSize:
+	default:
+		return nil
+	}
+
+	if err := f.close(); err != nil {
+		return err
+	}
+	f.day = day
+	return f.open()
+}
+
+// open creates the next file, a daily file is appended to when it is not full.
+func (f *rotatingFile) open() error {
+	var name string
+	if f.cfg.Rotation == "day" {
+		name = "chat-" + f.day
+	} else {
+		name = "chat-" + f.session.Format("2006-01-02_15-04-05")
+	}
+	extension := ".jsonl"
+	if f.cfg.Compress {
+		extension += ".gz"
+	}
+
+	for {
+		f.part++
+		path := filepath.Join(f.cfg.Directory, name+extension)
+		if f.part > 1 {
+			path = filepath.Join(f.cfg.Directory, fmt.Sprintf("%s.%d%s", name, f.part, extension))
+		}
+
+		info, err := os.Stat(path)
+		if err == nil && (f.cfg.Rotation != "day" || (f.maxSize > 0 && info.Size() >= f.maxSize)) {
+			continue
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("error opening %s: %w", path, err)
+		}
+
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %w", path, err)
+		}
+		var size int64
+		if info != nil {
+			size = info.Size()
+		}
+
+		f.file, f.path = file, path
+		f.size = &countingWriter{writer: file, written: size}
+		var output io.Writer = f.size
+		if f.cfg.Compress {
+			// Appending to a compressed file adds a gzip member, which readers handle as one stream
+			f.gzip = gzip.NewWriter(f.size)
+			output = f.gzip
+		}
+		f.buffer = bufio.NewWriter(output)
+		return nil
+	}
+}
+
+// sync flushes the buffered lines and commits the file to disk.
+func (f *rotatingFile) sync() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.buffer.Flush()
+	if f.gzip != nil && err == nil {
+		err = f.gzip.Flush()
+	}
+	if err == nil {
+		err = f.file.Sync()
+	}
+	if err != nil {
+		return fmt.Errorf("error syncing %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// close flushes and closes the current file.
+func (f *rotatingFile) close() error {
+	if f.file == nil {
+		return nil
+	}
+	err := f.buffer.Flush()
+	if f.gzip != nil {
+		err = errors.Join(err, f.gzip.Close())
+	}
+	err = errors.Join(err, f.file.Sync(), f.file.Close())
+	f.file, f.gzip, f.buffer = nil, nil, nil
+	if err != nil {
+		return fmt.Errorf("error closing %s: %w", f.path, err)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written to the file, to rotate it by size.
+type countingWriter struct {
+	writer  io.Writer
+	written int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,152 @@
+package recorder
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+// maxLineSize is the longest line accepted when reading a recording
+const maxLineSize = 1024 * 1024
+
+// Record is a line of a recording. Chat messages are written as they are, so a recording is also
+// a plain JSON Lines log of ChatMessage, while events and moderation are wrapped in an object with
+// an Event or a Moderation field to tell them apart.
+type Record struct {
+	Message    *chatmodels.ChatMessage
+	Event      *chatmodels.ChatEvent
+	Moderation *chatmodels.ModerationEvent
+}
+
+// wrappedRecord is the line of the records that are not chat messages
+type wrappedRecord struct {
+	Event      *chatmodels.ChatEvent       `json:",omitempty"`
+	Moderation *chatmodels.ModerationEvent `json:",omitempty"`
+}
+
+func (r Record) MarshalJSON() ([]byte, error) {
+	if r.Message != nil {
+		return json.Marshal(r.Message)
+	}
+	return json.Marshal(wrappedRecord{Event: r.Event, Moderation: r.Moderation})
+}
+
+func (r *Record) UnmarshalJSON(data []byte) error {
+	var wrapped wrappedRecord
+	if err := json.Unmarshal(data, &wrapped); err != nil {
+		return err
+	}
+	if wrapped.Event != nil || wrapped.Moderation != nil {
+		*r = Record{Event: wrapped.Event, Moderation: wrapped.Moderation}
+		return nil
+	}
+
+	var message chatmodels.ChatMessage
+	if err := json.Unmarshal(data, &message); err != nil {
+		return err
+	}
+	*r = Record{Message: &message}
+	return nil
+}
+
+// Time returns when the recorded message or event happened.
+func (r Record) Time() time.Time {
+	switch {
+	case r.Message != nil:
+		return r.Message.Timestamp
+	case r.Event != nil:
+		return r.Event.Timestamp
+	case r.Moderation != nil:
+		return r.Moderation.Timestamp
+	default:
+		return time.Time{}
+	}
+}
+
+// Reader reads the records of a recording file, gzip compressed files are detected.
+type Reader struct {
+	path    string
+	file    *os.File
+	scanner *bufio.Scanner
+	line    int
+}
+
+// Open opens a recording for reading.
+func Open(path string) (*Reader, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var content io.Reader = bufio.NewReader(file)
+	magic, _ := content.(*bufio.Reader).Peek(2)
+	if len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		content, err = gzip.NewReader(content)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+	}
+
+	scanner := bufio.NewScanner(content)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return &Reader{path: path, file: file, scanner: scanner}, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the recording. Empty lines are skipped.
+func (r *Reader) Next() (Record, error) {
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(line, &record); err != nil {
+			return Record{}, fmt.Errorf("%s line %d: %w", r.path, r.line, err)
+		}
+		return record, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		// A recording cut by a crash ends with a truncated gzip stream, what was read is kept
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, io.EOF
+		}
+		return Record{}, fmt.Errorf("error reading %s: %w", r.path, err)
+	}
+	return Record{}, io.EOF
+}
+
+func (r *Reader) Close() error {
+	return r.file.Close()
+}
+
+// ReadFile calls handle with every record of a recording, stopping at the first error.
+func ReadFile(path string, handle func(Record) error) error {
+	reader, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := handle(record); err != nil {
+			return err
+		}
+	}
+}
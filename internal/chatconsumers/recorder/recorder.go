@@ -0,0 +1,134 @@
+package recorder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+// RecorderConsumer is a ChatConsumer that writes the chat messages, events and moderation to
+// JSON Lines files, one Record per line, so the chat of a stream can be kept and replayed.
+type RecorderConsumer struct {
+	Name string
+	cfg  config.RecorderConfig
+	// mux serializes the writes with the periodic sync
+	mux  sync.Mutex
+	file *rotatingFile
+	// done is closed by Stop to end the periodic sync, Start replaces it when the recorder is
+	// started again
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewRecorderConsumer() *RecorderConsumer {
+	return &RecorderConsumer{
+		Name: "Recorder",
+		done: make(chan struct{}),
+	}
+}
+
+// Start creates the directory and the first file, so the errors are reported on start up.
+func (c *RecorderConsumer) Start(ctx context.Context, cfg *config.Config) error {
+	c.cfg = cfg.Consumers.Recorder
+	select {
+	case <-c.done:
+		c.done = make(chan struct{})
+	default:
+	}
+	if err := os.MkdirAll(c.cfg.Directory, 0o755); err != nil {
+		return fmt.Errorf("error creating recordings directory: %w", err)
+	}
+
+	c.file = newRotatingFile(c.cfg, time.Now)
+	if err := c.file.rotate(); err != nil {
+		return err
+	}
+	log.Println("Recording chat to", c.file.path)
+
+	if c.cfg.Sync == "interval" {
+		c.wg.Add(1)
+		go c.syncPeriodically(c.cfg.SyncInterval, c.done)
+	}
+	return nil
+}
+
+func (c *RecorderConsumer) syncPeriodically(interval time.Duration, done <-chan struct{}) {
+	defer c.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.mux.Lock()
+			err := c.file.sync()
+			c.mux.Unlock()
+			if err != nil {
+				log.Println("Error recording chat:", err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+// Stop writes the pending lines and closes the file.
+func (c *RecorderConsumer) Stop(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return nil
+	default:
+		close(c.done)
+	}
+	c.wg.Wait()
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.file == nil {
+		return nil
+	}
+	return c.file.close()
+}
+
+// UpdateConfig keeps recording when the recorder options did not change, otherwise the
+// recorder is replaced to start a file with the new options.
+func (c *RecorderConsumer) UpdateConfig(cfg *config.Config) bool {
+	return cfg.Consumers.Recorder == c.cfg
+}
+
+func (c *RecorderConsumer) Consume(message chatmodels.ChatMessage) {
+	c.record(Record{Message: &message})
+}
+
+func (c *RecorderConsumer) ConsumeEvent(event chatmodels.ChatEvent) {
+	c.record(Record{Event: &event})
+}
+
+func (c *RecorderConsumer) ConsumeModeration(event chatmodels.ModerationEvent) {
+	c.record(Record{Moderation: &event})
+}
+
+func (c *RecorderConsumer) record(record Record) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Println("Error recording chat:", err)
+		return
+	}
+
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if err := c.file.write(line); err != nil {
+		log.Println("Error recording chat:", err)
+	}
+}
+
+// GetName returns the name of the consumer.
+func (c *RecorderConsumer) GetName() string {
+	return c.Name
+}
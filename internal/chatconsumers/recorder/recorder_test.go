@@ -0,0 +1,150 @@
+package recorder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+func recorderConfig(dir string) *config.Config {
+	cfg := config.Default()
+	cfg.Consumers.Recorder.Enabled = true
+	cfg.Consumers.Recorder.Directory = dir
+	return cfg
+}
+
+func readRecords(t *testing.T, path string) []Record {
+	var records []Record
+	assert.NoError(t, ReadFile(path, func(record Record) error {
+		records = append(records, record)
+		return nil
+	}))
+	return records
+}
+
+func TestRecorderConsumer_RecordsMessagesAndEvents(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		cfg := recorderConfig(dir)
+		cfg.Consumers.Recorder.Compress = compress
+		at := time.Date(2025, 3, 20, 18, 30, 15, 0, time.UTC)
+
+		consumer := NewRecorderConsumer()
+		assert.NoError(t, consumer.Start(context.Background(), cfg))
+		consumer.Consume(chatmodels.ChatMessage{ID: "msg-1", Provider: "Twitch", AuthorName: "Viewer", Content: "hello", Timestamp: at})
+		consumer.ConsumeEvent(chatmodels.ChatEvent{ID: "raid-1", Kind: chatmodels.EventRaid, AuthorName: "Raider", Timestamp: at.Add(time.Second)})
+		consumer.ConsumeModeration(chatmodels.ModerationEvent{Action: chatmodels.ModerationDeleteMessage, MessageID: "msg-1", Timestamp: at.Add(2 * time.Second)})
+		assert.NoError(t, consumer.Stop(context.Background()))
+
+		files, _ := filepath.Glob(filepath.Join(dir, "chat-*"))
+		assert.Len(t, files, 1)
+		if compress {
+			assert.Equal(t, ".gz", filepath.Ext(files[0]))
+		}
+
+		records := readRecords(t, files[0])
+		assert.Len(t, records, 3)
+		assert.Equal(t, "hello", records[0].Message.Content)
+		assert.Equal(t, at, records[0].Time().UTC())
+		assert.Equal(t, chatmodels.EventRaid, records[1].Event.Kind)
+		assert.Nil(t, records[1].Message)
+		assert.Equal(t, "msg-1", records[2].Moderation.MessageID)
+		assert.Equal(t, at.Add(2*time.Second), records[2].Time().UTC())
+	}
+}
+
+func TestRecorderConsumer_Restart(t *testing.T) {
+	dir := t.TempDir()
+	cfg := recorderConfig(dir)
+	consumer := NewRecorderConsumer()
+
+	// Every session is written to its own file, which is flushed and closed by Stop
+	for _, content := range []string{"first", "second"} {
+		assert.NoError(t, consumer.Start(context.Background(), cfg))
+		consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: content})
+		assert.NoError(t, consumer.Stop(context.Background()))
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "chat-*"))
+	assert.Len(t, files, 2)
+	var contents []string
+	for _, file := range files {
+		for _, record := range readRecords(t, file) {
+			contents = append(contents, record.Message.Content)
+		}
+	}
+	assert.ElementsMatch(t, []string{"first", "second"}, contents)
+}
+
+func TestRecord_MessageLineIsAChatMessage(t *testing.T) {
+	line, err := Record{Message: &chatmodels.ChatMessage{ID: "msg-1"}}.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(line), `"ID":"msg-1"`)
+	assert.NotContains(t, string(line), `"Event"`)
+
+	line, err = Record{Event: &chatmodels.ChatEvent{ID: "event-1"}}.MarshalJSON()
+	assert.NoError(t, err)
+	assert.Contains(t, string(line), `{"Event":{`)
+}
+
+func TestRotatingFile_DayRotationAppends(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 20, 23, 59, 0, 0, time.Local)
+	cfg := config.RecorderConfig{Directory: dir, Rotation: "day", Compress: true}
+
+	for _, content := range []string{`{"ID":"1"}`, `{"ID":"2"}`} {
+		file := newRotatingFile(cfg, func() time.Time { return now })
+		assert.NoError(t, file.write([]byte(content)))
+		assert.NoError(t, file.close())
+	}
+	now = now.Add(2 * time.Minute)
+	file := newRotatingFile(cfg, func() time.Time { return now })
+	assert.NoError(t, file.write([]byte(`{"ID":"3"}`)))
+	assert.NoError(t, file.close())
+
+	assert.Len(t, readRecords(t, filepath.Join(dir, "chat-2025-03-20.jsonl.gz")), 2)
+	assert.Len(t, readRecords(t, filepath.Join(dir, "chat-2025-03-21.jsonl.gz")), 1)
+}
+
+func TestRotatingFile_SizeRotation(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 20, 18, 30, 15, 0, time.Local)
+	file := newRotatingFile(config.RecorderConfig{Directory: dir, Rotation: "session", Sync: "always"}, func() time.Time { return now })
+	file.maxSize = 20
+
+	for _, content := range []string{`{"ID":"1"}`, `{"ID":"2"}`, `{"ID":"3"}`} {
+		assert.NoError(t, file.write([]byte(content)))
+	}
+	assert.NoError(t, file.close())
+
+	assert.Len(t, readRecords(t, filepath.Join(dir, "chat-2025-03-20_18-30-15.jsonl")), 2)
+	assert.Len(t, readRecords(t, filepath.Join(dir, "chat-2025-03-20_18-30-15.2.jsonl")), 1)
+}
+
+func TestReadFile_InvalidLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chat.jsonl")
+	assert.NoError(t, os.WriteFile(path, []byte("{\"ID\":\"1\"}\n\nnot json\n"), 0o644))
+
+	var count int
+	err := ReadFile(path, func(Record) error { count++; return nil })
+	assert.ErrorContains(t, err, "line 3")
+	assert.Equal(t, 1, count)
+}
+
+func TestRecorderConsumer_UpdateConfig(t *testing.T) {
+	cfg := recorderConfig(t.TempDir())
+	consumer := NewRecorderConsumer()
+	assert.NoError(t, consumer.Start(context.Background(), cfg))
+	defer consumer.Stop(context.Background())
+
+	assert.True(t, consumer.UpdateConfig(cfg))
+	changed := recorderConfig(cfg.Consumers.Recorder.Directory)
+	changed.Consumers.Recorder.Compress = true
+	assert.False(t, consumer.UpdateConfig(changed))
+}
@@ -0,0 +1,216 @@
+package simplepage
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+)
+
+// apiPrefix is the path of the version of the HTTP API, a new version gets a new prefix so
+// the tools using the previous one keep working
+const apiPrefix = "/api/v1/"
+
+const (
+	defaultMessagesLimit = 100
+	maxMessagesLimit     = 1000
+)
+
+// openAPI describes the HTTP API
+//
+//go:embed openapi.json
+var openAPI []byte
+
+// SetMonitor sets the functions reporting the provider statuses and the consumer health served by the API.
+func (c *SimplePageConsumer) SetMonitor(providerStatuses func() []chatmodels.ProviderStatus, consumerHealth func() []chatmodels.ConsumerHealth) {
+	c.providerStatuses = providerStatuses
+	c.consumerHealth = consumerHealth
+}
+
+// registerAPI adds the read only JSON API, for the tools (bots, dashboards) that do not use the page.
+func (c *SimplePageConsumer) registerAPI(mux *http.ServeMux) {
+	mux.Handle(apiPrefix+"messages", apiHandler(c.handleMessagesAPI))
+	mux.Handle(apiPrefix+"providers", apiHandler(c.handleProvidersAPI))
+	mux.Handle(apiPrefix+"rates", apiHandler(c.handleRatesAPI))
+	mux.Handle(apiPrefix+"consumers", apiHandler(c.handleConsumersAPI))
+	mux.Handle(apiPrefix+"search", apiHandler(c.handleSearchAPI))
+	mux.Handle(apiPrefix+"clients", apiHandler(c.handleClientsAPI))
+	mux.HandleFunc(apiPrefix+"openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPI)
+	})
+	mux.Handle("/api/", apiHandler(func(r *http.Request) (any, error) {
+		return nil, apiError{status: http.StatusNotFound, message: "unknown endpoint " + r.URL.Path}
+	}))
+}
+
+// apiError is an error returned to the client with its status, other errors are logged and
+// reported as internal errors.
+type apiError struct {
+	status  int
+	message string
+}
+
+func (e apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...any) apiError {
+	return apiError{status: http.StatusBadRequest, message: fmt.Sprintf(format, args...)}
+}
+
+// apiHandler serves an endpoint of the API, the response is encoded as JSON and the errors as {"Error": "..."}.
+func apiHandler(handle func(r *http.Request) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+
+		var response any
+		var err error
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			response, err = handle(r)
+		} else {
+			err = apiError{status: http.StatusMethodNotAllowed, message: "method not allowed"}
+		}
+
+		status := http.StatusOK
+		if err != nil {
+			var requestErr apiError
+			if !errors.As(err, &requestErr) {
+				log.Println("Error serving API:", err)
+				requestErr = apiError{status: http.StatusInternalServerError, message: "internal error"}
+			}
+			status = requestErr.status
+			response = struct{ Error string }{requestErr.message}
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(response)
+	})
+}
+
+// messagesPage is a page of messages. The cursors are opaque values passed back as the before
+// and after parameters.
+type messagesPage struct {
+	Messages []history.Entry
+	// Older is the cursor of the previous messages, empty when there are none
+	Older string `json:",omitempty"`
+	// Newer is the cursor of the messages received after this page
+	Newer string
+}
+
+// handleMessagesAPI returns the most recent messages, the messages before the before cursor,
+// or the oldest messages after the after cursor to follow the chat.
+func (c *SimplePageConsumer) handleMessagesAPI(r *http.Request) (any, error) {
+	values := r.URL.Query()
+	limit := defaultMessagesLimit
+	if value := values.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxMessagesLimit {
+			return nil, badRequest("invalid limit %q, use a number between 1 and %d", value, maxMessagesLimit)
+		}
+		limit = parsed
+	}
+	before, err := parseCursor(values.Get("before"))
+	if err != nil {
+		return nil, err
+	}
+	after, err := parseCursor(values.Get("after"))
+	if err != nil {
+		return nil, err
+	}
+	following := values.Has("after")
+	if following && values.Has("before") {
+		return nil, badRequest("use either before or after")
+	}
+
+	// One more message is read to know whether there are more
+	entries, err := c.queryHistory(r.Context(), history.Query{Before: before, After: after, Limit: limit + 1, Oldest: following})
+	if err != nil {
+		return nil, err
+	}
+
+	page := messagesPage{Messages: entries, Newer: formatCursor(after)}
+	if following {
+		page.Messages = entries[:min(limit, len(entries))]
+	} else if len(entries) > limit {
+		page.Messages = entries[1:]
+		page.Older = formatCursor(page.Messages[0].ID)
+	}
+	if len(page.Messages) > 0 {
+		page.Newer = formatCursor(page.Messages[len(page.Messages)-1].ID)
+	} else {
+		page.Messages = []history.Entry{}
+	}
+	return page, nil
+}
+
+func parseCursor(value string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+	cursor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || cursor < 0 {
+		return 0, badRequest("invalid cursor %q", value)
+	}
+	return cursor, nil
+}
+
+func formatCursor(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
+
+// handleProvidersAPI returns the connection status of every provider.
+func (c *SimplePageConsumer) handleProvidersAPI(r *http.Request) (any, error) {
+	statuses := []chatmodels.ProviderStatus{}
+	if c.providerStatuses != nil {
+		statuses = append(statuses, c.providerStatuses()...)
+	}
+	return struct{ Providers []chatmodels.ProviderStatus }{statuses}, nil
+}
+
+// handleRatesAPI returns the rate of messages of every provider and of all of them.
+func (c *SimplePageConsumer) handleRatesAPI(r *http.Request) (any, error) {
+	all, providers := c.rates.snapshot()
+	return struct {
+		Since     time.Time
+		All       messageRate
+		Providers []messageRate
+	}{c.rates.started, all, providers}, nil
+}
+
+// consumerHealth is the delivery state of a consumer with a summary of its health: ok,
+// backlogged when its queue is full or stopped when it is not running.
+type consumerHealth struct {
+	chatmodels.ConsumerHealth
+	Status string
+}
+
+// handleConsumersAPI returns the health of every consumer.
+func (c *SimplePageConsumer) handleConsumersAPI(r *http.Request) (any, error) {
+	consumers := []consumerHealth{}
+	if c.consumerHealth != nil {
+		for _, health := range c.consumerHealth() {
+			status := "ok"
+			if !health.Running {
+				status = "stopped"
+			} else if health.Queued >= health.Capacity {
+				status = "backlogged"
+			}
+			consumers = append(consumers, consumerHealth{ConsumerHealth: health, Status: status})
+		}
+	}
+	return struct{ Consumers []consumerHealth }{consumers}, nil
+}
+
+// handleClientsAPI returns the connected pages and the counters of their WebSocket.
+func (c *SimplePageConsumer) handleClientsAPI(r *http.Request) (any, error) {
+	return struct{ WebSocket wsReport }{c.wsReport()}, nil
+}
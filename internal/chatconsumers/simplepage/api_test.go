@@ -0,0 +1,190 @@
+package simplepage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+// getAPI serves a request with the API of the consumer and decodes the JSON response into target.
+func getAPI(t *testing.T, c *SimplePageConsumer, path string, target any) int {
+	mux := http.NewServeMux()
+	c.registerAPI(mux)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), target))
+	return recorder.Code
+}
+
+// getMessages requests a page of messages from the API of the consumer.
+func getMessages(t *testing.T, c *SimplePageConsumer, query string) messagesPage {
+	var page messagesPage
+	assert.Equal(t, http.StatusOK, getAPI(t, c, "/api/v1/messages?"+query, &page))
+	return page
+}
+
+func messageIDs(page messagesPage) []string {
+	ids := []string{}
+	for _, entry := range page.Messages {
+		ids = append(ids, entry.Message.ID)
+	}
+	return ids
+}
+
+func TestSimplePageConsumer_MessagesAPI(t *testing.T) {
+	for _, store := range []bool{false, true} {
+		consumer := NewSimplePageConsumer()
+		if store {
+			assert.NoError(t, consumer.openHistory(context.Background(), config.HistoryConfig{Enabled: true, Path: ":memory:"}))
+		}
+		drain(consumer)
+		for i := 1; i <= 5; i++ {
+			consumer.Consume(chatmodels.ChatMessage{ID: fmt.Sprint(i), Provider: "Twitch", Timestamp: time.Now()})
+		}
+
+		page := getMessages(t, consumer, "limit=2")
+		assert.Equal(t, []string{"4", "5"}, messageIDs(page))
+
+		// Page back to the first message
+		page = getMessages(t, consumer, "limit=2&before="+page.Older)
+		assert.Equal(t, []string{"2", "3"}, messageIDs(page))
+		page = getMessages(t, consumer, "limit=2&before="+page.Older)
+		assert.Equal(t, []string{"1"}, messageIDs(page))
+		assert.Empty(t, page.Older)
+
+		// Follow the chat from the start
+		page = getMessages(t, consumer, "limit=3&after=0")
+		assert.Equal(t, []string{"1", "2", "3"}, messageIDs(page))
+		page = getMessages(t, consumer, "limit=3&after="+page.Newer)
+		assert.Equal(t, []string{"4", "5"}, messageIDs(page))
+		newer := page.Newer
+		page = getMessages(t, consumer, "after="+newer)
+		assert.Empty(t, page.Messages)
+		assert.Equal(t, newer, page.Newer)
+
+		consumer.Consume(chatmodels.ChatMessage{ID: "6", Provider: "Twitch", Timestamp: time.Now()})
+		page = getMessages(t, consumer, "after="+newer)
+		assert.Equal(t, []string{"6"}, messageIDs(page))
+
+		var apiErr struct{ Error string }
+		assert.Equal(t, http.StatusBadRequest, getAPI(t, consumer, "/api/v1/messages?limit=0", &apiErr))
+		assert.Equal(t, http.StatusBadRequest, getAPI(t, consumer, "/api/v1/messages?before=x", &apiErr))
+		assert.Equal(t, http.StatusBadRequest, getAPI(t, consumer, "/api/v1/messages?before=1&after=1", &apiErr))
+		assert.Equal(t, "use either before or after", apiErr.Error)
+
+		close(consumer.done)
+		consumer.closeHistory()
+	}
+}
+
+func TestSimplePageConsumer_ProvidersAndConsumersAPI(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+
+	var providers struct{ Providers []chatmodels.ProviderStatus }
+	getAPI(t, consumer, "/api/v1/providers", &providers)
+	assert.Empty(t, providers.Providers)
+
+	consumer.SetMonitor(
+		func() []chatmodels.ProviderStatus {
+			return []chatmodels.ProviderStatus{{Provider: "Twitch", State: chatmodels.ProviderConnected}}
+		},
+		func() []chatmodels.ConsumerHealth {
+			return []chatmodels.ConsumerHealth{
+				{Consumer: "Console", Running: true, Capacity: 10, Queued: 2},
+				{Consumer: "SimplePage", Running: true, Capacity: 10, Queued: 10},
+				{Consumer: "Recorder", Capacity: 10},
+			}
+		},
+	)
+
+	getAPI(t, consumer, "/api/v1/providers", &providers)
+	assert.Equal(t, []chatmodels.ProviderStatus{{Provider: "Twitch", State: chatmodels.ProviderConnected}}, providers.Providers)
+
+	var consumers struct{ Consumers []consumerHealth }
+	assert.Equal(t, http.StatusOK, getAPI(t, consumer, "/api/v1/consumers", &consumers))
+	assert.Len(t, consumers.Consumers, 3)
+	assert.Equal(t, "Console", consumers.Consumers[0].Consumer)
+	assert.Equal(t, "ok", consumers.Consumers[0].Status)
+	assert.Equal(t, "backlogged", consumers.Consumers[1].Status)
+	assert.Equal(t, "stopped", consumers.Consumers[2].Status)
+}
+
+func TestMessageRates(t *testing.T) {
+	now := time.Date(2025, 3, 20, 18, 30, 0, 0, time.UTC)
+	rates := newMessageRates(func() time.Time { return now })
+
+	for range 30 {
+		rates.add("Twitch")
+	}
+	now = now.Add(2 * time.Minute)
+	for range 10 {
+		rates.add("Twitch")
+		rates.add("Youtube")
+	}
+
+	all, providers := rates.snapshot()
+	assert.Equal(t, messageRate{Total: 50, LastMinute: 20, LastFiveMinutes: 50, PerMinute: 25}, all)
+	assert.Equal(t, []messageRate{
+		{Provider: "Twitch", Total: 40, LastMinute: 10, LastFiveMinutes: 40, PerMinute: 20},
+		{Provider: "Youtube", Total: 10, LastMinute: 10, LastFiveMinutes: 10, PerMinute: 5},
+	}, providers)
+
+	// The messages older than the window are only counted in the total
+	now = now.Add(4 * time.Minute)
+	all, _ = rates.snapshot()
+	assert.Equal(t, messageRate{Total: 50, LastFiveMinutes: 20, PerMinute: 4}, all)
+}
+
+func TestSimplePageConsumer_RatesAPI(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch"})
+
+	var rates struct {
+		Since     time.Time
+		All       messageRate
+		Providers []messageRate
+	}
+	assert.Equal(t, http.StatusOK, getAPI(t, consumer, "/api/v1/rates", &rates))
+	assert.Equal(t, uint64(1), rates.All.Total)
+	assert.Equal(t, "Twitch", rates.Providers[0].Provider)
+	assert.False(t, rates.Since.IsZero())
+}
+
+func TestSimplePageConsumer_API_Errors(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	var apiErr struct{ Error string }
+
+	assert.Equal(t, http.StatusNotFound, getAPI(t, consumer, "/api/v1/unknown", &apiErr))
+	assert.Equal(t, "unknown endpoint /api/v1/unknown", apiErr.Error)
+
+	mux := http.NewServeMux()
+	consumer.registerAPI(mux)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/api/v1/messages", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestSimplePageConsumer_OpenAPI(t *testing.T) {
+	var description struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]any
+	}
+	assert.Equal(t, http.StatusOK, getAPI(t, NewSimplePageConsumer(), "/api/v1/openapi.json", &description))
+	assert.Equal(t, "3.0.3", description.OpenAPI)
+
+	// Every endpoint is described
+	for _, endpoint := range []string{"messages", "providers", "rates", "consumers", "openapi.json"} {
+		assert.Contains(t, description.Paths, "/"+endpoint)
+	}
+}
@@ -0,0 +1,188 @@
+package simplepage
+
+import (
+	"log"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultPingInterval is the time between the pings sent to the pages
+	defaultPingInterval = 30 * time.Second
+	// defaultWriteWait bounds every write to a page, a page that does not read for that long is
+	// disconnected
+	defaultWriteWait = 10 * time.Second
+	// defaultClientQueueSize is the number of envelopes queued for a page, a page that falls further
+	// behind is disconnected and resumes from its cursor when it reconnects
+	defaultClientQueueSize = 256
+)
+
+// wsClient is a connected page. Its envelopes are queued by the broadcasts and written by its own
+// goroutine, so a slow page does not delay the others.
+type wsClient struct {
+	ws        *websocket.Conn
+	connected time.Time
+	// filter is set by the URL of the page
+	filter feedFilter
+	// cursor is the ID of the last message handled for the page, the messages of the history sent
+	// when it connected are skipped by the broadcasts
+	cursor int64
+	// backlog is the history written when the page connects, before the queued envelopes
+	backlog []envelope
+	send    chan envelope
+	// closed is closed when the page is disconnected
+	closed    chan struct{}
+	closeOnce sync.Once
+	sent      atomic.Uint64
+}
+
+// wsMetrics counts the pages and the envelopes written to them since the start, for the API.
+type wsMetrics struct {
+	connections     atomic.Uint64
+	sent            atomic.Uint64
+	slowDisconnects atomic.Uint64
+	writeErrors     atomic.Uint64
+}
+
+func (c *SimplePageConsumer) newWSClient(ws *websocket.Conn, filter feedFilter) *wsClient {
+	return &wsClient{
+		ws:        ws,
+		connected: time.Now(),
+		filter:    filter,
+		send:      make(chan envelope, c.clientQueueSize),
+		closed:    make(chan struct{}),
+	}
+}
+
+// enqueue queues an envelope for the page without waiting, it returns false when the queue is full.
+func (client *wsClient) enqueue(message envelope) bool {
+	select {
+	case client.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+// close disconnects the page, which ends its writer and the read loop of its connection.
+func (client *wsClient) close() {
+	client.closeOnce.Do(func() {
+		close(client.closed)
+		client.ws.Close()
+	})
+}
+
+// expectPongs sets the read deadline of the page, which is extended by its answers to the pings.
+// A page that does not answer within two ping intervals is disconnected when the read fails.
+func (c *SimplePageConsumer) expectPongs(ws *websocket.Conn) {
+	pongWait := 2 * c.pingInterval
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+}
+
+// writeLoop writes the backlog and then the queued envelopes of a page, and pings it, until the
+// page is disconnected. It is the only writer of the connection.
+func (c *SimplePageConsumer) writeLoop(client *wsClient) {
+	defer client.close()
+
+	for _, message := range client.backlog {
+		if !c.writeEnvelope(client, message) {
+			return
+		}
+	}
+	client.backlog = nil
+
+	ping := time.NewTicker(c.pingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case message := <-client.send:
+			if !c.writeEnvelope(client, message) {
+				return
+			}
+		case <-ping.C:
+			client.ws.SetWriteDeadline(time.Now().Add(c.writeWait))
+			if err := client.ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.writeFailed(client, err)
+				return
+			}
+		case <-client.closed:
+			return
+		}
+	}
+}
+
+func (c *SimplePageConsumer) writeEnvelope(client *wsClient, message envelope) bool {
+	client.ws.SetWriteDeadline(time.Now().Add(c.writeWait))
+	if err := client.ws.WriteJSON(message); err != nil {
+		c.writeFailed(client, err)
+		return false
+	}
+	client.sent.Add(1)
+	c.wsMetrics.sent.Add(1)
+	return true
+}
+
+// writeFailed counts the failed writes, except those of the pages already disconnected.
+func (c *SimplePageConsumer) writeFailed(client *wsClient, err error) {
+	select {
+	case <-client.closed:
+	default:
+		log.Printf("Error writing to page %s: %v", client.ws.RemoteAddr(), err)
+		c.wsMetrics.writeErrors.Add(1)
+	}
+}
+
+// wsClientStatus is the state of a connected page served by the API.
+type wsClientStatus struct {
+	RemoteAddr  string
+	ConnectedAt time.Time
+	// Queued is the number of envelopes waiting to be written
+	Queued int
+	Sent   uint64
+}
+
+// wsReport is the state of the WebSocket pages served by the API.
+type wsReport struct {
+	Connected int
+	// QueueSize is the number of envelopes queued for a page at most before it is disconnected
+	QueueSize       int
+	Connections     uint64
+	Sent            uint64
+	SlowDisconnects uint64
+	WriteErrors     uint64
+	Clients         []wsClientStatus
+}
+
+func (c *SimplePageConsumer) wsReport() wsReport {
+	report := wsReport{
+		QueueSize:       c.clientQueueSize,
+		Connections:     c.wsMetrics.connections.Load(),
+		Sent:            c.wsMetrics.sent.Load(),
+		SlowDisconnects: c.wsMetrics.slowDisconnects.Load(),
+		WriteErrors:     c.wsMetrics.writeErrors.Load(),
+		Clients:         []wsClientStatus{},
+	}
+
+	c.wsClientsMux.Lock()
+	defer c.wsClientsMux.Unlock()
+	report.Connected = len(c.wsClients)
+	for client := range c.wsClients {
+		report.Clients = append(report.Clients, wsClientStatus{
+			RemoteAddr:  client.ws.RemoteAddr().String(),
+			ConnectedAt: client.connected,
+			Queued:      len(client.send),
+			Sent:        client.sent.Load(),
+		})
+	}
+	slices.SortFunc(report.Clients, func(a, b wsClientStatus) int {
+		return a.ConnectedAt.Compare(b.ConnectedAt)
+	})
+	return report
+}
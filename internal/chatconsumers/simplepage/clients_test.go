@@ -0,0 +1,108 @@
+package simplepage
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimplePageConsumer_Broadcast_SlowClient(t *testing.T) {
+	_, dial := startWebSocket(t, nil)
+	consumer := NewSimplePageConsumer()
+	consumer.clientQueueSize = 2
+
+	// The clients are not written to, so their queues fill up
+	slowConn, _, err := dial("")
+	assert.NoError(t, err)
+	slow := consumer.newWSClient(slowConn, feedFilter{})
+	filteredConn, _, err := dial("")
+	assert.NoError(t, err)
+	filtered := consumer.newWSClient(filteredConn, feedFilter{providers: []string{"youtube"}})
+	consumer.wsClients[slow] = struct{}{}
+	consumer.wsClients[filtered] = struct{}{}
+
+	for id := int64(1); id <= 3; id++ {
+		consumer.broadcastMessage(history.Entry{ID: id, Message: chatmodels.ChatMessage{Provider: "Twitch"}})
+	}
+
+	assert.NotContains(t, consumer.wsClients, slow)
+	assert.Contains(t, consumer.wsClients, filtered)
+	assert.Len(t, slow.send, 2)
+	assert.Equal(t, uint64(1), consumer.wsMetrics.slowDisconnects.Load())
+	select {
+	case <-slow.closed:
+	default:
+		t.Error("the slow client is not closed")
+	}
+}
+
+func TestSimplePageConsumer_WebSocket_SlowClientDoesNotDelayOthers(t *testing.T) {
+	consumer, dial := startWebSocket(t, func(c *SimplePageConsumer) {
+		c.writeWait = 50 * time.Millisecond
+		c.clientQueueSize = 8
+	})
+	clients := func() int {
+		consumer.wsClientsMux.Lock()
+		defer consumer.wsClientsMux.Unlock()
+		return len(consumer.wsClients)
+	}
+
+	fast, _, err := dial("")
+	assert.NoError(t, err)
+	// The slow page never reads, its writes block once the buffers of the connection are full
+	_, _, err = dial("")
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return clients() == 2 }, time.Second, time.Millisecond)
+
+	content := strings.Repeat("x", 128*1024)
+	for i := 1; i <= 200; i++ {
+		consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: content})
+		id, _ := readContent(t, fast)
+		if !assert.Equal(t, int64(i), id) {
+			return
+		}
+	}
+
+	assert.Eventually(t, func() bool { return clients() == 1 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return sentToClients(consumer) == 200 }, time.Second, time.Millisecond)
+	report := consumer.wsReport()
+	assert.Equal(t, 1, report.Connected)
+	assert.Equal(t, uint64(2), report.Connections)
+	assert.Equal(t, uint64(1), report.SlowDisconnects+report.WriteErrors)
+	assert.Len(t, report.Clients, 1)
+}
+
+// sentToClients is the number of envelopes written to the connected pages
+func sentToClients(c *SimplePageConsumer) uint64 {
+	var sent uint64
+	for _, client := range c.wsReport().Clients {
+		sent += client.Sent
+	}
+	return sent
+}
+
+func TestSimplePageConsumer_ClientsAPI(t *testing.T) {
+	consumer, dial := startWebSocket(t, nil)
+	ws, _, err := dial("")
+	assert.NoError(t, err)
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "hello"})
+	readContent(t, ws)
+	// The envelope is counted once its write returns
+	assert.Eventually(t, func() bool { return consumer.wsReport().Sent == 1 }, time.Second, time.Millisecond)
+
+	var clients struct{ WebSocket wsReport }
+	assert.Equal(t, http.StatusOK, getAPI(t, consumer, "/api/v1/clients", &clients))
+	assert.Equal(t, 1, clients.WebSocket.Connected)
+	assert.Equal(t, defaultClientQueueSize, clients.WebSocket.QueueSize)
+	assert.Equal(t, uint64(1), clients.WebSocket.Sent)
+	if assert.Len(t, clients.WebSocket.Clients, 1) {
+		assert.Equal(t, ws.LocalAddr().String(), clients.WebSocket.Clients[0].RemoteAddr)
+		assert.Equal(t, 0, clients.WebSocket.Clients[0].Queued)
+		assert.Equal(t, uint64(1), clients.WebSocket.Clients[0].Sent)
+	}
+}
@@ -0,0 +1,90 @@
+package simplepage
+
+import (
+	"context"
+	"crypto/subtle"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+const (
+	commandAuth     = "auth"
+	commandModerate = "moderate"
+)
+
+// moderationTimeout bounds the provider API call made for a moderation command
+const moderationTimeout = 10 * time.Second
+
+// command is sent by the page over the WebSocket. Moderation commands are only accepted
+// once the connection is authenticated with the moderation token.
+type command struct {
+	Command string
+	// ID is returned in the result so the page can match it with the command
+	ID        string
+	Token     string
+	Provider  string
+	Action    chatmodels.ModerationAction
+	MessageID string
+	AuthorID  string
+	// Seconds is the length of a timeout
+	Seconds int
+	Reason  string
+}
+
+// result answers a command, Error is empty when the command succeeded.
+type result struct {
+	ID    string
+	Error string
+}
+
+// SetModerator sets the function used to apply the moderation commands of the page.
+func (c *SimplePageConsumer) SetModerator(moderate func(ctx context.Context, request chatmodels.ModerationRequest) error) {
+	c.moderate = moderate
+}
+
+// handleCommand runs a command for a connection. authToken is the token the connection
+// authenticated with, the token after the command is returned with the result. Connections
+// authenticated with a previous token are rejected once the token is changed.
+func (c *SimplePageConsumer) handleCommand(ctx context.Context, cmd command, authToken string) (result, string) {
+	res := result{ID: cmd.ID}
+
+	moderationToken := c.getModerationToken()
+	if moderationToken == "" || c.moderate == nil {
+		res.Error = "moderation is disabled"
+		return res, ""
+	}
+
+	switch cmd.Command {
+	case commandAuth:
+		authToken = ""
+		if subtle.ConstantTimeCompare([]byte(cmd.Token), []byte(moderationToken)) == 1 {
+			authToken = cmd.Token
+		} else {
+			res.Error = "invalid moderation token"
+		}
+	case commandModerate:
+		if subtle.ConstantTimeCompare([]byte(authToken), []byte(moderationToken)) != 1 {
+			authToken = ""
+			res.Error = "not authenticated"
+			break
+		}
+		moderationCtx, cancel := context.WithTimeout(ctx, moderationTimeout)
+		defer cancel()
+		err := c.moderate(moderationCtx, chatmodels.ModerationRequest{
+			Provider:  cmd.Provider,
+			Action:    cmd.Action,
+			MessageID: cmd.MessageID,
+			AuthorID:  cmd.AuthorID,
+			Duration:  time.Duration(cmd.Seconds) * time.Second,
+			Reason:    cmd.Reason,
+		})
+		if err != nil {
+			res.Error = err.Error()
+		}
+	default:
+		res.Error = "unknown command: " + cmd.Command
+	}
+
+	return res, authToken
+}
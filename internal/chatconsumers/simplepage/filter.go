@@ -0,0 +1,109 @@
+package simplepage
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+)
+
+const (
+	// typeMessage, typeEvent and typeModeration are the types of what the feeds send: chat
+	// messages, non-chat events and moderation actions
+	typeMessage    = "message"
+	typeEvent      = "event"
+	typeModeration = "moderation"
+)
+
+// eventKinds are the event kinds accepted as types, to receive only some events
+var eventKinds = []chatmodels.EventKind{
+	chatmodels.EventSubscription,
+	chatmodels.EventResubscription,
+	chatmodels.EventGiftSubscription,
+	chatmodels.EventCommunityGift,
+	chatmodels.EventRaid,
+	chatmodels.EventBits,
+	chatmodels.EventSuperChat,
+	chatmodels.EventSuperSticker,
+}
+
+// feedFilter selects the chat messages, events and moderation actions sent to a client.
+type feedFilter struct {
+	// providers are the names of the providers sent, case insensitive. Every provider when empty
+	providers []string
+	// types are message, event or the kinds of the events sent, and moderation. Every type when empty
+	types []string
+	// minRole drops the chat messages of the authors without the role or a more privileged one
+	minRole chatmodels.Role
+	// hideCommands drops the chat bot commands
+	hideCommands bool
+}
+
+// parseFeedFilter reads the provider, type, min_role and hide_commands query parameters. The
+// provider and type parameters can be repeated or hold a comma separated list.
+func parseFeedFilter(values url.Values) (feedFilter, error) {
+	filter := feedFilter{
+		providers: splitValues(values["provider"]),
+		types:     splitValues(values["type"]),
+		minRole:   chatmodels.Role(strings.ToLower(values.Get("min_role"))),
+	}
+	if value := values.Get("hide_commands"); value != "" {
+		hide, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, fmt.Errorf("invalid hide_commands %q, use true or false", value)
+		}
+		filter.hideCommands = hide
+	}
+
+	for _, t := range filter.types {
+		if t != typeMessage && t != typeEvent && t != typeModeration && !slices.Contains(eventKinds, chatmodels.EventKind(t)) {
+			return filter, fmt.Errorf("unknown type %q, use message, event, moderation or an event kind", t)
+		}
+	}
+	if filter.minRole != "" && filter.minRole.Rank() == 0 {
+		return filter, fmt.Errorf("unknown role %q, use subscriber, member, vip, moderator or broadcaster", filter.minRole)
+	}
+	return filter, nil
+}
+
+// splitValues returns the lower case values of repeated or comma separated parameters.
+func splitValues(values []string) []string {
+	var split []string
+	for _, value := range values {
+		for part := range strings.SplitSeq(value, ",") {
+			if part = strings.ToLower(strings.TrimSpace(part)); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+	return split
+}
+
+// matches reports whether the history entry, chat message, event or moderation event is sent.
+// What is not chat, like the configuration pushed to the pages, is always sent.
+func (f feedFilter) matches(item any) bool {
+	switch item := item.(type) {
+	case history.Entry:
+		return f.matches(item.Message)
+	case chatmodels.ChatMessage:
+		return f.hasProvider(item.Provider) && f.hasType(typeMessage) && item.HasRoleAtLeast(f.minRole) && !(f.hideCommands && item.IsCommand())
+	case chatmodels.ChatEvent:
+		return f.hasProvider(item.Provider) && (f.hasType(typeEvent) || f.hasType(string(item.Kind)))
+	case chatmodels.ModerationEvent:
+		return f.hasProvider(item.Provider) && f.hasType(typeModeration)
+	default:
+		return true
+	}
+}
+
+func (f feedFilter) hasProvider(provider string) bool {
+	return len(f.providers) == 0 || slices.Contains(f.providers, strings.ToLower(provider))
+}
+
+func (f feedFilter) hasType(t string) bool {
+	return len(f.types) == 0 || slices.Contains(f.types, t)
+}
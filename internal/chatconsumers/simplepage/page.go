@@ -0,0 +1,79 @@
+package simplepage
+
+import (
+	"cmp"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/a-h/templ"
+)
+
+//go:generate go run github.com/a-h/templ/cmd/templ@v0.3.857 generate
+
+// staticFiles are the style sheet shared by the themes and the script of the page
+//
+//go:embed static
+var staticFiles embed.FS
+
+// contentSecurityPolicy only lets the page run its own scripts and style sheets, so markup that
+// would get through the escaping still can not run. The emotes are loaded from the provider CDNs.
+const contentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self'; img-src 'self' https: data:; connect-src 'self'; base-uri 'none'; form-action 'none'"
+
+// handlePage renders the page with the current history and display options, in the theme
+// selected by the theme query parameter or the configured one. The other query parameters are
+// the settings of the page.
+func (c *SimplePageConsumer) handlePage(w http.ResponseWriter, r *http.Request) {
+	options := c.getDisplayOptions()
+	name := r.URL.Query().Get("theme")
+	if name == "" {
+		name = cmp.Or(options.Theme, defaultTheme)
+	}
+	files, ok := c.findTheme(name)
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown theme %q", name), http.StatusNotFound)
+		return
+	}
+	settings, err := parsePageSettings(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	history, cursor := c.getHistoryWithCursor()
+	messages := settings.visible(history)
+
+	w.Header().Set("Content-Security-Policy", contentSecurityPolicy)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if hasFile(files, themeTemplate) {
+		renderThemeTemplate(w, files, themePage{Theme: name, Messages: messages, Cursor: cursor, Options: options, Settings: settings, ProviderLabel: settings.providerLabel(options)})
+		return
+	}
+	theme := pageTheme{Name: name, Stylesheet: hasFile(files, themeStylesheet), Script: hasFile(files, themeScript)}
+	templ.Handler(page(messages, cursor, settings.providerLabel(options), settings, theme)).ServeHTTP(w, r)
+}
+
+// staticHandler serves the static files under /static/.
+func staticHandler() http.Handler {
+	files, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/static/", http.FileServerFS(files))
+}
+
+// emoteSrcset lists the images of an emote with their scale, like page.js does.
+func emoteSrcset(images []chatmodels.EmoteImage) string {
+	candidates := make([]string, 0, len(images))
+	for _, image := range images {
+		scale, err := strconv.ParseFloat(strings.TrimSuffix(image.Scale, "x"), 64)
+		if err != nil {
+			continue
+		}
+		candidates = append(candidates, image.URL+" "+strconv.FormatFloat(scale, 'f', -1, 64)+"x")
+	}
+	return strings.Join(candidates, ", ")
+}
@@ -0,0 +1,216 @@
+package simplepage
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/themes"
+	"github.com/stretchr/testify/assert"
+)
+
+func renderPage(t *testing.T, c *SimplePageConsumer) *httptest.ResponseRecorder {
+	return renderPath(t, c, "/", http.StatusOK)
+}
+
+func renderPath(t *testing.T, c *SimplePageConsumer, path string, status int) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.handlePage)
+	mux.HandleFunc("/themes/", c.handleThemeFile)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, status, recorder.Code, path)
+	return recorder
+}
+
+func TestSimplePageConsumer_Page_EscapesMessages(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+
+	consumer.Consume(chatmodels.ChatMessage{
+		ID:                `1" onmouseover="alert(1)`,
+		Provider:          `<script>alert("provider")</script>`,
+		ProviderShortName: `"><script>alert("short")</script>`,
+		AuthorName:        `<img src=x onerror=alert("author")>`,
+		Content:           `</div><script>alert("content")</script>`,
+	})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", AuthorName: "Viewer", Content: "Tom & Jerry <3"})
+
+	body := renderPage(t, consumer).Body.String()
+
+	// The only script and element attributes are the page's own
+	assert.Equal(t, 1, strings.Count(body, "<script"))
+	assert.Contains(t, body, `<script src="/static/page.js"></script>`)
+	assert.NotContains(t, body, "<img")
+	assert.NotContains(t, body, `" onmouseover=`)
+	assert.NotContains(t, body, `"><script>`)
+
+	assert.Contains(t, body, `&lt;script&gt;alert(&#34;provider&#34;)&lt;/script&gt;`)
+	assert.Contains(t, body, `data-short-name="&#34;&gt;&lt;script&gt;alert(&#34;short&#34;)&lt;/script&gt;"`)
+	assert.Contains(t, body, `&lt;img src=x onerror=alert(&#34;author&#34;)&gt;:`)
+	assert.Contains(t, body, `&lt;/div&gt;&lt;script&gt;alert(&#34;content&#34;)&lt;/script&gt;`)
+	assert.Contains(t, body, `data-id="1&#34; onmouseover=&#34;alert(1)"`)
+	assert.Contains(t, body, "Tom &amp; Jerry &lt;3")
+}
+
+func TestSimplePageConsumer_Page_Fragments(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+
+	consumer.Consume(chatmodels.ChatMessage{
+		Provider:    "Twitch",
+		AuthorName:  "Viewer",
+		AuthorColor: `red"><script>alert(1)</script>`,
+		Content:     "Kappa hi @friend <b> Cheer100",
+		Fragments: []chatmodels.Fragment{
+			{Type: chatmodels.FragmentEmote, Text: "Kappa", Images: []chatmodels.EmoteImage{
+				{Scale: "1.0", URL: "https://cdn.example/kappa/1.0"},
+				{Scale: "2.0", URL: "https://cdn.example/kappa/2.0"},
+			}},
+			{Type: chatmodels.FragmentText, Text: " hi "},
+			{Type: chatmodels.FragmentMention, Text: "@friend", Mention: "friend"},
+			{Type: chatmodels.FragmentText, Text: " <b> "},
+			{Type: chatmodels.FragmentCheermote, Text: "Cheer100", Bits: 100, Images: []chatmodels.EmoteImage{{Scale: "1.0", URL: "https://cdn.example/cheer/1"}}},
+			// An emote without images falls back to its text
+			{Type: chatmodels.FragmentEmote, Text: "LUL"},
+		},
+	})
+
+	body := renderPage(t, consumer).Body.String()
+	assert.Contains(t, body, `<div class="user" data-color="red&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">Viewer:</div>`)
+	assert.Contains(t, body, `<div class="messagecontents">`+
+		`<img class="emote" src="https://cdn.example/kappa/1.0" srcset="https://cdn.example/kappa/1.0 1x, https://cdn.example/kappa/2.0 2x" alt="Kappa" title="Kappa">`+
+		` hi <span class="mention">@friend</span> &lt;b&gt; `+
+		`<img class="emote" src="https://cdn.example/cheer/1" srcset="https://cdn.example/cheer/1 1x" alt="Cheer100" title="Cheer100"><span class="bits">100</span>`+
+		`LUL</div>`)
+	assert.Equal(t, 1, strings.Count(body, "<script"))
+}
+
+func TestSimplePageConsumer_Page_DisplayOptions(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+	consumer.display = config.SimplePageConfig{ShortenProvider: true}
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", ProviderShortName: "Tw", AuthorName: "Viewer", Content: "hello"})
+
+	body := renderPage(t, consumer).Body.String()
+	assert.Contains(t, body, `<body class="shortprovider" data-theme="classic" data-cursor="1" data-provider-label="" data-font-size="0" data-max-messages="0" data-fade-out="0">`)
+	assert.Contains(t, body, `data-name="Twitch" data-short-name="Tw">Tw</div>`)
+}
+
+func TestSimplePageConsumer_Page_Settings(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+	consumer.display = config.SimplePageConfig{ShortenProvider: true}
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", AuthorName: "Viewer", Content: "hello"})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Youtube", AuthorName: "Member", Content: "hi", Roles: []chatmodels.Role{chatmodels.RoleMember}})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", AuthorName: "Vip", Content: "!uptime", Roles: []chatmodels.Role{chatmodels.RoleVIP}})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", AuthorName: "Subscriber", Content: "first", Roles: []chatmodels.Role{chatmodels.RoleSubscriber}})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", AuthorName: "Moderator", Content: "second", Roles: []chatmodels.Role{chatmodels.RoleModerator}})
+
+	body := renderPath(t, consumer, "/?provider_label=hidden&font_size=32&max_messages=1&fade_out=2.5&provider=twitch&min_role=subscriber&hide_commands=true", http.StatusOK).Body.String()
+	assert.Contains(t, body, `<body class="hideprovider" data-theme="classic" data-cursor="5" data-provider-label="hidden" data-font-size="32" data-max-messages="1" data-fade-out="2500">`)
+	// Only the last message matching the filter is rendered
+	assert.Equal(t, 1, strings.Count(body, `class="message"`))
+	assert.Contains(t, body, `<div class="messagecontents">second</div>`)
+
+	body = renderPath(t, consumer, "/?provider=twitch&min_role=subscriber", http.StatusOK).Body.String()
+	assert.Equal(t, 3, strings.Count(body, `class="message"`))
+	assert.Contains(t, body, `<div class="messagecontents">!uptime</div>`)
+
+	for _, query := range []string{"provider_label=tiny", "font_size=2", "max_messages=-1", "fade_out=soon", "hide_commands=maybe", "min_role=viewer"} {
+		renderPath(t, consumer, "/?"+query, http.StatusBadRequest)
+	}
+}
+
+func TestPageSettings_ProviderLabel(t *testing.T) {
+	assert.Equal(t, providerLabelFull, pageSettings{}.providerLabel(config.SimplePageConfig{}))
+	assert.Equal(t, providerLabelShort, pageSettings{}.providerLabel(config.SimplePageConfig{ShortenProvider: true}))
+	assert.Equal(t, providerLabelHidden, pageSettings{}.providerLabel(config.SimplePageConfig{HideProvider: true}))
+	// The URL of the page takes precedence over the configuration
+	assert.Equal(t, providerLabelFull, pageSettings{ProviderLabel: providerLabelFull}.providerLabel(config.SimplePageConfig{HideProvider: true}))
+}
+
+func TestSimplePageConsumer_Page_Headers(t *testing.T) {
+	recorder := renderPage(t, NewSimplePageConsumer())
+	assert.Equal(t, contentSecurityPolicy, recorder.Header().Get("Content-Security-Policy"))
+	assert.Contains(t, contentSecurityPolicy, "script-src 'self';")
+	assert.NotContains(t, contentSecurityPolicy, "unsafe-inline")
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+}
+
+func TestStaticHandler(t *testing.T) {
+	server := httptest.NewServer(staticHandler())
+	defer server.Close()
+
+	for path, contentType := range map[string]string{"/static/page.js": "text/javascript", "/static/page.css": "text/css"} {
+		response, err := http.Get(server.URL + path)
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(response.Body)
+		response.Body.Close()
+		assert.Equal(t, http.StatusOK, response.StatusCode, path)
+		assert.Contains(t, response.Header.Get("Content-Type"), contentType)
+		assert.NotEmpty(t, body)
+	}
+}
+
+func TestSimplePageConsumer_Page_BuiltinThemes(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+
+	names := themes.Builtin()
+	for _, name := range names {
+		body := renderPath(t, consumer, "/?theme="+name, http.StatusOK).Body.String()
+		assert.Contains(t, body, `<link rel="stylesheet" href="/themes/`+name+`/theme.css">`)
+		assert.Contains(t, renderPath(t, consumer, "/themes/"+name+"/theme.css", http.StatusOK).Body.String(), "#chatbox")
+	}
+	assert.Len(t, names, 4)
+
+	// The configured theme is used when the URL has none
+	consumer.display.Theme = "ticker"
+	assert.Contains(t, renderPage(t, consumer).Body.String(), `data-theme="ticker"`)
+
+	renderPath(t, consumer, "/?theme=neon", http.StatusNotFound)
+	renderPath(t, consumer, "/?theme=..%2Fstatic", http.StatusNotFound)
+	renderPath(t, consumer, "/themes/neon/theme.css", http.StatusNotFound)
+	renderPath(t, consumer, "/themes/classic/missing.css", http.StatusNotFound)
+}
+
+func TestSimplePageConsumer_Page_UserThemes(t *testing.T) {
+	dir := t.TempDir()
+	writeFile := func(name, content string) {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	// A user theme hides the built-in theme with the same name
+	writeFile("bubbles/theme.css", "#chatbox { color: pink; }")
+	writeFile("bubbles/theme.js", "console.log('bubbles');")
+	writeFile("neon/page.html", `<body data-theme="{{.Theme}}">{{range .Messages}}<p title="{{.AuthorName}}">{{.Content}}</p>{{end}}</body>`)
+	writeFile("broken/page.html", `{{.Missing}`)
+
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+	consumer.display.ThemesDir = dir
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", AuthorName: `"><script>alert(1)</script>`, Content: `<img src=x onerror=alert(2)>`})
+
+	body := renderPath(t, consumer, "/?theme=bubbles", http.StatusOK).Body.String()
+	assert.Contains(t, body, `<script src="/themes/bubbles/theme.js"></script>`)
+	assert.Equal(t, "#chatbox { color: pink; }", renderPath(t, consumer, "/themes/bubbles/theme.css", http.StatusOK).Body.String())
+
+	recorder := renderPath(t, consumer, "/?theme=neon", http.StatusOK)
+	assert.Equal(t, contentSecurityPolicy, recorder.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, `<body data-theme="neon"><p title="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;">&lt;img src=x onerror=alert(2)&gt;</p></body>`, recorder.Body.String())
+
+	renderPath(t, consumer, "/?theme=broken", http.StatusInternalServerError)
+	// The built-in themes are still available
+	renderPath(t, consumer, "/?theme=marquee", http.StatusOK)
+}
@@ -0,0 +1,52 @@
+package simplepage
+
+import (
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+)
+
+// protocolVersion is the version of the WebSocket protocol, it changes when the envelopes change
+// in a way the pages of a previous version can not read
+const protocolVersion = 1
+
+// The types of the envelopes sent to the pages
+const (
+	envelopeMessage  = "message"
+	envelopeEvent    = "event"
+	envelopeDeletion = "deletion"
+	envelopeConfig   = "config"
+	envelopeStatus   = "status"
+	envelopeResult   = "result"
+)
+
+// envelope wraps everything sent to the pages over the WebSocket.
+type envelope struct {
+	Version int
+	Type    string
+	// ID is the entry ID of the chat messages, the pages reconnect with the last one as cursor
+	ID   int64 `json:",omitempty"`
+	Data any
+}
+
+// newEnvelope wraps a history entry, chat event, moderation event, provider status, display
+// options or command result.
+func newEnvelope(item any) envelope {
+	message := envelope{Version: protocolVersion, Data: item}
+	switch item := item.(type) {
+	case history.Entry:
+		message.Type = envelopeMessage
+		message.ID = item.ID
+		message.Data = item.Message
+	case chatmodels.ChatEvent:
+		message.Type = envelopeEvent
+	case chatmodels.ModerationEvent:
+		message.Type = envelopeDeletion
+	case chatmodels.ProviderStatus:
+		message.Type = envelopeStatus
+	case displayOptions:
+		message.Type = envelopeConfig
+	case result:
+		message.Type = envelopeResult
+	}
+	return message
+}
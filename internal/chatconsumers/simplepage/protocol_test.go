@@ -0,0 +1,147 @@
+package simplepage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// startWebSocket serves the WebSocket of a new consumer set up by configure, the returned function
+// connects a page with the query parameters.
+func startWebSocket(t *testing.T, configure func(c *SimplePageConsumer)) (*SimplePageConsumer, func(query string) (*websocket.Conn, *http.Response, error)) {
+	consumer := NewSimplePageConsumer()
+	if configure != nil {
+		configure(consumer)
+	}
+	go consumer.handleMessages()
+	server := httptest.NewServer(http.HandlerFunc(consumer.handleConnections))
+	t.Cleanup(func() {
+		close(consumer.done)
+		server.Close()
+	})
+
+	dial := func(query string) (*websocket.Conn, *http.Response, error) {
+		ws, response, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws?"+query, nil)
+		if err == nil {
+			t.Cleanup(func() { ws.Close() })
+		}
+		return ws, response, err
+	}
+	return consumer, dial
+}
+
+func readEnvelope(t *testing.T, ws *websocket.Conn) envelope {
+	var message envelope
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	assert.NoError(t, ws.ReadJSON(&message))
+	return message
+}
+
+func readContent(t *testing.T, ws *websocket.Conn) (id int64, content string) {
+	message := readEnvelope(t, ws)
+	assert.Equal(t, envelopeMessage, message.Type)
+	return message.ID, message.Data.(map[string]any)["Content"].(string)
+}
+
+func TestNewEnvelope(t *testing.T) {
+	message := chatmodels.ChatMessage{Provider: "Twitch", Content: "hello"}
+	assert.Equal(t, envelope{Version: protocolVersion, Type: envelopeMessage, ID: 7, Data: message}, newEnvelope(history.Entry{ID: 7, Message: message}))
+
+	tests := []struct {
+		item     any
+		expected string
+	}{
+		{chatmodels.ChatEvent{Provider: "Twitch"}, envelopeEvent},
+		{chatmodels.ModerationEvent{Provider: "Twitch"}, envelopeDeletion},
+		{chatmodels.ProviderStatus{Provider: "Twitch"}, envelopeStatus},
+		{displayOptions{HideProvider: true}, envelopeConfig},
+		{result{ID: "1"}, envelopeResult},
+	}
+	for _, test := range tests {
+		assert.Equal(t, envelope{Version: protocolVersion, Type: test.expected, Data: test.item}, newEnvelope(test.item))
+	}
+}
+
+func TestSimplePageConsumer_WebSocket_Resume(t *testing.T) {
+	consumer, dial := startWebSocket(t, func(c *SimplePageConsumer) {
+		c.providerStatuses = func() []chatmodels.ProviderStatus {
+			return []chatmodels.ProviderStatus{{Provider: "Twitch", State: chatmodels.ProviderConnected}}
+		}
+	})
+	for _, content := range []string{"first", "second", "third"} {
+		consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: content})
+	}
+
+	// Only the messages after the cursor are sent, followed by the provider statuses
+	ws, _, err := dial("protocol=1&cursor=1")
+	assert.NoError(t, err)
+	id, content := readContent(t, ws)
+	assert.Equal(t, int64(2), id)
+	assert.Equal(t, "second", content)
+	id, content = readContent(t, ws)
+	assert.Equal(t, int64(3), id)
+	assert.Equal(t, "third", content)
+	status := readEnvelope(t, ws)
+	assert.Equal(t, envelopeStatus, status.Type)
+	assert.Equal(t, "Twitch", status.Data.(map[string]any)["Provider"])
+
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "fourth"})
+	consumer.ConsumeStatus(chatmodels.ProviderStatus{Provider: "Twitch", State: chatmodels.ProviderReconnecting})
+	id, content = readContent(t, ws)
+	assert.Equal(t, int64(4), id)
+	assert.Equal(t, "fourth", content)
+	assert.Equal(t, envelopeStatus, readEnvelope(t, ws).Type)
+
+	// A cursor after the last message comes from before a restart, the recent history is sent
+	ws, _, err = dial("cursor=100")
+	assert.NoError(t, err)
+	id, content = readContent(t, ws)
+	assert.Equal(t, int64(1), id)
+	assert.Equal(t, "first", content)
+}
+
+func TestSimplePageConsumer_WebSocket_BadRequest(t *testing.T) {
+	_, dial := startWebSocket(t, nil)
+	for _, query := range []string{"protocol=2", "cursor=soon", "cursor=-1"} {
+		_, response, err := dial(query)
+		assert.Error(t, err, query)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, query)
+	}
+}
+
+func TestSimplePageConsumer_WebSocket_DeadClient(t *testing.T) {
+	consumer, dial := startWebSocket(t, func(c *SimplePageConsumer) {
+		c.pingInterval = 20 * time.Millisecond
+	})
+	clients := func() int {
+		consumer.wsClientsMux.Lock()
+		defer consumer.wsClientsMux.Unlock()
+		return len(consumer.wsClients)
+	}
+
+	// The pings are answered while the connection is read
+	alive, _, err := dial("")
+	assert.NoError(t, err)
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	// The connection that is never read does not answer the pings
+	_, _, err = dial("")
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool { return clients() == 2 }, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return clients() == 1 }, time.Second, 5*time.Millisecond)
+	time.Sleep(5 * consumer.pingInterval)
+	assert.Equal(t, 1, clients())
+}
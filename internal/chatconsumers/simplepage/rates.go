@@ -0,0 +1,112 @@
+package simplepage
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rateWindow is the longest period the message rates are counted over, per second
+const rateWindow = 5 * time.Minute
+
+// messageRates counts the messages received per provider over the last minutes.
+type messageRates struct {
+	mux       sync.Mutex
+	now       func() time.Time
+	started   time.Time
+	providers map[string]*providerRate
+}
+
+// providerRate counts the messages of a provider, buckets is a ring of per second counters.
+type providerRate struct {
+	total   uint64
+	buckets [int(rateWindow / time.Second)]rateBucket
+}
+
+type rateBucket struct {
+	second int64
+	count  int
+}
+
+// messageRate is the rate of messages of a provider, or of every provider, served by the API.
+type messageRate struct {
+	Provider        string `json:",omitempty"`
+	Total           uint64
+	LastMinute      int
+	LastFiveMinutes int
+	// PerMinute is the average over the last five minutes, or since the start when shorter
+	PerMinute float64
+}
+
+func newMessageRates(now func() time.Time) *messageRates {
+	return &messageRates{
+		now:       now,
+		started:   now(),
+		providers: make(map[string]*providerRate),
+	}
+}
+
+func (r *messageRates) add(provider string) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	rate, ok := r.providers[provider]
+	if !ok {
+		rate = &providerRate{}
+		r.providers[provider] = rate
+	}
+	second := r.now().Unix()
+	bucket := &rate.buckets[second%int64(len(rate.buckets))]
+	if bucket.second != second {
+		*bucket = rateBucket{second: second}
+	}
+	bucket.count++
+	rate.total++
+}
+
+// snapshot returns the rates of every provider, sorted by name, and the rate of all of them.
+func (r *messageRates) snapshot() (all messageRate, providers []messageRate) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+
+	now := r.now()
+	elapsed := min(now.Sub(r.started), rateWindow)
+	providers = []messageRate{}
+	for name, rate := range r.providers {
+		current := messageRate{
+			Provider:        name,
+			Total:           rate.total,
+			LastMinute:      rate.count(now, time.Minute),
+			LastFiveMinutes: rate.count(now, rateWindow),
+		}
+		current.PerMinute = perMinute(current.LastFiveMinutes, elapsed)
+		providers = append(providers, current)
+
+		all.Total += current.Total
+		all.LastMinute += current.LastMinute
+		all.LastFiveMinutes += current.LastFiveMinutes
+	}
+	all.PerMinute = perMinute(all.LastFiveMinutes, elapsed)
+	slices.SortFunc(providers, func(a, b messageRate) int { return strings.Compare(a.Provider, b.Provider) })
+	return all, providers
+}
+
+// count returns the messages received in the period before now.
+func (p *providerRate) count(now time.Time, period time.Duration) int {
+	from := now.Add(-period).Unix()
+	count := 0
+	for _, bucket := range p.buckets {
+		if bucket.second > from && bucket.second <= now.Unix() {
+			count += bucket.count
+		}
+	}
+	return count
+}
+
+func perMinute(count int, elapsed time.Duration) float64 {
+	if elapsed < time.Minute {
+		elapsed = time.Minute
+	}
+	return float64(count) / elapsed.Minutes()
+}
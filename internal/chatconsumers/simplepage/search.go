@@ -0,0 +1,91 @@
+package simplepage
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/history"
+)
+
+const (
+	// defaultSearchLimit is the number of messages returned when the search has no limit
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// searchPage is a page of search results, Older is the cursor to pass as before to get the
+// previous matches.
+type searchPage struct {
+	Messages []history.Entry
+	Older    string `json:",omitempty"`
+}
+
+// handleSearchAPI searches the history database. The query parameters are provider, author, q
+// (text in the content), from and to (RFC 3339 times), before (cursor) and limit. The most recent
+// matches are returned, in chronological order.
+func (c *SimplePageConsumer) handleSearchAPI(r *http.Request) (any, error) {
+	c.storeMux.RLock()
+	defer c.storeMux.RUnlock()
+	if c.store == nil {
+		return nil, apiError{status: http.StatusNotFound, message: "history is not enabled"}
+	}
+	query, err := parseSearch(r.URL.Query())
+	if err != nil {
+		return nil, badRequest("%s", err)
+	}
+
+	// One more match is read to know whether there are more
+	limit := query.Limit
+	query.Limit++
+	entries, err := c.store.Query(r.Context(), query)
+	if err != nil {
+		return nil, fmt.Errorf("error searching history: %w", err)
+	}
+	page := searchPage{Messages: entries}
+	if page.Messages == nil {
+		page.Messages = []history.Entry{}
+	}
+	if len(entries) > limit {
+		page.Messages = entries[1:]
+		page.Older = formatCursor(page.Messages[0].ID)
+	}
+	return page, nil
+}
+
+// parseSearch converts the search parameters into a history query.
+func parseSearch(values url.Values) (history.Query, error) {
+	query := history.Query{
+		Provider: values.Get("provider"),
+		Author:   values.Get("author"),
+		Text:     values.Get("q"),
+		Limit:    defaultSearchLimit,
+	}
+
+	for name, target := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		if value := values.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return query, fmt.Errorf("invalid %s %q, use an RFC 3339 time", name, value)
+			}
+			*target = parsed
+		}
+	}
+	if value := values.Get("before"); value != "" {
+		before, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || before < 1 {
+			return query, fmt.Errorf("invalid before %q", value)
+		}
+		query.Before = before
+	}
+	if value := values.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxSearchLimit {
+			return query, fmt.Errorf("invalid limit %q, use a number between 1 and %d", value, maxSearchLimit)
+		}
+		query.Limit = limit
+	}
+	return query, nil
+}
@@ -0,0 +1,124 @@
+package simplepage
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimplePageConsumer_History_RestoredAfterRestart(t *testing.T) {
+	cfg := config.HistoryConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "history.db")}
+
+	consumer := NewSimplePageConsumer()
+	assert.NoError(t, consumer.openHistory(context.Background(), cfg))
+	drain(consumer)
+	for i := range historySize + 5 {
+		consumer.Consume(chatmodels.ChatMessage{ID: string(rune('a' + i)), Provider: "Twitch", AuthorID: "7", Content: "hello", Timestamp: time.Now()})
+	}
+	consumer.ConsumeModeration(chatmodels.ModerationEvent{Provider: "Twitch", Action: chatmodels.ModerationDeleteMessage, MessageID: string(rune('a' + historySize + 4))})
+	close(consumer.done)
+	consumer.closeHistory()
+
+	restarted := NewSimplePageConsumer()
+	assert.NoError(t, restarted.openHistory(context.Background(), cfg))
+	defer restarted.closeHistory()
+
+	restored := restarted.getHistory()
+	assert.Len(t, restored, historySize)
+	assert.Equal(t, string(rune('a'+4)), restored[0].ID)
+	assert.Equal(t, string(rune('a'+historySize+3)), restored[historySize-1].ID)
+}
+
+func TestSimplePageConsumer_HandleSearch(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	assert.NoError(t, consumer.openHistory(context.Background(), config.HistoryConfig{Enabled: true, Path: ":memory:"}))
+	defer consumer.closeHistory()
+	drain(consumer)
+	defer close(consumer.done)
+
+	at := time.Date(2025, 3, 20, 18, 30, 0, 0, time.UTC)
+	consumer.Consume(chatmodels.ChatMessage{ID: "1", Provider: "Twitch", AuthorName: "Viewer", Content: "first hello", Timestamp: at})
+	consumer.Consume(chatmodels.ChatMessage{ID: "2", Provider: "Youtube", AuthorName: "Fan", Content: "hello", Timestamp: at.Add(time.Minute)})
+	consumer.Consume(chatmodels.ChatMessage{ID: "3", Provider: "Twitch", AuthorName: "viewer", Content: "bye", Timestamp: at.Add(2 * time.Minute)})
+
+	search := func(query string) (int, []history.Entry) {
+		var page searchPage
+		code := getAPI(t, consumer, "/api/v1/search?"+query, &page)
+		return code, page.Messages
+	}
+
+	code, entries := search("q=hello&provider=Twitch")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "1", entries[0].Message.ID)
+
+	_, entries = search("author=VIEWER&from=2025-03-20T18:31:00Z")
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "3", entries[0].Message.ID)
+
+	var page searchPage
+	getAPI(t, consumer, "/api/v1/search?limit=2", &page)
+	assert.Len(t, page.Messages, 2)
+	assert.Equal(t, "2", page.Older)
+	older := searchPage{}
+	getAPI(t, consumer, "/api/v1/search?before="+page.Older, &older)
+	assert.Len(t, older.Messages, 1)
+	assert.Equal(t, "1", older.Messages[0].Message.ID)
+	assert.Empty(t, older.Older)
+
+	code, entries = search("q=nothing")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, entries)
+
+	code, _ = search("from=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+	code, _ = search("limit=5000")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestSimplePageConsumer_HandleSearch_Errors(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	assert.NoError(t, consumer.openHistory(context.Background(), config.HistoryConfig{Enabled: true, Path: ":memory:"}))
+	defer consumer.closeHistory()
+
+	var response struct{ Error string }
+	assert.Equal(t, http.StatusBadRequest, getAPI(t, consumer, "/api/v1/search?limit=5000", &response))
+	assert.Equal(t, "invalid limit \"5000\", use a number between 1 and 1000", response.Error)
+}
+
+func TestSimplePageConsumer_HandleSearch_DuringStop(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	cfg := &config.Config{History: config.HistoryConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "history.db")}}
+	assert.NoError(t, consumer.Start(context.Background(), cfg))
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "hello"})
+
+	// The searches in progress finish before the database is closed, the next ones find it disabled
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				var response struct{ Error string }
+				code := getAPI(t, consumer, "/api/v1/search?q=hello", &response)
+				assert.Contains(t, []int{http.StatusOK, http.StatusNotFound}, code)
+			}
+		}()
+	}
+	assert.NoError(t, consumer.Stop(context.Background()))
+	wg.Wait()
+}
+
+func TestSimplePageConsumer_HandleSearch_HistoryDisabled(t *testing.T) {
+	var response struct{ Error string }
+	assert.Equal(t, http.StatusNotFound, getAPI(t, NewSimplePageConsumer(), "/api/v1/search?q=hello", &response))
+	assert.Equal(t, "history is not enabled", response.Error)
+}
@@ -0,0 +1,112 @@
+package simplepage
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+// The provider label modes of the pages
+const (
+	providerLabelFull   = "full"
+	providerLabelShort  = "short"
+	providerLabelHidden = "hidden"
+)
+
+const (
+	minFontSize = 6
+	maxFontSize = 200
+	// maxVisibleMessages bounds max_messages, 0 shows every message
+	maxVisibleMessages = 1000
+	maxFadeOut         = time.Hour
+)
+
+// pageSettings are the display options of a page, set with the query parameters of its URL so
+// every browser source can look different while fed by the same server. The zero values keep the
+// look of the theme and the configuration.
+type pageSettings struct {
+	// ProviderLabel shows the provider name (full), its short name (short) or no provider (hidden).
+	// When empty the page follows the configuration, also when it changes
+	ProviderLabel string
+	// FontSize is the font size in pixels
+	FontSize int
+	// MaxMessages is the number of messages shown at most, the oldest are removed
+	MaxMessages int
+	// FadeOut removes the messages once they were shown for the duration
+	FadeOut time.Duration
+	// filter selects the messages shown, it is applied by the server to the history and the
+	// WebSocket of the page
+	filter feedFilter
+}
+
+// parsePageSettings reads the provider_label, font_size, max_messages and fade_out (seconds) query
+// parameters, and the feed filter parameters.
+func parsePageSettings(values url.Values) (pageSettings, error) {
+	var settings pageSettings
+	var err error
+	if settings.filter, err = parseFeedFilter(values); err != nil {
+		return settings, err
+	}
+
+	switch label := values.Get("provider_label"); label {
+	case "", providerLabelFull, providerLabelShort, providerLabelHidden:
+		settings.ProviderLabel = label
+	default:
+		return settings, fmt.Errorf("invalid provider_label %q, use full, short or hidden", label)
+	}
+	if value := values.Get("font_size"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < minFontSize || size > maxFontSize {
+			return settings, fmt.Errorf("invalid font_size %q, use a number of pixels between %d and %d", value, minFontSize, maxFontSize)
+		}
+		settings.FontSize = size
+	}
+	if value := values.Get("max_messages"); value != "" {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 0 || count > maxVisibleMessages {
+			return settings, fmt.Errorf("invalid max_messages %q, use a number between 0 and %d", value, maxVisibleMessages)
+		}
+		settings.MaxMessages = count
+	}
+	if value := values.Get("fade_out"); value != "" {
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 || seconds > maxFadeOut.Seconds() {
+			return settings, fmt.Errorf("invalid fade_out %q, use a number of seconds between 0 and %.0f", value, maxFadeOut.Seconds())
+		}
+		settings.FadeOut = time.Duration(seconds * float64(time.Second))
+	}
+	return settings, nil
+}
+
+// providerLabel returns the provider label mode of the page, from the display options of the
+// configuration when its URL does not set one.
+func (s pageSettings) providerLabel(options config.SimplePageConfig) string {
+	switch {
+	case s.ProviderLabel != "":
+		return s.ProviderLabel
+	case options.HideProvider:
+		return providerLabelHidden
+	case options.ShortenProvider:
+		return providerLabelShort
+	default:
+		return providerLabelFull
+	}
+}
+
+// visible returns the messages of the history shown by the page.
+func (s pageSettings) visible(messages []chatmodels.ChatMessage) []chatmodels.ChatMessage {
+	var visible []chatmodels.ChatMessage
+	for _, message := range messages {
+		if s.filter.matches(message) {
+			visible = append(visible, message)
+		}
+	}
+	if s.MaxMessages > 0 && len(visible) > s.MaxMessages {
+		visible = visible[len(visible)-s.MaxMessages:]
+	}
+	return visible
+}
@@ -2,282 +2,602 @@ package simplepage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/SergioCurto/ChatClient/config"
 	"github.com/SergioCurto/ChatClient/internal/chatmodels"
-	"github.com/a-h/templ"
+	"github.com/SergioCurto/ChatClient/internal/history"
 	"github.com/gorilla/websocket"
 )
 
 // SimplePageConsumer is a ChatConsumer that logs messages to an HTML page.
 type SimplePageConsumer struct {
-	Name         string
-	messages     chan chatmodels.ChatMessage
-	wsClients    map[*websocket.Conn]bool
+	Name string
+	// messages holds the history entries, events, provider statuses and display options to
+	// broadcast, in order
+	messages     chan any
+	wsClients    map[*wsClient]struct{}
 	wsClientsMux sync.Mutex
 	upgrader     websocket.Upgrader
+	// pingInterval, writeWait and clientQueueSize set how the pages are kept alive and when they
+	// are disconnected
+	pingInterval    time.Duration
+	writeWait       time.Duration
+	clientQueueSize int
+	wsMetrics       wsMetrics
+	// streamClients are the clients of the Server-Sent Events stream
+	streamClients    map[*streamClient]struct{}
+	streamClientsMux sync.Mutex
 	// history allows the page to show some of the most recent messages on page reload
-	messageHistory []chatmodels.ChatMessage
+	messageHistory []history.Entry
 	historyMutex   sync.Mutex
+	// lastID is the ID of the last message, the entry IDs come from the store when it is enabled
+	lastID int64
+	// store persists the history when the history database is enabled, it is nil otherwise.
+	// storeMux is held for reading while the store is used, so Stop waits for the requests using
+	// it before closing it
+	store    *history.Store
+	storeMux sync.RWMutex
+	server   *http.Server
+	// done is closed by Stop to end the message handling, Start replaces it when the consumer is
+	// started again. It is read with stopped
+	done    chan struct{}
+	doneMux sync.Mutex
+	// moderate applies the moderation commands, which require the moderation token
+	moderate func(ctx context.Context, request chatmodels.ModerationRequest) error
+	// providerStatuses and consumerHealth report the state served by the API, when set
+	providerStatuses func() []chatmodels.ProviderStatus
+	consumerHealth   func() []chatmodels.ConsumerHealth
+	rates            *messageRates
+	// settingsMux guards the options that can be changed while running by UpdateConfig
+	settingsMux     sync.RWMutex
+	display         config.SimplePageConfig
+	port            int
+	moderationToken string
+	history         config.HistoryConfig
+}
+
+// historySize is the number of recent messages shown on page load
+const historySize = 30
+
+// pruneInterval is the time between the deletions of the messages older than the retention
+const pruneInterval = time.Hour
+
+// displayOptions is pushed to the connected pages when the display options change.
+type displayOptions struct {
+	ShortenProvider bool
+	HideProvider    bool
 }
 
 func NewSimplePageConsumer() *SimplePageConsumer {
 	return &SimplePageConsumer{
 		Name:      "SimplePage",
-		messages:  make(chan chatmodels.ChatMessage),
-		wsClients: make(map[*websocket.Conn]bool),
+		messages:  make(chan any),
+		wsClients: make(map[*wsClient]struct{}),
 		upgrader: websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool {
 				return true
 			},
 		},
-		messageHistory: make([]chatmodels.ChatMessage, 0, 30),
+		streamClients:   make(map[*streamClient]struct{}),
+		pingInterval:    defaultPingInterval,
+		writeWait:       defaultWriteWait,
+		clientQueueSize: defaultClientQueueSize,
+		messageHistory:  make([]history.Entry, 0, historySize),
+		done:            make(chan struct{}),
+		rates:           newMessageRates(time.Now),
 	}
 }
 
 func (c *SimplePageConsumer) Consume(message chatmodels.ChatMessage) {
-	c.messages <- message
-	c.addToHistory(message)
+	select {
+	case <-c.stopped():
+		return
+	default:
+	}
+	c.rates.add(message.Provider)
+
+	// The message is broadcast with its entry ID, so the streams can resume from the history
+	entry := c.addToHistory(message, c.saveMessage(message))
+	select {
+	case c.messages <- entry:
+	case <-c.stopped():
+	}
+}
+
+// ConsumeEvent sends the non-chat events to the pages and the streams.
+func (c *SimplePageConsumer) ConsumeEvent(event chatmodels.ChatEvent) {
+	select {
+	case c.messages <- event:
+	case <-c.stopped():
+	}
+}
+
+// ConsumeStatus sends the provider connection changes to the pages.
+func (c *SimplePageConsumer) ConsumeStatus(status chatmodels.ProviderStatus) {
+	select {
+	case c.messages <- status:
+	case <-c.stopped():
+	}
+}
+
+// ConsumeModeration removes the moderated messages from the history and from the connected pages.
+func (c *SimplePageConsumer) ConsumeModeration(event chatmodels.ModerationEvent) {
+	c.removeFromHistory(event)
+	c.saveModeration(event)
+	select {
+	case c.messages <- event:
+	case <-c.stopped():
+	}
+}
+
+// stopped returns the channel closed when the consumer is stopped.
+func (c *SimplePageConsumer) stopped() <-chan struct{} {
+	c.doneMux.Lock()
+	defer c.doneMux.Unlock()
+	return c.done
 }
 
 func (c *SimplePageConsumer) GetName() string {
 	return c.Name
 }
 
-// index is a templ.Component that renders the HTML page.
-type index struct {
-	messages []chatmodels.ChatMessage
-	config   *config.Config
-}
-
-// Render implements the templ.Component interface.
-func (i index) Render(ctx context.Context, w io.Writer) error {
-	// Creating a chat box with dark terminal like colors
-	/// flex space used to manage the chat messages
-	fmt.Fprint(w, `<!DOCTYPE html>
-	<html lang="en">
-		<head>
-			<meta charset="UTF-8"/>
-			<meta name="viewport" content="width=device-width, initial-scale=1.0"/>
-			<title>Chat Client</title>
-			<style>
-				body {
-					font-family: sans-serif;
-					overflow: hidden;
-					background-color: #18181b !important;
-					color: lightgray;
-				}
-				#chatbox {
-					height: 95vh;
-					overflow-y: auto;
-					display: flex;
-    				flex-direction: column;
-					align-content: flex-end;
-				}
-				#fill {
-					flex-grow: 1;
-				}
-				.message {
-					margin-bottom: 5px;
-					width: 100%;
-				}
-				.messagecontainer {
-					display: flex;
-					flex-direction: row;
-				}`)
-
-	providerMinWidth := "70px"
-	if i.config.WebpageOuputShortenProvider {
-		providerMinWidth = "25px"
-	}
-
-	providerHide := ""
-	if i.config.WebpageOutputHideProvider {
-		providerHide = "display: none;"
-	}
-
-	fmt.Fprintf(w, `
-				.provider {
-					min-width: %s !important;
-					overflow: hidden;
-					text-overflow: ellipsis;
-					white-space: nowrap;
-					text-align: right;
-					%s
-				}`,
-		providerMinWidth,
-		providerHide,
-	)
-
-	fmt.Fprint(w, `
-				.user {
-					min-width: 125px !important;
-					overflow: hidden;
-					text-overflow: ellipsis;
-					white-space: nowrap;
-					text-align: right;
-					padding-right: 10px;
-				}
-				.messagecontents {
-					flex-grow: 1;
-				}
-				
-			</style>
-		</head>
-		<body>
-			<div id="chatbox">
-				<div id="fill"></div>`)
-
-	for _, message := range i.messages {
-		providerName := message.Provider
-		if i.config.WebpageOuputShortenProvider {
-			providerName = message.ProviderShortName
+func (c *SimplePageConsumer) Start(ctx context.Context, cfg *config.Config) error {
+	c.settingsMux.Lock()
+	c.display = cfg.Consumers.SimplePage
+	c.port = cfg.Server.Port
+	c.moderationToken = cfg.Server.ModerationToken
+	c.history = cfg.History
+	c.settingsMux.Unlock()
+
+	// A consumer removed and added again, or restarted by a configuration change, is started
+	// again after Stop closed done
+	c.doneMux.Lock()
+	select {
+	case <-c.done:
+		c.done = make(chan struct{})
+	default:
+	}
+	c.doneMux.Unlock()
+
+	if cfg.History.Enabled {
+		if err := c.openHistory(ctx, cfg.History); err != nil {
+			return err
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", c.handlePage)
+	mux.Handle("/static/", staticHandler())
+	mux.HandleFunc("/themes/", c.handleThemeFile)
+	mux.HandleFunc("/ws", c.handleConnections)
+	mux.HandleFunc("/stream", c.handleStream)
+	c.registerAPI(mux)
+
+	// Bind the port before returning so that start up errors are reported to the aggregator
+	var listenConfig net.ListenConfig
+	listener, err := listenConfig.Listen(ctx, "tcp", fmt.Sprintf(":%d", cfg.Server.Port))
+	if err != nil {
+		c.closeHistory()
+		return err
+	}
+	c.server = &http.Server{Handler: mux}
+
+	go c.handleMessages()
+	if cfg.History.Enabled && cfg.History.Retention > 0 {
+		go c.pruneHistoryPeriodically(cfg.History.Retention)
+	}
+
+	log.Println("HTTP server started on :", cfg.Server.Port)
+	go func() {
+		err := c.server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Println("HTTP server error:", err)
 		}
-		fmt.Fprintf(w, `<div class="message"><div class="messagecontainer"><div class="provider">%s</div><div class="user">%s:</div><div class="messagecontents">%s</div></div></div>`, providerName, message.AuthorName, message.Content)
-	}
-
-	fmt.Fprintf(w, `</div>
-			<script>
-				const chatbox = document.getElementById('chatbox');
-				const ws = new WebSocket('ws://' + window.location.host + '/ws');
-				const useShortProvider = %v;
-
-				ws.onmessage = (event) => {
-					const message = JSON.parse(event.data);
-					const messageElement = document.createElement('div');
-					messageElement.classList.add('message');
-
-					const container = document.createElement('div');
-					container.classList.add('messagecontainer');
-					messageElement.appendChild(container);
-
-					const provider = document.createElement('div');
-					provider.classList.add('provider');
-					if (useShortProvider) {
-						provider.textContent = message.ProviderShortName;
-					} else {
-						provider.textContent = message.Provider;
-					}
-
-					const user = document.createElement('div');
-					user.classList.add('user');
-					user.textContent = message.AuthorName+":";
-
-					const messageContents = document.createElement('div');
-					messageContents.classList.add('messagecontents');
-					messageContents.textContent = message.Content;
-					
-					container.appendChild(provider);
-					container.appendChild(user);
-					container.appendChild(messageContents);
-					
-					chatbox.appendChild(messageElement);
-					chatbox.scrollTop = chatbox.scrollHeight;
-				};
-			</script>
-		</body>
-	</html>`,
-		i.config.WebpageOuputShortenProvider,
-	)
+	}()
 
 	return nil
 }
 
-func (c *SimplePageConsumer) Start(cfg *config.Config) error {
-	// Use the index component directly
-	http.Handle("/", templ.Handler(index{messages: c.getHistory(), config: cfg}))
-	http.HandleFunc("/ws", c.handleConnections)
+// UpdateConfig applies the new display options and moderation token, and pushes the display
+// options to the connected pages. A different port needs a new server and different history
+// options a new database, so it returns false.
+func (c *SimplePageConsumer) UpdateConfig(cfg *config.Config) bool {
+	c.settingsMux.Lock()
+	if cfg.Server.Port != c.port || cfg.History != c.history {
+		c.settingsMux.Unlock()
+		return false
+	}
+	changed := c.display != cfg.Consumers.SimplePage
+	c.display = cfg.Consumers.SimplePage
+	c.moderationToken = cfg.Server.ModerationToken
+	c.settingsMux.Unlock()
+
+	if changed {
+		options := displayOptions{
+			ShortenProvider: cfg.Consumers.SimplePage.ShortenProvider,
+			HideProvider:    cfg.Consumers.SimplePage.HideProvider,
+		}
+		select {
+		case c.messages <- options:
+		case <-c.stopped():
+		}
+	}
+	return true
+}
 
-	go c.handleMessages()
+func (c *SimplePageConsumer) getDisplayOptions() config.SimplePageConfig {
+	c.settingsMux.RLock()
+	defer c.settingsMux.RUnlock()
+	return c.display
+}
 
-	log.Println("HTTP server started on :", cfg.WebpageOutputPort)
-	err := http.ListenAndServe(fmt.Sprintf(":%d", cfg.WebpageOutputPort), nil)
-	if err != nil {
-		return err
+func (c *SimplePageConsumer) getModerationToken() string {
+	c.settingsMux.RLock()
+	defer c.settingsMux.RUnlock()
+	return c.moderationToken
+}
+
+// Stop shuts down the HTTP server and disconnects the WebSocket and stream clients.
+func (c *SimplePageConsumer) Stop(ctx context.Context) error {
+	c.doneMux.Lock()
+	select {
+	case <-c.done:
+		c.doneMux.Unlock()
+		return nil
+	default:
+		close(c.done)
 	}
+	c.doneMux.Unlock()
 
-	return nil
+	// Shutdown does not track hijacked connections, so the WebSocket clients are closed here
+	c.wsClientsMux.Lock()
+	for client := range c.wsClients {
+		client.close()
+		delete(c.wsClients, client)
+	}
+	c.wsClientsMux.Unlock()
+	// The streams end once done is closed, so Shutdown does not wait for them
+
+	if c.server == nil {
+		return nil
+	}
+	err := c.server.Shutdown(ctx)
+	c.closeHistory()
+	return err
 }
 
+// handleConnections serves the WebSocket of the pages. The page passes the query parameters of
+// its URL, so the messages it does not show are filtered here, and the cursor of the last message
+// it received, so it only receives the messages it missed when it reconnects.
 func (c *SimplePageConsumer) handleConnections(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	if version := values.Get("protocol"); version != "" && version != strconv.Itoa(protocolVersion) {
+		http.Error(w, fmt.Sprintf("unsupported protocol version %q, the server uses version %d", version, protocolVersion), http.StatusBadRequest)
+		return
+	}
+	filter, err := parseFeedFilter(values)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	cursor, err := parseCursor(values.Get("cursor"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	ws, err := c.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Println("Error upgrading to WebSocket:", err)
 		return
 	}
-	defer ws.Close()
-
-	c.wsClientsMux.Lock()
-	c.wsClients[ws] = true
-	c.wsClientsMux.Unlock()
+	client := c.newWSClient(ws, filter)
+	defer client.close()
 
-	// Send the history to the new client
-	c.sendHistoryToClient(ws)
+	if err := c.addClient(r.Context(), client, cursor, values.Has("cursor")); err != nil {
+		log.Println("Error reading history:", err)
+		return
+	}
+	defer c.removeClient(client)
+	c.expectPongs(ws)
+	go c.writeLoop(client)
 
+	// authToken is the moderation token the connection authenticated with
+	authToken := ""
 	for {
-		_, _, err := ws.ReadMessage()
+		var cmd command
+		err := ws.ReadJSON(&cmd)
 		if err != nil {
-			c.wsClientsMux.Lock()
-			delete(c.wsClients, ws)
-			c.wsClientsMux.Unlock()
+			var syntaxError *json.SyntaxError
+			if errors.As(err, &syntaxError) {
+				continue
+			}
 			break
 		}
+
+		var res result
+		res, authToken = c.handleCommand(r.Context(), cmd, authToken)
+		c.writeToClient(client, res)
+	}
+}
+
+// addClient queues the messages a page missed since its cursor, or the recent history when it has
+// none, and the provider statuses as its backlog, then adds it to the broadcasts. The broadcasts
+// wait meanwhile, so the page receives every message once and in order.
+func (c *SimplePageConsumer) addClient(ctx context.Context, client *wsClient, cursor int64, resume bool) error {
+	c.wsClientsMux.Lock()
+	defer c.wsClientsMux.Unlock()
+
+	// A cursor after the last message comes from before a restart without the history database
+	query := history.Query{Limit: historySize}
+	if resume && cursor <= c.historyCursor() {
+		query = history.Query{After: cursor, Limit: maxResumeMessages}
+		client.cursor = cursor
+	}
+	entries, err := c.queryHistory(ctx, query)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		client.cursor = entry.ID
+		if client.filter.matches(entry) {
+			client.backlog = append(client.backlog, newEnvelope(entry))
+		}
+	}
+	if c.providerStatuses != nil {
+		for _, status := range c.providerStatuses() {
+			client.backlog = append(client.backlog, newEnvelope(status))
+		}
+	}
+
+	c.wsClients[client] = struct{}{}
+	c.wsMetrics.connections.Add(1)
+	return nil
+}
+
+func (c *SimplePageConsumer) removeClient(client *wsClient) {
+	c.wsClientsMux.Lock()
+	defer c.wsClientsMux.Unlock()
+	delete(c.wsClients, client)
+}
+
+// writeToClient queues a reply for a single client, after the broadcasts already queued.
+func (c *SimplePageConsumer) writeToClient(client *wsClient, message any) {
+	c.wsClientsMux.Lock()
+	defer c.wsClientsMux.Unlock()
+	if _, ok := c.wsClients[client]; ok && !client.enqueue(newEnvelope(message)) {
+		c.disconnectSlowClient(client)
 	}
 }
 
 func (c *SimplePageConsumer) handleMessages() {
-	for msg := range c.messages {
-		c.broadcastMessage(msg)
+	done := c.stopped()
+	for {
+		select {
+		case msg := <-c.messages:
+			c.publish(msg)
+			c.broadcastMessage(msg)
+		case <-done:
+			return
+		}
 	}
 }
 
-func (c *SimplePageConsumer) broadcastMessage(message chatmodels.ChatMessage) {
+// broadcastMessage queues a history entry, event, moderation event, provider status or display
+// options for the pages whose filter matches it. It does not wait for the pages: those whose queue
+// is full are disconnected.
+func (c *SimplePageConsumer) broadcastMessage(message any) {
+	envelope := newEnvelope(message)
+
 	c.wsClientsMux.Lock()
 	defer c.wsClientsMux.Unlock()
 	for client := range c.wsClients {
-		err := client.WriteJSON(message)
-		if err != nil {
-			log.Printf("error: %v", err)
-			client.Close()
-			delete(c.wsClients, client)
+		if envelope.ID != 0 {
+			// The message was already sent with the history when the page connected
+			if envelope.ID <= client.cursor {
+				continue
+			}
+			client.cursor = envelope.ID
+		}
+		if !client.filter.matches(message) {
+			continue
+		}
+		if !client.enqueue(envelope) {
+			c.disconnectSlowClient(client)
 		}
 	}
 }
 
-func (c *SimplePageConsumer) addToHistory(message chatmodels.ChatMessage) {
+// disconnectSlowClient disconnects a page whose queue is full, it reconnects with its cursor once
+// it catches up. The caller holds wsClientsMux.
+func (c *SimplePageConsumer) disconnectSlowClient(client *wsClient) {
+	log.Printf("Page %s is too slow, disconnecting it", client.ws.RemoteAddr())
+	c.wsMetrics.slowDisconnects.Add(1)
+	client.close()
+	delete(c.wsClients, client)
+}
+
+// openHistory opens the history database, deletes the messages older than the retention and
+// restores the most recent messages.
+func (c *SimplePageConsumer) openHistory(ctx context.Context, cfg config.HistoryConfig) error {
+	store, err := history.Open(cfg.Path)
+	if err != nil {
+		return err
+	}
+	c.storeMux.Lock()
+	c.store = store
+	c.storeMux.Unlock()
+
+	if cfg.Retention > 0 {
+		c.pruneHistory(ctx, cfg.Retention)
+	}
+
+	entries, err := store.Query(ctx, history.Query{Limit: historySize})
+	if err != nil {
+		c.closeHistory()
+		return err
+	}
+	// The database has the messages kept in memory before a restart of the consumer
+	c.historyMutex.Lock()
+	c.messageHistory = append(c.messageHistory[:0], entries...)
+	if len(entries) > 0 {
+		c.lastID = entries[len(entries)-1].ID
+	}
+	c.historyMutex.Unlock()
+	return nil
+}
+
+// pruneHistoryPeriodically deletes the messages older than the retention until Stop.
+func (c *SimplePageConsumer) pruneHistoryPeriodically(retention time.Duration) {
+	done := c.stopped()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.pruneHistory(context.Background(), retention)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (c *SimplePageConsumer) pruneHistory(ctx context.Context, retention time.Duration) {
+	c.storeMux.RLock()
+	defer c.storeMux.RUnlock()
+	if c.store == nil {
+		return
+	}
+	if _, err := c.store.Prune(ctx, time.Now().Add(-retention)); err != nil {
+		log.Println("Error pruning history:", err)
+	}
+}
+
+// closeHistory closes the history database once the requests using it are done.
+func (c *SimplePageConsumer) closeHistory() {
+	c.storeMux.Lock()
+	defer c.storeMux.Unlock()
+	if c.store == nil {
+		return
+	}
+	if err := c.store.Close(); err != nil {
+		log.Println("Error closing history:", err)
+	}
+	c.store = nil
+}
+
+// saveMessage saves a message to the history database and returns its ID, or 0 when it was not
+// saved.
+func (c *SimplePageConsumer) saveMessage(message chatmodels.ChatMessage) int64 {
+	c.storeMux.RLock()
+	defer c.storeMux.RUnlock()
+	if c.store == nil {
+		return 0
+	}
+	id, err := c.store.Save(context.Background(), message)
+	if err != nil {
+		log.Println("Error saving history:", err)
+	}
+	return id
+}
+
+// saveModeration hides the moderated messages in the history database.
+func (c *SimplePageConsumer) saveModeration(event chatmodels.ModerationEvent) {
+	c.storeMux.RLock()
+	defer c.storeMux.RUnlock()
+	if c.store == nil {
+		return
+	}
+	if _, err := c.store.ApplyModeration(context.Background(), event); err != nil {
+		log.Println("Error saving history:", err)
+	}
+}
+
+// addToHistory keeps a message with its store ID, or the next ID when it was not stored, and
+// returns its entry.
+func (c *SimplePageConsumer) addToHistory(message chatmodels.ChatMessage, id int64) history.Entry {
 	c.historyMutex.Lock()
 	defer c.historyMutex.Unlock()
 
-	if len(c.messageHistory) >= 30 {
+	if id == 0 {
+		id = c.lastID + 1
+	}
+	c.lastID = id
+	if len(c.messageHistory) >= historySize {
 		c.messageHistory = c.messageHistory[1:]
 	}
-	c.messageHistory = append(c.messageHistory, message)
+	entry := history.Entry{ID: id, Message: message}
+	c.messageHistory = append(c.messageHistory, entry)
+	return entry
+}
+
+func (c *SimplePageConsumer) removeFromHistory(event chatmodels.ModerationEvent) {
+	c.historyMutex.Lock()
+	defer c.historyMutex.Unlock()
+
+	c.messageHistory = slices.DeleteFunc(c.messageHistory, func(entry history.Entry) bool { return event.Matches(entry.Message) })
+}
+
+// historyCursor returns the ID of the last message.
+func (c *SimplePageConsumer) historyCursor() int64 {
+	c.historyMutex.Lock()
+	defer c.historyMutex.Unlock()
+	return c.lastID
 }
 
 func (c *SimplePageConsumer) getHistory() []chatmodels.ChatMessage {
+	history, _ := c.getHistoryWithCursor()
+	return history
+}
+
+// getHistoryWithCursor returns the recent messages and the ID of the last message, the cursor a
+// page showing them reconnects with.
+func (c *SimplePageConsumer) getHistoryWithCursor() ([]chatmodels.ChatMessage, int64) {
 	c.historyMutex.Lock()
 	defer c.historyMutex.Unlock()
 
 	// Create a copy to avoid race conditions
 	historyCopy := make([]chatmodels.ChatMessage, len(c.messageHistory))
-	copy(historyCopy, c.messageHistory)
-	return historyCopy
+	for i, entry := range c.messageHistory {
+		historyCopy[i] = entry.Message
+	}
+	return historyCopy, c.lastID
 }
 
-func (c *SimplePageConsumer) sendHistoryToClient(ws *websocket.Conn) {
-	history := c.getHistory()
-	for _, msg := range history {
-		err := ws.WriteJSON(msg)
-		if err != nil {
-			log.Printf("error sending history: %v", err)
-			return
+// queryHistory returns the messages of the query from the store, or the entries of the recent
+// history matching its IDs and limit when the store is not enabled.
+func (c *SimplePageConsumer) queryHistory(ctx context.Context, query history.Query) ([]history.Entry, error) {
+	c.storeMux.RLock()
+	if c.store != nil {
+		defer c.storeMux.RUnlock()
+		return c.store.Query(ctx, query)
+	}
+	c.storeMux.RUnlock()
+
+	c.historyMutex.Lock()
+	defer c.historyMutex.Unlock()
+
+	var entries []history.Entry
+	for _, entry := range c.messageHistory {
+		if (query.Before == 0 || entry.ID < query.Before) && entry.ID > query.After {
+			entries = append(entries, entry)
+		}
+	}
+	if query.Limit > 0 && len(entries) > query.Limit {
+		if query.Oldest {
+			entries = entries[:query.Limit]
+		} else {
+			entries = entries[len(entries)-query.Limit:]
 		}
-		time.Sleep(10 * time.Millisecond)
 	}
+	return slices.Clone(entries), nil
 }
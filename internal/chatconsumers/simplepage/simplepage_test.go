@@ -0,0 +1,189 @@
+package simplepage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// drain discards what the consumer would broadcast to the WebSocket clients
+func drain(c *SimplePageConsumer) {
+	go func() {
+		for {
+			select {
+			case <-c.messages:
+			case <-c.done:
+				return
+			}
+		}
+	}()
+}
+
+func TestSimplePageConsumer_ConsumeModeration_RetractsHistory(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	drain(consumer)
+	defer close(consumer.done)
+
+	consumer.Consume(chatmodels.ChatMessage{ID: "1", Provider: "Twitch", AuthorID: "42", Content: "spam"})
+	consumer.Consume(chatmodels.ChatMessage{ID: "2", Provider: "Twitch", AuthorID: "7", Content: "hello"})
+	consumer.Consume(chatmodels.ChatMessage{ID: "3", Provider: "Twitch", AuthorID: "42", Content: "more spam"})
+	consumer.Consume(chatmodels.ChatMessage{ID: "1", Provider: "Youtube", AuthorID: "42", Content: "same id, other provider"})
+
+	consumer.ConsumeModeration(chatmodels.ModerationEvent{Provider: "Twitch", Action: chatmodels.ModerationDeleteMessage, MessageID: "1"})
+	assert.Len(t, consumer.getHistory(), 3)
+
+	consumer.ConsumeModeration(chatmodels.ModerationEvent{Provider: "Twitch", Action: chatmodels.ModerationTimeout, AuthorID: "42"})
+	history := consumer.getHistory()
+	assert.Len(t, history, 2)
+	assert.Equal(t, "hello", history[0].Content)
+	assert.Equal(t, "same id, other provider", history[1].Content)
+
+	consumer.ConsumeModeration(chatmodels.ModerationEvent{Provider: "Youtube", Action: chatmodels.ModerationClearChat})
+	assert.Len(t, consumer.getHistory(), 1)
+}
+
+func TestSimplePageConsumer_HandleCommand_Moderation(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	consumer.moderationToken = "secret"
+	var requests []chatmodels.ModerationRequest
+	consumer.SetModerator(func(ctx context.Context, request chatmodels.ModerationRequest) error {
+		requests = append(requests, request)
+		return nil
+	})
+
+	timeout := command{Command: "moderate", ID: "1", Provider: "Twitch", Action: chatmodels.ModerationTimeout, AuthorID: "42", Seconds: 600}
+
+	res, authToken := consumer.handleCommand(context.Background(), timeout, "")
+	assert.Equal(t, result{ID: "1", Error: "not authenticated"}, res)
+	assert.Empty(t, authToken)
+
+	res, authToken = consumer.handleCommand(context.Background(), command{Command: "auth", ID: "2", Token: "wrong"}, "")
+	assert.Equal(t, "invalid moderation token", res.Error)
+	assert.Empty(t, authToken)
+
+	res, authToken = consumer.handleCommand(context.Background(), command{Command: "auth", ID: "3", Token: "secret"}, "")
+	assert.Empty(t, res.Error)
+	assert.Equal(t, "secret", authToken)
+
+	res, authToken = consumer.handleCommand(context.Background(), timeout, authToken)
+	assert.Equal(t, result{ID: "1"}, res)
+	assert.Equal(t, "secret", authToken)
+	assert.Equal(t, []chatmodels.ModerationRequest{{Provider: "Twitch", Action: chatmodels.ModerationTimeout, AuthorID: "42", Duration: 10 * time.Minute}}, requests)
+}
+
+func TestSimplePageConsumer_HandleCommand_ModerationDisabled(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	consumer.SetModerator(func(ctx context.Context, request chatmodels.ModerationRequest) error {
+		t.Fatal("moderation must be disabled without a token")
+		return nil
+	})
+
+	res, authToken := consumer.handleCommand(context.Background(), command{Command: "auth", ID: "1", Token: ""}, "")
+	assert.Equal(t, "moderation is disabled", res.Error)
+	assert.Empty(t, authToken)
+
+	res, _ = consumer.handleCommand(context.Background(), command{Command: "moderate", ID: "2", Action: chatmodels.ModerationBan}, "secret")
+	assert.Equal(t, "moderation is disabled", res.Error)
+}
+
+func TestSimplePageConsumer_UpdateConfig(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	defer close(consumer.done)
+	consumer.port = 8080
+	consumer.moderationToken = "secret"
+	consumer.SetModerator(func(ctx context.Context, request chatmodels.ModerationRequest) error { return nil })
+
+	cfg := &config.Config{Server: config.ServerConfig{Port: 8080, ModerationToken: "rotated"}}
+	cfg.Consumers.SimplePage = config.SimplePageConfig{Enabled: true, ShortenProvider: true}
+
+	pushed := make(chan any, 1)
+	go func() { pushed <- <-consumer.messages }()
+	assert.True(t, consumer.UpdateConfig(cfg))
+	assert.Equal(t, displayOptions{ShortenProvider: true}, <-pushed)
+	assert.Equal(t, cfg.Consumers.SimplePage, consumer.getDisplayOptions())
+
+	// Connections authenticated with the previous token lose their access
+	res, authToken := consumer.handleCommand(context.Background(), command{Command: "moderate", ID: "1", Action: chatmodels.ModerationBan, AuthorID: "42"}, "secret")
+	assert.Equal(t, "not authenticated", res.Error)
+	assert.Empty(t, authToken)
+
+	// The same options are not pushed again, a new port or history database needs a new consumer
+	assert.True(t, consumer.UpdateConfig(cfg))
+	cfg.Server.Port = 9090
+	assert.False(t, consumer.UpdateConfig(cfg))
+	cfg.Server.Port = 8080
+	cfg.History = config.HistoryConfig{Enabled: true, Path: "history.db"}
+	assert.False(t, consumer.UpdateConfig(cfg))
+}
+
+func TestSimplePageConsumer_Restart(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	cfg := &config.Config{History: config.HistoryConfig{Enabled: true, Path: filepath.Join(t.TempDir(), "history.db")}}
+	contents := func() []string {
+		var contents []string
+		for _, message := range consumer.getHistory() {
+			contents = append(contents, message.Content)
+		}
+		return contents
+	}
+
+	assert.NoError(t, consumer.Start(context.Background(), cfg))
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "first"})
+	assert.Eventually(t, func() bool { return len(contents()) == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, consumer.Stop(context.Background()))
+
+	// The restarted consumer handles the messages again, the history is restored once
+	assert.NoError(t, consumer.Start(context.Background(), cfg))
+	defer consumer.Stop(context.Background())
+	assert.Equal(t, []string{"first"}, contents())
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "second"})
+	assert.Eventually(t, func() bool { return len(contents()) == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"first", "second"}, contents())
+}
+
+func TestSimplePageConsumer_WebSocket_Filter(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	go consumer.handleMessages()
+	server := httptest.NewServer(http.HandlerFunc(consumer.handleConnections))
+	defer server.Close()
+	defer close(consumer.done)
+
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?provider=twitch&hide_commands=true&font_size=20"
+	ws, _, err := websocket.DefaultDialer.Dial(url, nil)
+	assert.NoError(t, err)
+	defer ws.Close()
+	assert.Eventually(t, func() bool {
+		consumer.wsClientsMux.Lock()
+		defer consumer.wsClientsMux.Unlock()
+		return len(consumer.wsClients) == 1
+	}, time.Second, time.Millisecond)
+
+	read := func() map[string]any {
+		var message map[string]any
+		ws.SetReadDeadline(time.Now().Add(time.Second))
+		assert.NoError(t, ws.ReadJSON(&message))
+		return message
+	}
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Youtube", Content: "other provider"})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "!command"})
+	consumer.ConsumeModeration(chatmodels.ModerationEvent{Provider: "Youtube", Action: chatmodels.ModerationClearChat})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "shown"})
+	consumer.UpdateConfig(&config.Config{Consumers: config.ConsumersConfig{SimplePage: config.SimplePageConfig{HideProvider: true}}})
+
+	assert.Equal(t, "shown", read()["Data"].(map[string]any)["Content"])
+	// The configuration is sent to every page
+	assert.Equal(t, envelopeConfig, read()["Type"])
+
+	_, response, err := websocket.DefaultDialer.Dial(url+"&min_role=viewer", nil)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,273 @@
+const chatbox = document.getElementById('chatbox');
+// The display options are body classes rendered by the server, so they can be changed live
+let useShortProvider = document.body.classList.contains('shortprovider');
+
+// The settings of the page are rendered by the server from the query parameters of its URL
+const settings = document.body.dataset;
+const fixedProviderLabel = Boolean(settings.providerLabel);
+const maxMessages = Number(settings.maxMessages) || 0;
+const fadeOut = Number(settings.fadeOut) || 0;
+if (Number(settings.fontSize)) {
+	document.body.style.fontSize = settings.fontSize + 'px';
+}
+
+// Removes the oldest messages over the limit of the page
+const limitMessages = () => {
+	if (!maxMessages) {
+		return;
+	}
+	const messages = chatbox.querySelectorAll('.message');
+	for (let i = 0; i < messages.length - maxMessages; i++) {
+		messages[i].remove();
+	}
+};
+// Fades out a message once it was shown for the fade out delay of the page, then removes it
+const scheduleFadeOut = (element) => {
+	if (!fadeOut) {
+		return;
+	}
+	setTimeout(() => {
+		element.classList.add('fadeout');
+		setTimeout(() => element.remove(), 1000);
+	}, fadeOut);
+};
+
+// Opening the page with ?token= enables the moderation buttons once the server accepts the token
+const moderationToken = new URLSearchParams(window.location.search).get('token');
+let ws = null;
+let lastCommandId = 0;
+const sendCommand = (command) => {
+	command.ID = String(++lastCommandId);
+	if (ws && ws.readyState === WebSocket.OPEN) {
+		ws.send(JSON.stringify(command));
+	}
+	return command.ID;
+};
+let authCommandId = null;
+// The server pushes the display options when its configuration changes
+const applyDisplayOptions = (options) => {
+	// The provider label set in the URL of the page takes precedence
+	if (fixedProviderLabel) {
+		return;
+	}
+	useShortProvider = options.ShortenProvider;
+	document.body.classList.toggle('shortprovider', options.ShortenProvider);
+	document.body.classList.toggle('hideprovider', options.HideProvider);
+	for (const provider of chatbox.querySelectorAll('.provider')) {
+		provider.textContent = useShortProvider ? provider.dataset.shortName : provider.dataset.name;
+	}
+};
+
+const handleResult = (result) => {
+	if (result.ID === authCommandId) {
+		document.body.classList.toggle('moderating', !result.Error);
+	}
+	if (result.Error) {
+		console.warn('Command failed:', result.Error);
+	}
+};
+
+const moderationButton = (label, title, onClick) => {
+	const button = document.createElement('button');
+	button.textContent = label;
+	button.title = title;
+	button.onclick = onClick;
+	return button;
+};
+const moderationTools = (message) => {
+	const moderate = (action, seconds) => sendCommand({
+		Command: 'moderate',
+		Provider: message.Provider,
+		Action: action,
+		MessageID: message.ID || '',
+		AuthorID: message.AuthorID || '',
+		Seconds: seconds || 0,
+	});
+	const tools = document.createElement('div');
+	tools.classList.add('modtools');
+	tools.appendChild(moderationButton('🗑', 'Delete message', () => moderate('delete_message')));
+	tools.appendChild(moderationButton('⏱', 'Timeout for 10 minutes', () => moderate('timeout', 600)));
+	tools.appendChild(moderationButton('⛔', 'Ban', () => moderate('ban')));
+	return tools;
+};
+
+// The messages rendered by the server get the author color, the moderation tools and the fade out
+// of the new messages
+for (const element of chatbox.querySelectorAll('.message')) {
+	const data = element.dataset;
+	const user = element.querySelector('.user');
+	if (user && user.dataset.color) {
+		user.style.color = user.dataset.color;
+	}
+	const message = {Provider: data.provider, ID: data.id, AuthorID: data.authorId};
+	element.querySelector('.messagecontainer').appendChild(moderationTools(message));
+	scheduleFadeOut(element);
+}
+
+// Emotes are shown as images, everything else falls back to the fragment text
+const renderFragments = (element, message) => {
+	if (!message.Fragments || message.Fragments.length === 0) {
+		element.textContent = message.Content;
+		return;
+	}
+	for (const fragment of message.Fragments) {
+		const images = fragment.Images || [];
+		if ((fragment.Type === 'emote' || fragment.Type === 'cheermote') && images.length > 0) {
+			const image = document.createElement('img');
+			image.classList.add('emote');
+			image.src = images[0].URL;
+			image.srcset = images.map((img) => img.URL + ' ' + parseFloat(img.Scale) + 'x').join(', ');
+			image.alt = fragment.Text;
+			image.title = fragment.Text;
+			element.appendChild(image);
+			if (fragment.Type === 'cheermote') {
+				const bits = document.createElement('span');
+				bits.classList.add('bits');
+				bits.textContent = fragment.Bits;
+				element.appendChild(bits);
+			}
+		} else if (fragment.Type === 'mention') {
+			const mention = document.createElement('span');
+			mention.classList.add('mention');
+			mention.textContent = fragment.Text;
+			element.appendChild(mention);
+		} else {
+			element.appendChild(document.createTextNode(fragment.Text));
+		}
+	}
+};
+
+// Removes the messages matched by a moderation event, mirrors ModerationEvent.Matches
+const retract = (moderation) => {
+	for (const element of chatbox.querySelectorAll('.message')) {
+		const data = element.dataset;
+		if (data.provider !== moderation.Provider) {
+			continue;
+		}
+		if (moderation.Channel && data.channel && data.channel !== moderation.Channel) {
+			continue;
+		}
+		let matches = false;
+		switch (moderation.Action) {
+		case 'delete_message':
+			matches = moderation.MessageID !== '' && data.id === moderation.MessageID;
+			break;
+		case 'timeout':
+		case 'ban':
+			matches = moderation.AuthorID ? data.authorId === moderation.AuthorID : data.authorName === moderation.AuthorName;
+			break;
+		case 'clear_chat':
+			matches = true;
+			break;
+		}
+		if (matches) {
+			element.remove();
+		}
+	}
+};
+
+const showMessage = (message) => {
+	const messageElement = document.createElement('div');
+	messageElement.classList.add('message');
+	messageElement.dataset.id = message.ID || '';
+	messageElement.dataset.provider = message.Provider;
+	messageElement.dataset.channel = message.Channel || '';
+	messageElement.dataset.authorId = message.AuthorID || '';
+	messageElement.dataset.authorName = message.AuthorName;
+
+	const container = document.createElement('div');
+	container.classList.add('messagecontainer');
+	messageElement.appendChild(container);
+
+	const provider = document.createElement('div');
+	provider.classList.add('provider');
+	provider.dataset.name = message.Provider;
+	provider.dataset.shortName = message.ProviderShortName;
+	if (useShortProvider) {
+		provider.textContent = message.ProviderShortName;
+	} else {
+		provider.textContent = message.Provider;
+	}
+
+	const user = document.createElement('div');
+	user.classList.add('user');
+	user.textContent = message.AuthorName+":";
+	if (message.AuthorColor) {
+		user.style.color = message.AuthorColor;
+	}
+
+	const messageContents = document.createElement('div');
+	messageContents.classList.add('messagecontents');
+	renderFragments(messageContents, message);
+
+	container.appendChild(provider);
+	container.appendChild(user);
+	container.appendChild(messageContents);
+	container.appendChild(moderationTools(message));
+
+	chatbox.appendChild(messageElement);
+	limitMessages();
+	scheduleFadeOut(messageElement);
+	// The themes lay the messages out vertically or horizontally
+	chatbox.scrollTop = chatbox.scrollHeight;
+	chatbox.scrollLeft = chatbox.scrollWidth;
+};
+
+// The WebSocket protocol sends envelopes: {Version, Type, ID, Data}, the ID of the chat messages
+// is the cursor sent back when reconnecting so the server only sends the messages that were missed
+const protocolVersion = 1;
+let cursor = settings.cursor || '';
+const handleEnvelope = (envelope) => {
+	if (envelope.Version !== protocolVersion) {
+		console.warn('Unsupported protocol version:', envelope.Version);
+		return;
+	}
+	switch (envelope.Type) {
+	case 'message':
+		cursor = String(envelope.ID);
+		showMessage(envelope.Data);
+		break;
+	case 'deletion':
+		retract(envelope.Data);
+		break;
+	case 'config':
+		applyDisplayOptions(envelope.Data);
+		break;
+	case 'result':
+		handleResult(envelope.Data);
+		break;
+	}
+	// The themes can react to every envelope, including the events and provider statuses
+	document.dispatchEvent(new CustomEvent('chatclient:' + envelope.Type, {detail: envelope}));
+};
+
+// The connection is retried with an exponential backoff, the server closes the connections that
+// stop answering its pings
+const minReconnectDelay = 1000;
+const maxReconnectDelay = 30000;
+let reconnectDelay = minReconnectDelay;
+const connect = () => {
+	// The query parameters of the page select the messages the server sends. The moderation token
+	// is sent in the auth command once connected, so it does not end up in the logs of the proxies
+	const params = new URLSearchParams(window.location.search);
+	params.delete('token');
+	params.set('protocol', protocolVersion);
+	if (cursor) {
+		params.set('cursor', cursor);
+	}
+	const scheme = window.location.protocol === 'https:' ? 'wss://' : 'ws://';
+	ws = new WebSocket(scheme + window.location.host + '/ws?' + params);
+	ws.onopen = () => {
+		reconnectDelay = minReconnectDelay;
+		if (moderationToken) {
+			authCommandId = sendCommand({Command: 'auth', Token: moderationToken});
+		}
+	};
+	ws.onmessage = (event) => handleEnvelope(JSON.parse(event.data));
+	ws.onclose = () => {
+		document.body.classList.remove('moderating');
+		setTimeout(connect, reconnectDelay);
+		reconnectDelay = Math.min(reconnectDelay * 2, maxReconnectDelay);
+	};
+};
+connect();
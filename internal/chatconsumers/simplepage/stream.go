@@ -0,0 +1,205 @@
+package simplepage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/history"
+)
+
+const (
+	// streamBufferSize is the number of events queued for a stream client, a client that falls
+	// further behind is disconnected and can resume with Last-Event-ID
+	streamBufferSize = 256
+	// streamKeepAlive is the time between the comments that keep idle streams open through proxies
+	streamKeepAlive = 15 * time.Second
+	// maxResumeMessages is the number of missed messages replayed at most when a stream resumes
+	maxResumeMessages = 1000
+)
+
+// streamEvent is an event of the Server-Sent Events stream.
+type streamEvent struct {
+	// ID is the entry ID of the chat messages, the events and moderation actions have none
+	ID   int64
+	Type string
+	Data any
+}
+
+// streamClient is a connected stream, events is closed when the client falls behind.
+type streamClient struct {
+	filter feedFilter
+	events chan streamEvent
+}
+
+// newStreamEvent converts what is broadcast to a stream event, ok is false for what is not streamed.
+func newStreamEvent(item any) (event streamEvent, ok bool) {
+	switch item := item.(type) {
+	case history.Entry:
+		return streamEvent{ID: item.ID, Type: typeMessage, Data: item.Message}, true
+	case chatmodels.ChatEvent:
+		return streamEvent{Type: typeEvent, Data: item}, true
+	case chatmodels.ModerationEvent:
+		return streamEvent{Type: typeModeration, Data: item}, true
+	default:
+		return streamEvent{}, false
+	}
+}
+
+// handleStream streams the chat messages, events and moderation actions as Server-Sent Events,
+// selected with the feed filter parameters. The chat messages have the ID of their history entry:
+// a client reconnecting with the Last-Event-ID header (or the last_event_id parameter) first
+// receives the messages it missed that are still in the history.
+func (c *SimplePageConsumer) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	filter, err := parseFeedFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lastID, resume, err := parseLastEventID(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Subscribing before reading the history, the messages received meanwhile are skipped by ID
+	client := c.subscribe(filter)
+	defer c.unsubscribe(client)
+
+	var missed []history.Entry
+	if resume {
+		if missed, err = c.queryHistory(r.Context(), history.Query{After: lastID, Limit: maxResumeMessages}); err != nil {
+			log.Println("Error reading history:", err)
+			http.Error(w, "error reading history", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Access-Control-Allow-Origin", "*")
+	// Disables the response buffering of nginx
+	header.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	for _, entry := range missed {
+		lastID = entry.ID
+		if !filter.matches(entry) {
+			continue
+		}
+		event, _ := newStreamEvent(entry)
+		if err := writeStreamEvent(w, event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	done := c.stopped()
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case event, ok := <-client.events:
+			if !ok {
+				return
+			}
+			if event.ID != 0 && event.ID <= lastID {
+				continue
+			}
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		case <-done:
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// parseLastEventID returns the ID of the last message received by a reconnecting client.
+func parseLastEventID(r *http.Request) (id int64, ok bool, err error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, false, nil
+	}
+	id, err = strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, false, fmt.Errorf("invalid last event ID %q", value)
+	}
+	return id, true, nil
+}
+
+func writeStreamEvent(w http.ResponseWriter, event streamEvent) error {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		return err
+	}
+	if event.ID != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", event.ID); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+func (c *SimplePageConsumer) subscribe(filter feedFilter) *streamClient {
+	client := &streamClient{filter: filter, events: make(chan streamEvent, streamBufferSize)}
+	c.streamClientsMux.Lock()
+	c.streamClients[client] = struct{}{}
+	c.streamClientsMux.Unlock()
+	return client
+}
+
+func (c *SimplePageConsumer) unsubscribe(client *streamClient) {
+	c.streamClientsMux.Lock()
+	delete(c.streamClients, client)
+	c.streamClientsMux.Unlock()
+}
+
+// publish queues what is broadcast for the stream clients whose filter matches it. The clients
+// that fell behind are disconnected rather than slowing down the broadcast.
+func (c *SimplePageConsumer) publish(item any) {
+	event, ok := newStreamEvent(item)
+	if !ok {
+		return
+	}
+
+	c.streamClientsMux.Lock()
+	defer c.streamClientsMux.Unlock()
+	for client := range c.streamClients {
+		if !client.filter.matches(item) {
+			continue
+		}
+		select {
+		case client.events <- event:
+		default:
+			log.Println("Stream client is too slow, disconnecting it")
+			delete(c.streamClients, client)
+			close(client.events)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package simplepage
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+// receivedEvent is an event read from the stream, with its data still encoded
+type receivedEvent struct {
+	ID   string
+	Type string
+	Data string
+}
+
+// startStream serves the stream of a consumer handling its messages.
+func startStream(t *testing.T, c *SimplePageConsumer) *httptest.Server {
+	go c.handleMessages()
+	server := httptest.NewServer(http.HandlerFunc(c.handleStream))
+	t.Cleanup(func() {
+		close(c.done)
+		server.Close()
+	})
+	return server
+}
+
+// readEvents reads count events from the stream, skipping the comments.
+func readEvents(t *testing.T, reader *bufio.Reader, count int) []receivedEvent {
+	var events []receivedEvent
+	var event receivedEvent
+	for len(events) < count {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return events
+		}
+		line = strings.TrimSuffix(line, "\n")
+		field, value, _ := strings.Cut(line, ": ")
+		switch field {
+		case "id":
+			event.ID = value
+		case "event":
+			event.Type = value
+		case "data":
+			event.Data = value
+		case "":
+			if event.Type != "" {
+				events = append(events, event)
+			}
+			event = receivedEvent{}
+		}
+	}
+	return events
+}
+
+func TestSimplePageConsumer_Stream_Resume(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	server := startStream(t, consumer)
+	for _, content := range []string{"one", "two", "three"} {
+		consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: content})
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	request.Header.Set("Last-Event-ID", "1")
+	response, err := http.DefaultClient.Do(request)
+	assert.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "four"})
+
+	events := readEvents(t, bufio.NewReader(response.Body), 3)
+	assert.Equal(t, []string{"2", "3", "4"}, []string{events[0].ID, events[1].ID, events[2].ID})
+	assert.Contains(t, events[0].Data, `"Content":"two"`)
+	assert.Contains(t, events[2].Data, `"Content":"four"`)
+	assert.Equal(t, typeMessage, events[2].Type)
+}
+
+func TestSimplePageConsumer_Stream_Filter(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	server := startStream(t, consumer)
+
+	response, err := http.Get(server.URL + "?provider=youtube&type=message,raid,moderation&min_role=subscriber")
+	assert.NoError(t, err)
+	defer response.Body.Close()
+
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Twitch", Content: "other provider", Roles: []chatmodels.Role{chatmodels.RoleSubscriber}})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Youtube", Content: "viewer"})
+	consumer.ConsumeEvent(chatmodels.ChatEvent{Provider: "Youtube", Kind: chatmodels.EventSuperChat})
+	consumer.ConsumeEvent(chatmodels.ChatEvent{Provider: "Youtube", Kind: chatmodels.EventRaid})
+	consumer.Consume(chatmodels.ChatMessage{Provider: "Youtube", Content: "moderator", Roles: []chatmodels.Role{chatmodels.RoleModerator}})
+	consumer.ConsumeModeration(chatmodels.ModerationEvent{Provider: "Youtube", Action: chatmodels.ModerationClearChat})
+
+	events := readEvents(t, bufio.NewReader(response.Body), 3)
+	assert.Equal(t, typeEvent, events[0].Type)
+	assert.Contains(t, events[0].Data, `"Kind":"raid"`)
+	assert.Equal(t, receivedEvent{ID: "3", Type: typeMessage}, receivedEvent{ID: events[1].ID, Type: events[1].Type})
+	assert.Contains(t, events[1].Data, `"Content":"moderator"`)
+	assert.Equal(t, typeModeration, events[2].Type)
+}
+
+func TestSimplePageConsumer_Stream_SlowClient(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	client := consumer.subscribe(feedFilter{})
+
+	for range streamBufferSize + 1 {
+		consumer.publish(chatmodels.ModerationEvent{Provider: "Twitch"})
+	}
+
+	// The client is disconnected once its buffer is full
+	assert.NotContains(t, consumer.streamClients, client)
+	for range streamBufferSize {
+		<-client.events
+	}
+	_, ok := <-client.events
+	assert.False(t, ok)
+}
+
+func TestSimplePageConsumer_Stream_BadRequest(t *testing.T) {
+	consumer := NewSimplePageConsumer()
+	server := startStream(t, consumer)
+
+	for _, query := range []string{"type=whisper", "min_role=viewer", "last_event_id=x"} {
+		response, err := http.Get(server.URL + "?" + query)
+		assert.NoError(t, err)
+		response.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, query)
+	}
+}
+
+func TestFeedFilter(t *testing.T) {
+	filter, err := parseFeedFilter(url.Values{"provider": {"Twitch, youtube"}, "type": {"message", "bits"}, "min_role": {"VIP"}})
+	assert.NoError(t, err)
+	assert.Equal(t, feedFilter{providers: []string{"twitch", "youtube"}, types: []string{"message", "bits"}, minRole: chatmodels.RoleVIP}, filter)
+
+	vip := chatmodels.ChatMessage{Provider: "Twitch", Roles: []chatmodels.Role{chatmodels.RoleVIP}}
+	assert.True(t, filter.matches(vip))
+	assert.False(t, filter.matches(chatmodels.ChatMessage{Provider: "Twitch", Roles: []chatmodels.Role{chatmodels.RoleSubscriber}}))
+	assert.False(t, filter.matches(chatmodels.ChatMessage{Provider: "Kick", Roles: []chatmodels.Role{chatmodels.RoleVIP}}))
+	assert.True(t, filter.matches(chatmodels.ChatEvent{Provider: "Youtube", Kind: chatmodels.EventBits}))
+	assert.False(t, filter.matches(chatmodels.ChatEvent{Provider: "Youtube", Kind: chatmodels.EventRaid}))
+	assert.False(t, filter.matches(chatmodels.ModerationEvent{Provider: "Twitch"}))
+
+	// Without parameters everything is sent
+	filter, err = parseFeedFilter(url.Values{})
+	assert.NoError(t, err)
+	assert.True(t, filter.matches(chatmodels.ChatMessage{Provider: "Kick"}))
+	assert.True(t, filter.matches(chatmodels.ChatEvent{Provider: "Kick", Kind: chatmodels.EventRaid}))
+	assert.True(t, filter.matches(chatmodels.ModerationEvent{Provider: "Kick"}))
+}
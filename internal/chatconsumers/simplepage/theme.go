@@ -0,0 +1,102 @@
+package simplepage
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/themes"
+)
+
+// defaultTheme is used when neither the URL nor the configuration select a theme
+const defaultTheme = "classic"
+
+const (
+	// themeStylesheet, themeScript and themeTemplate are the files of a theme, they are all optional
+	themeStylesheet = "theme.css"
+	themeScript     = "theme.js"
+	themeTemplate   = "page.html"
+)
+
+// pageTheme is the theme a page is rendered with.
+type pageTheme struct {
+	Name string
+	// Stylesheet and Script report whether the theme has a style sheet and a script
+	Stylesheet bool
+	Script     bool
+}
+
+// themePage is the data of the page.html template of a user theme.
+type themePage struct {
+	Theme    string
+	Messages []chatmodels.ChatMessage
+	// Cursor is the ID of the last message, page.js reads it from the data-cursor attribute of
+	// the body to only receive the next messages
+	Cursor   int64
+	Options  config.SimplePageConfig
+	Settings pageSettings
+	// ProviderLabel is how the provider is shown: full, short or hidden
+	ProviderLabel string
+}
+
+// findTheme returns the files of a theme. A theme of the themes directory hides the built-in
+// theme with the same name.
+func (c *SimplePageConsumer) findTheme(name string) (fs.FS, bool) {
+	if !themes.NamePattern.MatchString(name) {
+		return nil, false
+	}
+	if dir := c.getDisplayOptions().ThemesDir; dir != "" {
+		files := os.DirFS(filepath.Join(dir, name))
+		if info, err := fs.Stat(files, "."); err == nil && info.IsDir() {
+			return files, true
+		}
+	}
+	return themes.Files(name)
+}
+
+// themeURL returns the URL of a file of a theme.
+func themeURL(theme, file string) string {
+	return "/themes/" + url.PathEscape(theme) + "/" + file
+}
+
+func hasFile(files fs.FS, name string) bool {
+	_, err := fs.Stat(files, name)
+	return err == nil
+}
+
+// renderThemeTemplate renders the page.html template of a theme. The template is read on every
+// request so themes can be edited without a restart, and html/template escapes the messages.
+func renderThemeTemplate(w http.ResponseWriter, files fs.FS, data themePage) {
+	tmpl, err := template.ParseFS(files, themeTemplate)
+	var page bytes.Buffer
+	if err == nil {
+		err = tmpl.Execute(&page, data)
+	}
+	if err != nil {
+		log.Printf("Error rendering theme %s: %v", data.Theme, err)
+		http.Error(w, fmt.Sprintf("error rendering theme %q", data.Theme), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
+
+// handleThemeFile serves the files of the themes under /themes/<theme>/.
+func (c *SimplePageConsumer) handleThemeFile(w http.ResponseWriter, r *http.Request) {
+	name, file, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/themes/"), "/")
+	files, ok := c.findTheme(name)
+	if !ok || !fs.ValidPath(file) || file == "." {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFileFS(w, r, files, file)
+}
@@ -0,0 +1,100 @@
+package chatmodels
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EventKind identifies a non-chat event, kinds are shared between providers.
+type EventKind string
+
+const (
+	// EventSubscription is a new Twitch subscription or YouTube membership
+	EventSubscription EventKind = "subscription"
+	// EventResubscription is a Twitch resubscription or YouTube membership milestone
+	EventResubscription EventKind = "resubscription"
+	// EventGiftSubscription is a subscription or membership gifted to a single viewer
+	EventGiftSubscription EventKind = "gift_subscription"
+	// EventCommunityGift is a batch of subscriptions or memberships gifted to the community
+	EventCommunityGift EventKind = "community_gift"
+	EventRaid          EventKind = "raid"
+	EventBits          EventKind = "bits"
+	EventSuperChat     EventKind = "super_chat"
+	EventSuperSticker  EventKind = "super_sticker"
+)
+
+// ChatEvent is a non-chat event such as a subscription, a raid or a paid message.
+type ChatEvent struct {
+	// ID is the provider-native identifier of the event
+	ID                string
+	Provider          string
+	ProviderShortName string
+	Channel           string
+	Timestamp         time.Time
+	Kind              EventKind
+	// AuthorID and AuthorName identify the viewer that triggered the event
+	AuthorID   string
+	AuthorName string
+	// Message is the comment the viewer attached to the event, if any
+	Message string
+	// Amount is the paid amount in Currency, bits use the "bits" currency
+	Amount   float64
+	Currency string
+	// Tier is the subscription tier or membership level name
+	Tier   string
+	Months int
+	// Gifter is the name of the viewer that paid for gifted subscriptions
+	Gifter         string
+	Recipient      string
+	RecipientCount int
+	// Viewers is the number of viewers brought by a raid
+	Viewers int
+	// Raw keeps the provider metadata that has no dedicated field
+	Raw map[string]string
+}
+
+// Describe returns a short human readable description of the event.
+func (e ChatEvent) Describe() string {
+	var description string
+	switch e.Kind {
+	case EventSubscription:
+		description = fmt.Sprintf("%s subscribed%s", e.AuthorName, e.tierSuffix())
+	case EventResubscription:
+		description = fmt.Sprintf("%s subscribed for %d months%s", e.AuthorName, e.Months, e.tierSuffix())
+	case EventGiftSubscription:
+		if e.Gifter == "" {
+			description = fmt.Sprintf("%s received a gifted subscription%s", e.Recipient, e.tierSuffix())
+		} else {
+			description = fmt.Sprintf("%s gifted a subscription to %s%s", e.Gifter, e.Recipient, e.tierSuffix())
+		}
+	case EventCommunityGift:
+		description = fmt.Sprintf("%s gifted %d subscriptions%s", e.Gifter, e.RecipientCount, e.tierSuffix())
+	case EventRaid:
+		description = fmt.Sprintf("%s is raiding with %d viewers", e.AuthorName, e.Viewers)
+	case EventBits:
+		description = fmt.Sprintf("%s cheered %.0f bits", e.AuthorName, e.Amount)
+	case EventSuperChat:
+		description = fmt.Sprintf("%s sent a Super Chat of %s", e.AuthorName, e.formatAmount())
+	case EventSuperSticker:
+		description = fmt.Sprintf("%s sent a Super Sticker of %s", e.AuthorName, e.formatAmount())
+	default:
+		description = fmt.Sprintf("%s: %s", e.AuthorName, e.Kind)
+	}
+
+	if e.Message != "" {
+		description += ": " + e.Message
+	}
+	return description
+}
+
+func (e ChatEvent) tierSuffix() string {
+	if e.Tier == "" {
+		return ""
+	}
+	return " (" + e.Tier + ")"
+}
+
+func (e ChatEvent) formatAmount() string {
+	return strings.TrimSpace(fmt.Sprintf("%.2f %s", e.Amount, e.Currency))
+}
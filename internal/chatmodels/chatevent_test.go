@@ -0,0 +1,17 @@
+package chatmodels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatEvent_Describe(t *testing.T) {
+	assert.Equal(t, "Viewer subscribed for 12 months (Tier 1): still here", ChatEvent{Kind: EventResubscription, AuthorName: "Viewer", Months: 12, Tier: "Tier 1", Message: "still here"}.Describe())
+	assert.Equal(t, "Gifter gifted a subscription to Lucky (Gold)", ChatEvent{Kind: EventGiftSubscription, Gifter: "Gifter", Recipient: "Lucky", Tier: "Gold"}.Describe())
+	assert.Equal(t, "Lucky received a gifted subscription", ChatEvent{Kind: EventGiftSubscription, Recipient: "Lucky"}.Describe())
+	assert.Equal(t, "Gifter gifted 5 subscriptions", ChatEvent{Kind: EventCommunityGift, Gifter: "Gifter", RecipientCount: 5}.Describe())
+	assert.Equal(t, "Partner is raiding with 120 viewers", ChatEvent{Kind: EventRaid, AuthorName: "Partner", Viewers: 120}.Describe())
+	assert.Equal(t, "Fan sent a Super Chat of 5.00 USD", ChatEvent{Kind: EventSuperChat, AuthorName: "Fan", Amount: 5, Currency: "USD"}.Describe())
+	assert.Equal(t, "Fan cheered 100 bits: Cheer100", ChatEvent{Kind: EventBits, AuthorName: "Fan", Amount: 100, Currency: "bits", Message: "Cheer100"}.Describe())
+}
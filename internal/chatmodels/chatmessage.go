@@ -1,11 +1,89 @@
 package chatmodels
 
-import "time"
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Role is a privilege level of the message author in the channel.
+type Role string
+
+const (
+	RoleBroadcaster Role = "broadcaster"
+	RoleModerator   Role = "moderator"
+	RoleVIP         Role = "vip"
+	RoleSubscriber  Role = "subscriber"
+	RoleMember      Role = "member"
+	RoleVerified    Role = "verified"
+)
+
+// roleRanks orders the roles that grant privileges in the channel, subscribers and members are
+// the same level on different providers.
+var roleRanks = map[Role]int{
+	RoleSubscriber:  1,
+	RoleMember:      1,
+	RoleVIP:         2,
+	RoleModerator:   3,
+	RoleBroadcaster: 4,
+}
+
+// Rank returns the privilege level of the role, 0 for the roles that grant no privilege.
+func (r Role) Rank() int {
+	return roleRanks[r]
+}
+
+// Badge is a provider badge displayed next to the author name.
+type Badge struct {
+	Name    string
+	Version string
+}
+
+// ReplyReference points to the message a chat message is answering.
+type ReplyReference struct {
+	MessageID  string
+	AuthorID   string
+	AuthorName string
+	Content    string
+}
 
 type ChatMessage struct {
+	// ID is the provider-native message identifier
+	ID                string
 	Provider          string
 	ProviderShortName string
+	Channel           string
 	Timestamp         time.Time
 	Content           string
-	AuthorName        string
+	// Fragments is the content split into text, emotes, mentions, links and cheermotes
+	Fragments []Fragment
+	// AuthorID is the provider-native user identifier
+	AuthorID    string
+	AuthorName  string
+	AuthorColor string
+	Roles       []Role
+	Badges      []Badge
+	ReplyTo     *ReplyReference
+	// Raw keeps the provider metadata that has no dedicated field
+	Raw map[string]string
+}
+
+// HasRole reports whether the author has the given role.
+func (m ChatMessage) HasRole(role Role) bool {
+	return slices.Contains(m.Roles, role)
+}
+
+// IsCommand reports whether the message is a chat bot command, starting with !.
+func (m ChatMessage) IsCommand() bool {
+	return strings.HasPrefix(strings.TrimSpace(m.Content), "!")
+}
+
+// HasRoleAtLeast reports whether the author has the given role or a more privileged one.
+func (m ChatMessage) HasRoleAtLeast(role Role) bool {
+	for _, r := range m.Roles {
+		if r.Rank() >= role.Rank() {
+			return true
+		}
+	}
+	return role.Rank() == 0
 }
@@ -0,0 +1,27 @@
+package chatmodels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChatMessage_HasRoleAtLeast(t *testing.T) {
+	viewer := ChatMessage{}
+	member := ChatMessage{Roles: []Role{RoleMember, RoleVerified}}
+	moderator := ChatMessage{Roles: []Role{RoleSubscriber, RoleModerator}}
+
+	assert.True(t, viewer.HasRoleAtLeast(""))
+	assert.False(t, viewer.HasRoleAtLeast(RoleSubscriber))
+	assert.True(t, member.HasRoleAtLeast(RoleSubscriber))
+	assert.False(t, member.HasRoleAtLeast(RoleVIP))
+	assert.True(t, moderator.HasRoleAtLeast(RoleVIP))
+	assert.True(t, moderator.HasRoleAtLeast(RoleModerator))
+	assert.False(t, moderator.HasRoleAtLeast(RoleBroadcaster))
+}
+
+func TestChatMessage_IsCommand(t *testing.T) {
+	assert.True(t, ChatMessage{Content: "!uptime"}.IsCommand())
+	assert.True(t, ChatMessage{Content: "  !so partner"}.IsCommand())
+	assert.False(t, ChatMessage{Content: "hello!"}.IsCommand())
+}
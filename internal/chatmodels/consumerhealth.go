@@ -0,0 +1,15 @@
+package chatmodels
+
+// ConsumerHealth is a snapshot of the delivery of the chat to a consumer, reported by the aggregator.
+type ConsumerHealth struct {
+	Consumer string
+	// Running is false when the consumer was added but is not started
+	Running bool
+	// Policy is what happens when the queue is full: drop-oldest, drop-newest or block
+	Policy string
+	// Capacity and Queued are the size and the current length of the delivery queue
+	Capacity  int
+	Queued    int
+	Delivered uint64
+	Dropped   uint64
+}
@@ -0,0 +1,92 @@
+package chatmodels
+
+import (
+	"regexp"
+	"strings"
+)
+
+// FragmentType identifies what a part of the message content represents.
+type FragmentType string
+
+const (
+	FragmentText      FragmentType = "text"
+	FragmentEmote     FragmentType = "emote"
+	FragmentMention   FragmentType = "mention"
+	FragmentURL       FragmentType = "url"
+	FragmentCheermote FragmentType = "cheermote"
+)
+
+// EmoteImage is an image of an emote or cheermote at a given scale.
+type EmoteImage struct {
+	Scale string
+	URL   string
+}
+
+// Fragment is a part of the message content. Text always holds the original text of the
+// fragment, so consumers that cannot render a fragment type can fall back to it.
+type Fragment struct {
+	Type FragmentType
+	Text string
+	// EmoteID and Images are set for emotes and cheermotes
+	EmoteID string
+	Images  []EmoteImage
+	// Mention is the mentioned user name, without the @
+	Mention string
+	URL     string
+	// Bits is the amount cheered with a cheermote
+	Bits int
+}
+
+// textTokens matches the links and mentions found in plain chat text.
+var textTokens = regexp.MustCompile(`https?://\S*[^\s.,!?;:'")\]]|@\w+`)
+
+// ParseTextFragments splits plain text into text, mention and URL fragments.
+func ParseTextFragments(text string) []Fragment {
+	var fragments []Fragment
+	cursor := 0
+	for _, match := range textTokens.FindAllStringIndex(text, -1) {
+		fragments = AppendText(fragments, text[cursor:match[0]])
+
+		token := text[match[0]:match[1]]
+		if strings.HasPrefix(token, "@") {
+			fragments = append(fragments, Fragment{Type: FragmentMention, Text: token, Mention: token[1:]})
+		} else {
+			fragments = append(fragments, Fragment{Type: FragmentURL, Text: token, URL: token})
+		}
+		cursor = match[1]
+	}
+	return AppendText(fragments, text[cursor:])
+}
+
+// AppendParsedText parses the text and appends its fragments, merging consecutive text.
+func AppendParsedText(fragments []Fragment, text string) []Fragment {
+	for _, fragment := range ParseTextFragments(text) {
+		if fragment.Type == FragmentText {
+			fragments = AppendText(fragments, fragment.Text)
+		} else {
+			fragments = append(fragments, fragment)
+		}
+	}
+	return fragments
+}
+
+// AppendText adds text to the fragments, merging it with a trailing text fragment.
+func AppendText(fragments []Fragment, text string) []Fragment {
+	if text == "" {
+		return fragments
+	}
+	if last := len(fragments) - 1; last >= 0 && fragments[last].Type == FragmentText {
+		fragments[last].Text += text
+		return fragments
+	}
+	return append(fragments, Fragment{Type: FragmentText, Text: text})
+}
+
+// FragmentsText joins the text of the fragments back into the plain message content.
+func FragmentsText(fragments []Fragment) string {
+	var builder strings.Builder
+	for _, fragment := range fragments {
+		builder.WriteString(fragment.Text)
+	}
+	return builder.String()
+}
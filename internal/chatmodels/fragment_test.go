@@ -0,0 +1,38 @@
+package chatmodels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTextFragments(t *testing.T) {
+	fragments := ParseTextFragments("hey @Streamer, see https://example.com/clip?id=1. nice")
+
+	assert.Equal(t, []Fragment{
+		{Type: FragmentText, Text: "hey "},
+		{Type: FragmentMention, Text: "@Streamer", Mention: "Streamer"},
+		{Type: FragmentText, Text: ", see "},
+		{Type: FragmentURL, Text: "https://example.com/clip?id=1", URL: "https://example.com/clip?id=1"},
+		{Type: FragmentText, Text: ". nice"},
+	}, fragments)
+	assert.Equal(t, "hey @Streamer, see https://example.com/clip?id=1. nice", FragmentsText(fragments))
+}
+
+func TestParseTextFragments_PlainText(t *testing.T) {
+	assert.Equal(t, []Fragment{{Type: FragmentText, Text: "just text"}}, ParseTextFragments("just text"))
+	assert.Empty(t, ParseTextFragments(""))
+}
+
+func TestAppendText_MergesText(t *testing.T) {
+	fragments := AppendText(nil, "a")
+	fragments = AppendText(fragments, "b")
+	fragments = append(fragments, Fragment{Type: FragmentEmote, Text: "Kappa"})
+	fragments = AppendText(fragments, "c")
+
+	assert.Equal(t, []Fragment{
+		{Type: FragmentText, Text: "ab"},
+		{Type: FragmentEmote, Text: "Kappa"},
+		{Type: FragmentText, Text: "c"},
+	}, fragments)
+}
@@ -0,0 +1,93 @@
+package chatmodels
+
+import (
+	"fmt"
+	"time"
+)
+
+// ModerationAction is the kind of moderation applied to the chat.
+type ModerationAction string
+
+const (
+	ModerationDeleteMessage ModerationAction = "delete_message"
+	ModerationTimeout       ModerationAction = "timeout"
+	ModerationBan           ModerationAction = "ban"
+	ModerationClearChat     ModerationAction = "clear_chat"
+	ModerationUnban         ModerationAction = "unban"
+)
+
+// ModerationEvent reports content removed by the moderators, consumers should retract the
+// messages it matches.
+type ModerationEvent struct {
+	Provider          string
+	ProviderShortName string
+	Channel           string
+	Timestamp         time.Time
+	Action            ModerationAction
+	// MessageID is the provider-native identifier of the deleted message
+	MessageID string
+	// AuthorID and AuthorName identify the user that was timed out or banned
+	AuthorID   string
+	AuthorName string
+	// Duration is the length of a timeout
+	Duration time.Duration
+	// Raw keeps the provider metadata that has no dedicated field
+	Raw map[string]string
+}
+
+// Matches reports whether the moderation event removes the given message.
+func (e ModerationEvent) Matches(message ChatMessage) bool {
+	if e.Provider != message.Provider {
+		return false
+	}
+	if e.Channel != "" && message.Channel != "" && e.Channel != message.Channel {
+		return false
+	}
+
+	switch e.Action {
+	case ModerationDeleteMessage:
+		return e.MessageID != "" && e.MessageID == message.ID
+	case ModerationTimeout, ModerationBan:
+		if e.AuthorID != "" {
+			return e.AuthorID == message.AuthorID
+		}
+		return e.AuthorName != "" && e.AuthorName == message.AuthorName
+	case ModerationClearChat:
+		return true
+	default:
+		return false
+	}
+}
+
+// Describe returns a short human readable description of the moderation event.
+func (e ModerationEvent) Describe() string {
+	switch e.Action {
+	case ModerationDeleteMessage:
+		return fmt.Sprintf("message %s from %s was deleted", e.MessageID, e.AuthorName)
+	case ModerationTimeout:
+		return fmt.Sprintf("%s was timed out for %s", e.AuthorName, e.Duration)
+	case ModerationBan:
+		return fmt.Sprintf("%s was banned", e.AuthorName)
+	case ModerationClearChat:
+		return "chat was cleared"
+	case ModerationUnban:
+		return fmt.Sprintf("%s was unbanned", e.AuthorName)
+	default:
+		return string(e.Action)
+	}
+}
+
+// ModerationRequest asks a provider to moderate its chat. MessageID and AuthorID are the
+// provider-native identifiers found in ChatMessage.ID and ChatMessage.AuthorID.
+type ModerationRequest struct {
+	// Provider is the name of the provider that moderates the chat
+	Provider string
+	// Action is one of delete_message, timeout, ban or unban
+	Action    ModerationAction
+	MessageID string
+	AuthorID  string
+	// Duration is the length of a timeout
+	Duration time.Duration
+	// Reason is shown to the user when the provider supports it
+	Reason string
+}
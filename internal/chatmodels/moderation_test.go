@@ -0,0 +1,22 @@
+package chatmodels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerationEvent_Matches(t *testing.T) {
+	message := ChatMessage{ID: "m1", Provider: "Twitch", Channel: "streamer", AuthorID: "42", AuthorName: "Troll"}
+
+	assert.True(t, ModerationEvent{Provider: "Twitch", Action: ModerationDeleteMessage, MessageID: "m1"}.Matches(message))
+	assert.False(t, ModerationEvent{Provider: "Twitch", Action: ModerationDeleteMessage, MessageID: "m2"}.Matches(message))
+	assert.False(t, ModerationEvent{Provider: "Youtube", Action: ModerationDeleteMessage, MessageID: "m1"}.Matches(message))
+
+	assert.True(t, ModerationEvent{Provider: "Twitch", Action: ModerationTimeout, AuthorID: "42"}.Matches(message))
+	assert.False(t, ModerationEvent{Provider: "Twitch", Action: ModerationBan, AuthorID: "43"}.Matches(message))
+	assert.True(t, ModerationEvent{Provider: "Twitch", Action: ModerationBan, AuthorName: "Troll"}.Matches(message))
+
+	assert.True(t, ModerationEvent{Provider: "Twitch", Channel: "streamer", Action: ModerationClearChat}.Matches(message))
+	assert.False(t, ModerationEvent{Provider: "Twitch", Channel: "partner", Action: ModerationClearChat}.Matches(message))
+}
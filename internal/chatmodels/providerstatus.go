@@ -0,0 +1,27 @@
+package chatmodels
+
+import "time"
+
+// ProviderState describes the connection state of a chat provider.
+type ProviderState string
+
+const (
+	ProviderConnecting   ProviderState = "connecting"
+	ProviderConnected    ProviderState = "connected"
+	ProviderReconnecting ProviderState = "reconnecting"
+	ProviderDisconnected ProviderState = "disconnected"
+	ProviderFailed       ProviderState = "failed"
+)
+
+// ProviderStatus is emitted by the aggregator every time a provider changes state.
+type ProviderStatus struct {
+	Provider          string
+	ProviderShortName string
+	Timestamp         time.Time
+	State             ProviderState
+	// Attempt is the number of consecutive failed connection attempts
+	Attempt int
+	// RetryIn is the delay before the next connection attempt, when reconnecting
+	RetryIn time.Duration
+	Error   string
+}
@@ -0,0 +1,15 @@
+package chatmodels
+
+import "context"
+
+// Publish sends a value on a provider channel unless the listening context is already done or
+// nobody listens, so the providers stop sending once their context is cancelled.
+func Publish[T any](ctx context.Context, channel chan<- T, value T) {
+	if channel == nil || ctx.Err() != nil {
+		return
+	}
+	select {
+	case channel <- value:
+	case <-ctx.Done():
+	}
+}
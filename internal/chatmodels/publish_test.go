@@ -0,0 +1,25 @@
+package chatmodels
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	channel := make(chan int, 1)
+	Publish(ctx, channel, 1)
+	assert.Equal(t, 1, <-channel)
+
+	// Nobody listens
+	Publish[int](ctx, nil, 2)
+
+	// The listening context is done, the full channel does not block
+	channel <- 3
+	cancel()
+	Publish(ctx, channel, 4)
+	assert.Equal(t, 3, <-channel)
+	assert.Empty(t, channel)
+}
@@ -1,32 +1,85 @@
 package chatproviders
 
 import (
+	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/SergioCurto/ChatClient/config"
 	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/SergioCurto/ChatClient/internal/chatproviders/replay"
 	"github.com/SergioCurto/ChatClient/internal/chatproviders/twitch"
 	"github.com/SergioCurto/ChatClient/internal/chatproviders/youtube"
 )
 
+// ChatProvider is a source of chat messages, configured by the instance configuration it was created with.
+// The aggregator calls Connect and then Listen, which blocks publishing messages until the
+// context is cancelled or the connection ends. Listen returns nil when the context was
+// cancelled and an error when the connection failed, in which case the aggregator calls
+// Disconnect and reconnects the provider. Providers must stop sending on messages once the
+// context is done.
 type ChatProvider interface {
-	Connect(cfx *config.Config) error
+	Connect(ctx context.Context) error
 	Disconnect() error
-	Listen(messages chan<- chatmodels.ChatMessage) error
+	Listen(ctx context.Context, messages chan<- chatmodels.ChatMessage) error
 	GetName() string
 	GetShortName() string
 }
 
+// ChatEventProvider is implemented by providers that also publish non-chat events.
+// The aggregator sets the events channel before every call to Listen, events are sent
+// with the same rules as messages.
+type ChatEventProvider interface {
+	SetEventsChannel(events chan<- chatmodels.ChatEvent)
+}
+
+// ModerationProvider is implemented by providers that report deleted messages, timeouts,
+// bans and chat clears. The aggregator sets the channel before every call to Listen.
+type ModerationProvider interface {
+	SetModerationChannel(moderation chan<- chatmodels.ModerationEvent)
+}
+
+// ChatSender is implemented by providers that can post messages to their chat.
+// SendMessage returns an error when the provider is not connected or has no credentials.
+type ChatSender interface {
+	SendMessage(ctx context.Context, text string) error
+}
+
+// ChatModerator is implemented by providers that can moderate their chat with the configured account.
+// Message and user IDs are the provider-native IDs found in ChatMessage.ID and ChatMessage.AuthorID.
+type ChatModerator interface {
+	DeleteMessage(ctx context.Context, messageID string) error
+	TimeoutUser(ctx context.Context, userID string, duration time.Duration, reason string) error
+	BanUser(ctx context.Context, userID string, reason string) error
+	UnbanUser(ctx context.Context, userID string) error
+}
+
 type ChatProviderType int
 
 const (
 	Twitch ChatProviderType = iota
 	Youtube
+	Replay
 )
 
+// ParseChatProviderType converts the type of a provider instance configuration into a ChatProviderType.
+func ParseChatProviderType(value string) (ChatProviderType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "twitch":
+		return Twitch, nil
+	case "youtube":
+		return Youtube, nil
+	case "replay":
+		return Replay, nil
+	default:
+		return 0, fmt.Errorf("unknown provider type: %q", value)
+	}
+}
+
 // ChatProviderFactory is the factory interface for creating ChatProviders.
 type ChatProviderFactory interface {
-	CreateProvider(providerType string) (ChatProvider, error)
+	CreateProvider(providerType ChatProviderType, instance config.ProviderConfig) (ChatProvider, error)
 }
 
 // ConcreteChatProviderFactory is a concrete implementation of the ChatProviderFactory.
@@ -38,13 +91,15 @@ func NewConcreteChatProviderFactory() *ConcreteChatProviderFactory {
 	return &ConcreteChatProviderFactory{}
 }
 
-// CreateProvider creates a ChatProvider based on the given providerType.
-func (f *ConcreteChatProviderFactory) CreateProvider(providerType ChatProviderType) (ChatProvider, error) {
+// CreateProvider creates a ChatProvider based on the given providerType, configured by the instance configuration.
+func (f *ConcreteChatProviderFactory) CreateProvider(providerType ChatProviderType, instance config.ProviderConfig) (ChatProvider, error) {
 	switch providerType {
 	case Twitch:
-		return twitch.NewTwitchProvider(), nil
+		return twitch.NewTwitchProvider(instance), nil
 	case Youtube:
-		return youtube.NewYoutubeProvider(), nil
+		return youtube.NewYoutubeProvider(instance), nil
+	case Replay:
+		return replay.NewReplayProvider(instance), nil
 	default:
 		return nil, fmt.Errorf("unknown provider type: %v", providerType)
 	}
@@ -3,6 +3,7 @@ package chatproviders
 import (
 	"testing"
 
+	"github.com/SergioCurto/ChatClient/config"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -10,19 +11,56 @@ func TestConcreteChatProviderFactory_CreateProvider(t *testing.T) {
 	factory := NewConcreteChatProviderFactory()
 
 	// Test creating a Twitch provider
-	provider, err := factory.CreateProvider(Twitch)
+	provider, err := factory.CreateProvider(Twitch, config.ProviderConfig{})
 	assert.NoError(t, err)
 	assert.NotNil(t, provider)
 	assert.Equal(t, "Twitch", provider.GetName())
 
 	// Test creating a Youtube provider
-	provider, err = factory.CreateProvider(Youtube)
+	provider, err = factory.CreateProvider(Youtube, config.ProviderConfig{})
 	assert.NoError(t, err)
 	assert.NotNil(t, provider)
 	assert.Equal(t, "Youtube", provider.GetName())
 
+	// Test creating a Replay provider
+	provider, err = factory.CreateProvider(Replay, config.ProviderConfig{})
+	assert.NoError(t, err)
+	assert.NotNil(t, provider)
+	assert.Equal(t, "Replay", provider.GetName())
+
 	// Test creating an unknown provider
-	provider, err = factory.CreateProvider(ChatProviderType(999)) // Invalid provider type
+	provider, err = factory.CreateProvider(ChatProviderType(999), config.ProviderConfig{}) // Invalid provider type
 	assert.Error(t, err)
 	assert.Nil(t, provider)
 }
+
+func TestConcreteChatProviderFactory_CreateProvider_Instances(t *testing.T) {
+	factory := NewConcreteChatProviderFactory()
+
+	main, err := factory.CreateProvider(Twitch, config.ProviderConfig{Type: "twitch", Name: "Twitch", Twitch: config.TwitchConfig{Channel: "streamer"}})
+	assert.NoError(t, err)
+	partner, err := factory.CreateProvider(Twitch, config.ProviderConfig{Type: "twitch", Name: "Partner", ShortName: "Pa", Twitch: config.TwitchConfig{Channel: "partner"}})
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Twitch", main.GetName())
+	assert.Equal(t, "Tw", main.GetShortName())
+	assert.Equal(t, "Partner", partner.GetName())
+	assert.Equal(t, "Pa", partner.GetShortName())
+}
+
+func TestParseChatProviderType(t *testing.T) {
+	providerType, err := ParseChatProviderType("twitch")
+	assert.NoError(t, err)
+	assert.Equal(t, Twitch, providerType)
+
+	providerType, err = ParseChatProviderType(" YouTube ")
+	assert.NoError(t, err)
+	assert.Equal(t, Youtube, providerType)
+
+	providerType, err = ParseChatProviderType("replay")
+	assert.NoError(t, err)
+	assert.Equal(t, Replay, providerType)
+
+	_, err = ParseChatProviderType("kick")
+	assert.EqualError(t, err, `unknown provider type: "kick"`)
+}
@@ -0,0 +1,205 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatconsumers/recorder"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+)
+
+// ReplayProvider is a ChatProvider that plays back a chat recording with its original timing,
+// so the consumers can be tried with a realistic chat without going live. The messages keep
+// the provider labels of the recording and are timestamped when they are played.
+type ReplayProvider struct {
+	Name       string
+	ShortName  string
+	cfg        config.ReplayConfig
+	events     chan<- chatmodels.ChatEvent
+	moderation chan<- chatmodels.ModerationEvent
+}
+
+func NewReplayProvider(instance config.ProviderConfig) *ReplayProvider {
+	provider := &ReplayProvider{
+		Name:      "Replay",
+		ShortName: "Re",
+		cfg:       instance.Replay,
+	}
+	if instance.Name != "" {
+		provider.Name = instance.Name
+	}
+	if instance.ShortName != "" {
+		provider.ShortName = instance.ShortName
+	}
+	if provider.cfg.Speed <= 0 {
+		provider.cfg.Speed = 1
+	}
+	return provider
+}
+
+// Connect checks that the recording can be read.
+func (p *ReplayProvider) Connect(ctx context.Context) error {
+	file, err := os.Open(p.cfg.File)
+	if err != nil {
+		return fmt.Errorf("error opening recording: %w", err)
+	}
+	return file.Close()
+}
+
+func (p *ReplayProvider) Disconnect() error {
+	return nil
+}
+
+func (p *ReplayProvider) SetEventsChannel(events chan<- chatmodels.ChatEvent) {
+	p.events = events
+}
+
+func (p *ReplayProvider) SetModerationChannel(moderation chan<- chatmodels.ModerationEvent) {
+	p.moderation = moderation
+}
+
+// Listen plays the recording until the context is cancelled. Without looping the provider
+// stays connected and idle once the recording ends. A recording that can not be read further
+// also leaves it idle: returning the error would reconnect and play the recording again from
+// the start, repeating the messages already played.
+func (p *ReplayProvider) Listen(ctx context.Context, messages chan<- chatmodels.ChatMessage) error {
+	for {
+		if err := p.play(ctx, messages); err != nil {
+			fmt.Println("Replay stopped:", p.Name, err)
+			<-ctx.Done()
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if !p.cfg.Loop {
+			fmt.Println("Replay finished:", p.Name)
+			<-ctx.Done()
+			return nil
+		}
+	}
+}
+
+// play plays the recording once, waiting between the records the time that passed between them
+// divided by the speed. The records before the start offset are skipped, and the lines that are
+// not valid records are reported and skipped.
+func (p *ReplayProvider) play(ctx context.Context, messages chan<- chatmodels.ChatMessage) error {
+	reader, err := recorder.Open(p.cfg.File)
+	if err != nil {
+		return fmt.Errorf("error opening recording: %w", err)
+	}
+	defer reader.Close()
+
+	var first, last, started time.Time
+	for {
+		record, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+		if errors.As(err, &syntaxError) || errors.As(err, &typeError) {
+			fmt.Println("Skipping invalid record:", err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// Records without a time, or older than the previous one, are played along with the
+		// previous one: the YouTube messages are polled so they are recorded after newer messages
+		// of the other providers
+		at := record.Time()
+		if at.IsZero() || at.Before(last) {
+			at = last
+		}
+		if first.IsZero() {
+			first = at
+		}
+		last = at
+
+		offset := at.Sub(first)
+		if offset < p.cfg.Start {
+			continue
+		}
+		if started.IsZero() {
+			started = time.Now().Add(-p.scale(p.cfg.Start))
+		}
+
+		if wait := time.Until(started.Add(p.scale(offset))); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			}
+		}
+
+		if !p.emit(ctx, record, messages) {
+			return nil
+		}
+	}
+}
+
+// scale converts a duration of the recording into playback time.
+func (p *ReplayProvider) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / p.cfg.Speed)
+}
+
+// emit publishes a record, it returns false when the context was cancelled.
+func (p *ReplayProvider) emit(ctx context.Context, record recorder.Record, messages chan<- chatmodels.ChatMessage) bool {
+	now := time.Now()
+	switch {
+	case record.Message != nil:
+		message := *record.Message
+		message.Timestamp = now
+		message.Provider, message.ProviderShortName = p.label(message.Provider, message.ProviderShortName)
+		select {
+		case messages <- message:
+		case <-ctx.Done():
+			return false
+		}
+	case record.Event != nil && p.events != nil:
+		event := *record.Event
+		event.Timestamp = now
+		event.Provider, event.ProviderShortName = p.label(event.Provider, event.ProviderShortName)
+		select {
+		case p.events <- event:
+		case <-ctx.Done():
+			return false
+		}
+	case record.Moderation != nil && p.moderation != nil:
+		event := *record.Moderation
+		event.Timestamp = now
+		event.Provider, event.ProviderShortName = p.label(event.Provider, event.ProviderShortName)
+		select {
+		case p.moderation <- event:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+// label returns the provider labels of a record, the replay labels when it has none.
+func (p *ReplayProvider) label(name, shortName string) (string, string) {
+	if name == "" {
+		return p.Name, p.ShortName
+	}
+	return name, shortName
+}
+
+func (p *ReplayProvider) GetName() string {
+	return p.Name
+}
+
+func (p *ReplayProvider) GetShortName() string {
+	return p.ShortName
+}
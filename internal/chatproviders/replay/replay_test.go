@@ -0,0 +1,128 @@
+package replay
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+const recording = `{"ID":"msg-1","Provider":"Twitch","ProviderShortName":"Tw","Timestamp":"2025-03-20T18:30:00Z","Content":"first"}
+{"Event":{"ID":"raid-1","Provider":"Twitch","Timestamp":"2025-03-20T18:30:10Z","Kind":"raid"}}
+{"ID":"msg-2","Timestamp":"2025-03-20T18:30:20Z","Content":"second"}
+{"Moderation":{"Provider":"Twitch","Timestamp":"2025-03-20T18:30:30Z","Action":"delete_message","MessageID":"msg-1"}}
+`
+
+func newReplayProvider(t *testing.T, replay config.ReplayConfig) *ReplayProvider {
+	return newReplayProviderOf(t, replay, recording)
+}
+
+func newReplayProviderOf(t *testing.T, replay config.ReplayConfig, content string) *ReplayProvider {
+	replay.File = filepath.Join(t.TempDir(), "chat.jsonl")
+	assert.NoError(t, os.WriteFile(replay.File, []byte(content), 0o644))
+	return NewReplayProvider(config.ProviderConfig{Name: "Demo", ShortName: "De", Replay: replay})
+}
+
+func TestReplayProvider_PlaysRecording(t *testing.T) {
+	// 30 seconds of recording played in about 30ms
+	provider := newReplayProvider(t, config.ReplayConfig{Speed: 1000})
+	messages := make(chan chatmodels.ChatMessage, 10)
+	events := make(chan chatmodels.ChatEvent, 10)
+	moderation := make(chan chatmodels.ModerationEvent, 10)
+	provider.SetEventsChannel(events)
+	provider.SetModerationChannel(moderation)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, provider.Connect(ctx))
+	start := time.Now()
+	assert.NoError(t, provider.play(ctx, messages))
+
+	assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+	assert.Len(t, messages, 2)
+	first, second := <-messages, <-messages
+	assert.Equal(t, "first", first.Content)
+	assert.Equal(t, "Twitch", first.Provider)
+	assert.False(t, first.Timestamp.Before(start))
+	assert.Equal(t, "second", second.Content)
+	assert.Equal(t, "Demo", second.Provider)
+	assert.Equal(t, "De", second.ProviderShortName)
+	assert.Equal(t, chatmodels.EventRaid, (<-events).Kind)
+	assert.Equal(t, "msg-1", (<-moderation).MessageID)
+}
+
+func TestReplayProvider_Start(t *testing.T) {
+	provider := newReplayProvider(t, config.ReplayConfig{Speed: 100, Start: 15 * time.Second})
+	messages := make(chan chatmodels.ChatMessage, 10)
+
+	start := time.Now()
+	assert.NoError(t, provider.play(context.Background(), messages))
+
+	// The skipped 15 seconds are not waited for, the playback lasts the 15 seconds left
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	assert.Less(t, time.Since(start), 250*time.Millisecond)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "second", (<-messages).Content)
+}
+
+func TestReplayProvider_Loop(t *testing.T) {
+	provider := newReplayProvider(t, config.ReplayConfig{Speed: 1000, Loop: true})
+	messages := make(chan chatmodels.ChatMessage, 10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- provider.Listen(ctx, messages) }()
+
+	for _, content := range []string{"first", "second", "first"} {
+		select {
+		case message := <-messages:
+			assert.Equal(t, content, message.Content)
+		case <-time.After(time.Second):
+			t.Fatal("message not replayed")
+		}
+	}
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestReplayProvider_StaysConnectedAtTheEnd(t *testing.T) {
+	provider := newReplayProvider(t, config.ReplayConfig{Speed: 1000})
+	messages := make(chan chatmodels.ChatMessage, 10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, provider.Listen(ctx, messages))
+	assert.Len(t, messages, 2)
+}
+
+func TestReplayProvider_MissingFile(t *testing.T) {
+	provider := NewReplayProvider(config.ProviderConfig{Replay: config.ReplayConfig{File: filepath.Join(t.TempDir(), "missing.jsonl")}})
+	assert.ErrorContains(t, provider.Connect(context.Background()), "error opening recording")
+	assert.Equal(t, "Replay", provider.GetName())
+}
+
+func TestReplayProvider_OutOfOrderAndInvalidRecords(t *testing.T) {
+	// The YouTube messages are polled, so they are recorded after newer Twitch messages
+	const mixed = `{"ID":"tw-1","Provider":"Twitch","Timestamp":"2025-03-20T18:30:10Z","Content":"twitch"}
+{"ID":"yt-1","Provider":"Youtube","Timestamp":"2025-03-20T18:30:05Z","Content":"youtube"}
+{"ID":"broken",
+{"ID":"tw-2","Provider":"Twitch","Timestamp":"2025-03-20T18:30:20Z","Content":"last"}
+`
+	provider := newReplayProviderOf(t, config.ReplayConfig{Speed: 1000}, mixed)
+	messages := make(chan chatmodels.ChatMessage, 10)
+
+	start := time.Now()
+	assert.NoError(t, provider.play(context.Background(), messages))
+
+	// The late message is played with the previous one and the invalid line is skipped
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Len(t, messages, 3)
+	for _, content := range []string{"twitch", "youtube", "last"} {
+		assert.Equal(t, content, (<-messages).Content)
+	}
+}
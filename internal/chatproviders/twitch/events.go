@@ -0,0 +1,97 @@
+package twitch
+
+import (
+	"maps"
+	"strconv"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+// newUserNoticeEvent converts the USERNOTICE messages for subscriptions, gifts and raids
+// into a ChatEvent. Other notices, like announcements, are not events and return false.
+func (t *TwitchProvider) newUserNoticeEvent(message twitch.UserNoticeMessage) (chatmodels.ChatEvent, bool) {
+	params := message.MsgParams
+	event := chatmodels.ChatEvent{
+		ID:                message.ID,
+		Provider:          t.GetName(),
+		ProviderShortName: t.GetShortName(),
+		Channel:           message.Channel,
+		Timestamp:         message.Time,
+		AuthorID:          message.User.ID,
+		AuthorName:        displayName(message.User),
+		Message:           message.Message,
+		Tier:              subscriptionTier(params["msg-param-sub-plan"]),
+		Raw:               maps.Clone(message.Tags),
+	}
+
+	switch message.MsgID {
+	case "sub":
+		event.Kind = chatmodels.EventSubscription
+		event.Months = intParam(params, "msg-param-cumulative-months")
+	case "resub":
+		event.Kind = chatmodels.EventResubscription
+		event.Months = intParam(params, "msg-param-cumulative-months")
+	case "subgift", "anonsubgift":
+		event.Kind = chatmodels.EventGiftSubscription
+		event.Gifter = event.AuthorName
+		event.Recipient = params["msg-param-recipient-display-name"]
+		event.RecipientCount = 1
+		event.Months = intParam(params, "msg-param-months")
+	case "submysterygift", "anonsubmysterygift":
+		event.Kind = chatmodels.EventCommunityGift
+		event.Gifter = event.AuthorName
+		event.RecipientCount = intParam(params, "msg-param-mass-gift-count")
+	case "raid":
+		event.Kind = chatmodels.EventRaid
+		event.AuthorName = params["msg-param-displayName"]
+		event.Viewers = intParam(params, "msg-param-viewerCount")
+	default:
+		return chatmodels.ChatEvent{}, false
+	}
+
+	return event, true
+}
+
+// newBitsEvent creates the bits event of a cheer, the message itself is still published as chat.
+func (t *TwitchProvider) newBitsEvent(message twitch.PrivateMessage) chatmodels.ChatEvent {
+	return chatmodels.ChatEvent{
+		ID:                message.ID,
+		Provider:          t.GetName(),
+		ProviderShortName: t.GetShortName(),
+		Channel:           message.Channel,
+		Timestamp:         message.Time,
+		Kind:              chatmodels.EventBits,
+		AuthorID:          message.User.ID,
+		AuthorName:        displayName(message.User),
+		Message:           message.Message,
+		Amount:            float64(message.Bits),
+		Currency:          "bits",
+		Raw:               maps.Clone(message.Tags),
+	}
+}
+
+func displayName(user twitch.User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Name
+}
+
+func subscriptionTier(plan string) string {
+	switch plan {
+	case "1000":
+		return "Tier 1"
+	case "2000":
+		return "Tier 2"
+	case "3000":
+		return "Tier 3"
+	default:
+		return plan
+	}
+}
+
+func intParam(params map[string]string, name string) int {
+	value, _ := strconv.Atoi(params[name])
+	return value
+}
@@ -0,0 +1,113 @@
+package twitch
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+const (
+	emoteURLFormat     = "https://static-cdn.jtvnw.net/emoticons/v2/%s/default/dark/%s"
+	cheermoteURLFormat = "https://d3aqoihi2n8ty8.cloudfront.net/actions/%s/dark/animated/%d/%s.gif"
+)
+
+// cheermotes matches the default Twitch cheermotes followed by the amount of bits.
+var cheermotes = regexp.MustCompile(`(?i)\b(cheer|biblethump|cheerwhal|corgo|uni|showlove|party|seemsgood|pride|kappa|frankerz|heyguys|dansgame|elegiggle|trihard|kreygasm|4head|swiftrage|notlikethis|failfish|vohiyo|pjsalt|mrdestructoid|bday|ripcheer|shamrock)(\d+)\b`)
+
+// cheermoteTiers are the bit amounts from which a cheermote changes its image.
+var cheermoteTiers = []int{10000, 5000, 1000, 100, 1}
+
+type emoteSpan struct {
+	start int
+	end   int
+	emote *twitch.Emote
+}
+
+// fragments splits the message using the positions from the emotes tag, the positions
+// are code point offsets in the message text.
+func fragments(message twitch.PrivateMessage) []chatmodels.Fragment {
+	var spans []emoteSpan
+	for _, emote := range message.Emotes {
+		for _, position := range emote.Positions {
+			spans = append(spans, emoteSpan{start: position.Start, end: position.End, emote: emote})
+		}
+	}
+	slices.SortFunc(spans, func(a, b emoteSpan) int { return a.start - b.start })
+
+	runes := []rune(message.Message)
+	cheered := message.Bits > 0
+	var result []chatmodels.Fragment
+	cursor := 0
+	for _, span := range spans {
+		// Skip overlapping or out of range positions instead of producing broken text
+		if span.start < cursor || span.end < span.start || span.end >= len(runes) {
+			continue
+		}
+		result = appendText(result, string(runes[cursor:span.start]), cheered)
+		result = append(result, chatmodels.Fragment{
+			Type:    chatmodels.FragmentEmote,
+			Text:    string(runes[span.start : span.end+1]),
+			EmoteID: span.emote.ID,
+			Images:  emoteImages(span.emote.ID),
+		})
+		cursor = span.end + 1
+	}
+	return appendText(result, string(runes[cursor:]), cheered)
+}
+
+// appendText parses the text between emotes, looking for cheermotes when the message has bits.
+func appendText(fragments []chatmodels.Fragment, text string, cheered bool) []chatmodels.Fragment {
+	if !cheered {
+		return chatmodels.AppendParsedText(fragments, text)
+	}
+
+	cursor := 0
+	for _, match := range cheermotes.FindAllStringSubmatchIndex(text, -1) {
+		fragments = chatmodels.AppendParsedText(fragments, text[cursor:match[0]])
+
+		prefix := strings.ToLower(text[match[2]:match[3]])
+		bits, err := strconv.Atoi(text[match[4]:match[5]])
+		if err != nil || bits <= 0 {
+			fragments = chatmodels.AppendParsedText(fragments, text[match[0]:match[1]])
+		} else {
+			fragments = append(fragments, chatmodels.Fragment{
+				Type:    chatmodels.FragmentCheermote,
+				Text:    text[match[0]:match[1]],
+				EmoteID: prefix,
+				Images:  cheermoteImages(prefix, bits),
+				Bits:    bits,
+			})
+		}
+		cursor = match[1]
+	}
+	return chatmodels.AppendParsedText(fragments, text[cursor:])
+}
+
+func emoteImages(id string) []chatmodels.EmoteImage {
+	images := make([]chatmodels.EmoteImage, 0, 3)
+	for _, scale := range []string{"1.0", "2.0", "3.0"} {
+		images = append(images, chatmodels.EmoteImage{Scale: scale, URL: fmt.Sprintf(emoteURLFormat, id, scale)})
+	}
+	return images
+}
+
+func cheermoteImages(prefix string, bits int) []chatmodels.EmoteImage {
+	tier := 1
+	for _, threshold := range cheermoteTiers {
+		if bits >= threshold {
+			tier = threshold
+			break
+		}
+	}
+
+	images := make([]chatmodels.EmoteImage, 0, 3)
+	for _, scale := range []string{"1", "2", "3"} {
+		images = append(images, chatmodels.EmoteImage{Scale: scale + ".0", URL: fmt.Sprintf(cheermoteURLFormat, prefix, tier, scale)})
+	}
+	return images
+}
@@ -0,0 +1,150 @@
+package twitch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	helixURL    = "https://api.twitch.tv/helix"
+	validateURL = "https://id.twitch.tv/oauth2/validate"
+)
+
+// helixClient calls the Helix moderation endpoints with the OAuth token used for the chat.
+type helixClient struct {
+	httpClient  *http.Client
+	baseURL     string
+	validateURL string
+	token       string
+
+	// clientID and userID are read from the token validation, userID is the moderator
+	identityMux sync.Mutex
+	clientID    string
+	userID      string
+}
+
+func newHelixClient(token string) *helixClient {
+	return &helixClient{
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+		baseURL:     helixURL,
+		validateURL: validateURL,
+		token:       strings.TrimPrefix(token, "oauth:"),
+	}
+}
+
+// identify validates the token once to learn its client ID and the moderator user ID.
+func (h *helixClient) identify(ctx context.Context) (string, string, error) {
+	h.identityMux.Lock()
+	defer h.identityMux.Unlock()
+
+	if h.userID != "" {
+		return h.clientID, h.userID, nil
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, h.validateURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	request.Header.Set("Authorization", "OAuth "+h.token)
+
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return "", "", fmt.Errorf("error validating twitch token: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("error validating twitch token: %s", response.Status)
+	}
+
+	var validation struct {
+		ClientID string `json:"client_id"`
+		UserID   string `json:"user_id"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&validation); err != nil {
+		return "", "", fmt.Errorf("error reading twitch token validation: %v", err)
+	}
+
+	h.clientID = validation.ClientID
+	h.userID = validation.UserID
+	return h.clientID, h.userID, nil
+}
+
+// deleteMessage removes a single chat message.
+func (h *helixClient) deleteMessage(ctx context.Context, broadcasterID string, messageID string) error {
+	return h.do(ctx, http.MethodDelete, "/moderation/chat", broadcasterID, url.Values{"message_id": {messageID}}, nil)
+}
+
+// ban bans a user, a positive duration times the user out instead.
+func (h *helixClient) ban(ctx context.Context, broadcasterID string, userID string, duration time.Duration, reason string) error {
+	type banData struct {
+		UserID   string `json:"user_id"`
+		Duration int    `json:"duration,omitempty"`
+		Reason   string `json:"reason,omitempty"`
+	}
+	body := struct {
+		Data banData `json:"data"`
+	}{
+		Data: banData{UserID: userID, Duration: int(duration.Seconds()), Reason: reason},
+	}
+	return h.do(ctx, http.MethodPost, "/moderation/bans", broadcasterID, nil, body)
+}
+
+// unban removes a ban or a timeout.
+func (h *helixClient) unban(ctx context.Context, broadcasterID string, userID string) error {
+	return h.do(ctx, http.MethodDelete, "/moderation/bans", broadcasterID, url.Values{"user_id": {userID}}, nil)
+}
+
+// do sends a moderation request on behalf of the token user in the broadcaster channel.
+func (h *helixClient) do(ctx context.Context, method string, path string, broadcasterID string, query url.Values, body any) error {
+	clientID, moderatorID, err := h.identify(ctx)
+	if err != nil {
+		return err
+	}
+
+	if query == nil {
+		query = url.Values{}
+	}
+	query.Set("broadcaster_id", broadcasterID)
+	query.Set("moderator_id", moderatorID)
+
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, h.baseURL+path+"?"+query.Encode(), &payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+h.token)
+	request.Header.Set("Client-Id", clientID)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := h.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error calling twitch %s: %v", path, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= http.StatusBadRequest {
+		// Helix errors carry the reason in the message field
+		var helixError struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(response.Body).Decode(&helixError)
+		return fmt.Errorf("error calling twitch %s: %s", path, strings.TrimSpace(response.Status+" "+helixError.Message))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/gempir/go-twitch-irc/v4"
+)
+
+// newClearChatEvent converts a CLEARCHAT into a timeout, a ban or a full chat clear.
+func (t *TwitchProvider) newClearChatEvent(message twitch.ClearChatMessage) chatmodels.ModerationEvent {
+	event := chatmodels.ModerationEvent{
+		Provider:          t.GetName(),
+		ProviderShortName: t.GetShortName(),
+		Channel:           message.Channel,
+		Timestamp:         message.Time,
+		AuthorID:          message.TargetUserID,
+		AuthorName:        message.TargetUsername,
+		Raw:               maps.Clone(message.Tags),
+	}
+
+	switch {
+	case message.TargetUserID == "" && message.TargetUsername == "":
+		event.Action = chatmodels.ModerationClearChat
+	case message.BanDuration > 0:
+		event.Action = chatmodels.ModerationTimeout
+		event.Duration = time.Duration(message.BanDuration) * time.Second
+	default:
+		event.Action = chatmodels.ModerationBan
+	}
+	return event
+}
+
+// newClearMessageEvent converts a CLEARMSG into the deletion of a single message.
+func (t *TwitchProvider) newClearMessageEvent(message twitch.ClearMessage) chatmodels.ModerationEvent {
+	// The library does not parse the timestamp of CLEARMSG
+	timestamp := time.Now()
+	if millis, err := strconv.ParseInt(message.Tags["tmi-sent-ts"], 10, 64); err == nil {
+		timestamp = time.UnixMilli(millis)
+	}
+
+	return chatmodels.ModerationEvent{
+		Provider:          t.GetName(),
+		ProviderShortName: t.GetShortName(),
+		Channel:           message.Channel,
+		Timestamp:         timestamp,
+		Action:            chatmodels.ModerationDeleteMessage,
+		MessageID:         message.TargetMsgID,
+		AuthorName:        message.Login,
+		Raw:               maps.Clone(message.Tags),
+	}
+}
+
+// DeleteMessage deletes a chat message with the authenticated account, which must be a moderator.
+func (t *TwitchProvider) DeleteMessage(ctx context.Context, messageID string) error {
+	helix, roomID, err := t.moderator()
+	if err != nil {
+		return err
+	}
+	return helix.deleteMessage(ctx, roomID, messageID)
+}
+
+// TimeoutUser prevents a user from chatting for the given duration.
+func (t *TwitchProvider) TimeoutUser(ctx context.Context, userID string, duration time.Duration, reason string) error {
+	if duration < time.Second {
+		return fmt.Errorf("twitch timeouts must last at least one second")
+	}
+	helix, roomID, err := t.moderator()
+	if err != nil {
+		return err
+	}
+	return helix.ban(ctx, roomID, userID, duration, reason)
+}
+
+// BanUser permanently bans a user from the channel.
+func (t *TwitchProvider) BanUser(ctx context.Context, userID string, reason string) error {
+	helix, roomID, err := t.moderator()
+	if err != nil {
+		return err
+	}
+	return helix.ban(ctx, roomID, userID, 0, reason)
+}
+
+// UnbanUser lifts a ban or a timeout.
+func (t *TwitchProvider) UnbanUser(ctx context.Context, userID string) error {
+	helix, roomID, err := t.moderator()
+	if err != nil {
+		return err
+	}
+	return helix.unban(ctx, roomID, userID)
+}
+
+// moderator returns the Helix client and the channel ID needed by the moderation endpoints.
+func (t *TwitchProvider) moderator() (*helixClient, string, error) {
+	t.clientMux.RLock()
+	defer t.clientMux.RUnlock()
+
+	if t.helix == nil {
+		return nil, "", fmt.Errorf("twitch provider is anonymous, set TWITCH_USERNAME and TWITCH_OAUTH_TOKEN to moderate")
+	}
+	if t.roomID == "" {
+		return nil, "", fmt.Errorf("twitch channel ID is not known yet, the chat is not joined")
+	}
+	return t.helix, t.roomID, nil
+}
+
+func (t *TwitchProvider) setRoomID(roomID string) {
+	t.clientMux.Lock()
+	defer t.clientMux.Unlock()
+	t.roomID = roomID
+}
@@ -1,8 +1,16 @@
 package twitch
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/SergioCurto/ChatClient/config"
 	"github.com/SergioCurto/ChatClient/internal/chatmodels"
@@ -11,74 +19,234 @@ import (
 
 type TwitchProvider struct {
 	// Twitch specific variables
-	Name         string
-	ShortName    string
-	client       *twitch.Client
-	channel      string
-	messagesChan chan<- chatmodels.ChatMessage
+	Name       string
+	ShortName  string
+	cfg        config.TwitchConfig
+	client     *twitch.Client
+	channel    string
+	events     chan<- chatmodels.ChatEvent
+	moderation chan<- chatmodels.ModerationEvent
+	// authenticated is true when the client logged in with an OAuth token and can send messages
+	authenticated bool
+	connected     atomic.Bool
+	clientMux     sync.RWMutex
+	// helix moderates the chat with the OAuth token, roomID is the channel ID read from the IRC tags
+	helix  *helixClient
+	roomID string
 }
 
-func NewTwitchProvider() *TwitchProvider {
-	return &TwitchProvider{
+// NewTwitchProvider creates a provider for the channel of the instance configuration.
+// The instance name and short name default to Twitch and Tw.
+func NewTwitchProvider(instance config.ProviderConfig) *TwitchProvider {
+	provider := &TwitchProvider{
 		Name:      "Twitch",
 		ShortName: "Tw",
+		cfg:       instance.Twitch,
 	}
+	if instance.Name != "" {
+		provider.Name = instance.Name
+	}
+	if instance.ShortName != "" {
+		provider.ShortName = instance.ShortName
+	}
+	return provider
 }
 
-func (t *TwitchProvider) Connect(cfx *config.Config) error {
-	fmt.Println("Connecting to Twitch...")
+func (t *TwitchProvider) Connect(ctx context.Context) error {
+	fmt.Printf("Connecting to Twitch (%s)...\n", t.Name)
 
-	// Get Twitch credentials from environment variables
-	t.channel = cfx.TwitchChannel
-	if t.channel == "" {
-		return fmt.Errorf("missing twitch_channel in environment variables")
+	channel := t.cfg.Channel
+	if channel == "" {
+		return fmt.Errorf("missing twitch channel for provider %s", t.Name)
 	}
 
-	// Create a new Twitch client
-	t.client = twitch.NewAnonymousClient()
+	// Create a new Twitch client, anonymous unless credentials to send messages are configured
+	var client *twitch.Client
+	var helix *helixClient
+	authenticated := t.cfg.Username != "" && t.cfg.OAuthToken != ""
+	if authenticated {
+		client = twitch.NewClient(t.cfg.Username, "oauth:"+strings.TrimPrefix(t.cfg.OAuthToken, "oauth:"))
+		helix = newHelixClient(t.cfg.OAuthToken)
+	} else {
+		client = twitch.NewAnonymousClient()
+	}
 
 	// Join the specified channel
-	t.client.Join(t.channel)
+	client.Join(channel)
+
+	t.clientMux.Lock()
+	t.client = client
+	t.channel = channel
+	t.authenticated = authenticated
+	t.helix = helix
+	t.roomID = ""
+	t.clientMux.Unlock()
+	t.connected.Store(false)
 
 	return nil
 }
 
 func (t *TwitchProvider) Disconnect() error {
-	fmt.Println("Disconnecting from Twitch...")
-	if t.client != nil {
-		t.client.Disconnect()
+	fmt.Printf("Disconnecting from Twitch (%s)...\n", t.Name)
+	t.connected.Store(false)
+	if client := t.getClient(); client != nil {
+		client.Disconnect()
 	}
 	return nil
 }
 
-func (t *TwitchProvider) Listen(messages chan<- chatmodels.ChatMessage) error {
-	t.messagesChan = messages
+// SendMessage posts a message to the channel with the authenticated account.
+func (t *TwitchProvider) SendMessage(ctx context.Context, text string) error {
+	t.clientMux.RLock()
+	client, channel, authenticated := t.client, t.channel, t.authenticated
+	t.clientMux.RUnlock()
+
+	if !authenticated {
+		return fmt.Errorf("twitch provider is anonymous, set TWITCH_USERNAME and TWITCH_OAUTH_TOKEN to send messages")
+	}
+	if client == nil || !t.connected.Load() {
+		return fmt.Errorf("twitch provider is not connected")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// IRC messages end at the first line break
+	client.Say(channel, strings.Join(strings.Fields(text), " "))
+	return nil
+}
+
+func (t *TwitchProvider) getClient() *twitch.Client {
+	t.clientMux.RLock()
+	defer t.clientMux.RUnlock()
+	return t.client
+}
+
+// SetEventsChannel sets where subscriptions, bits and raids are published.
+func (t *TwitchProvider) SetEventsChannel(events chan<- chatmodels.ChatEvent) {
+	t.events = events
+}
+
+// SetModerationChannel sets where deleted messages, timeouts and bans are published.
+func (t *TwitchProvider) SetModerationChannel(moderation chan<- chatmodels.ModerationEvent) {
+	t.moderation = moderation
+}
+
+func (t *TwitchProvider) Listen(ctx context.Context, messages chan<- chatmodels.ChatMessage) error {
+	client := t.getClient()
+	if client == nil {
+		return fmt.Errorf("twitch provider is not connected")
+	}
+
+	// The room state is sent when joining, it carries the channel ID used to moderate
+	client.OnRoomStateMessage(func(message twitch.RoomStateMessage) {
+		t.setRoomID(message.RoomID)
+	})
 
 	// Handle incoming messages
-	t.client.OnPrivateMessage(func(message twitch.PrivateMessage) {
-		t.messagesChan <- chatmodels.ChatMessage{
-			Provider:          t.GetName(),
-			ProviderShortName: t.GetShortName(),
-			Timestamp:         message.Time,
-			Content:           message.Message,
-			AuthorName:        message.User.DisplayName,
+	client.OnPrivateMessage(func(message twitch.PrivateMessage) {
+		chatmodels.Publish(ctx, messages, t.newChatMessage(message))
+		if message.Bits > 0 {
+			chatmodels.Publish(ctx, t.events, t.newBitsEvent(message))
 		}
 	})
 
+	// Handle subscriptions, gifts and raids
+	client.OnUserNoticeMessage(func(message twitch.UserNoticeMessage) {
+		if event, ok := t.newUserNoticeEvent(message); ok {
+			chatmodels.Publish(ctx, t.events, event)
+		}
+	})
+
+	// Handle deleted messages, timeouts, bans and chat clears
+	client.OnClearChatMessage(func(message twitch.ClearChatMessage) {
+		chatmodels.Publish(ctx, t.moderation, t.newClearChatEvent(message))
+	})
+	client.OnClearMessage(func(message twitch.ClearMessage) {
+		chatmodels.Publish(ctx, t.moderation, t.newClearMessageEvent(message))
+	})
+
 	// Handle connection errors
-	t.client.OnConnect(func() {
+	client.OnConnect(func() {
+		t.connected.Store(true)
 		log.Println("Connected to Twitch chat")
 	})
 
-	// Start listening for messages
+	// Start listening for messages, the client only returns when the connection ends
+	connectErr := make(chan error, 1)
 	go func() {
-		err := t.client.Connect()
-		if err != nil {
-			log.Println("Error connecting to Twitch:", err)
-		}
+		connectErr <- client.Connect()
 	}()
 
-	return nil
+	select {
+	case err := <-connectErr:
+		if errors.Is(err, twitch.ErrClientDisconnected) {
+			return nil
+		}
+		return fmt.Errorf("error connecting to Twitch: %v", err)
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// newChatMessage converts an IRC private message, with its tags, into a ChatMessage.
+func (t *TwitchProvider) newChatMessage(message twitch.PrivateMessage) chatmodels.ChatMessage {
+	chatMessage := chatmodels.ChatMessage{
+		ID:                message.ID,
+		Provider:          t.GetName(),
+		ProviderShortName: t.GetShortName(),
+		Channel:           message.Channel,
+		Timestamp:         message.Time,
+		Content:           message.Message,
+		Fragments:         fragments(message),
+		AuthorID:          message.User.ID,
+		AuthorName:        displayName(message.User),
+		AuthorColor:       message.User.Color,
+		Roles:             roles(message.User.Badges),
+		Badges:            badges(message.User.Badges),
+		Raw:               maps.Clone(message.Tags),
+	}
+
+	if message.Reply != nil {
+		chatMessage.ReplyTo = &chatmodels.ReplyReference{
+			MessageID:  message.Reply.ParentMsgID,
+			AuthorID:   message.Reply.ParentUserID,
+			AuthorName: message.Reply.ParentDisplayName,
+			Content:    message.Reply.ParentMsgBody,
+		}
+	}
+
+	return chatMessage
+}
+
+// roles maps the Twitch badges that grant privileges to chat roles.
+func roles(userBadges map[string]int) []chatmodels.Role {
+	var result []chatmodels.Role
+	for _, badge := range []struct {
+		name string
+		role chatmodels.Role
+	}{
+		{"broadcaster", chatmodels.RoleBroadcaster},
+		{"moderator", chatmodels.RoleModerator},
+		{"vip", chatmodels.RoleVIP},
+		{"subscriber", chatmodels.RoleSubscriber},
+		{"founder", chatmodels.RoleSubscriber},
+		{"partner", chatmodels.RoleVerified},
+	} {
+		if _, ok := userBadges[badge.name]; ok && !slices.Contains(result, badge.role) {
+			result = append(result, badge.role)
+		}
+	}
+	return result
+}
+
+// badges returns the user badges sorted by name, the IRC tags do not keep their order.
+func badges(userBadges map[string]int) []chatmodels.Badge {
+	result := make([]chatmodels.Badge, 0, len(userBadges))
+	for _, name := range slices.Sorted(maps.Keys(userBadges)) {
+		result = append(result, chatmodels.Badge{Name: name, Version: strconv.Itoa(userBadges[name])})
+	}
+	return result
 }
 
 func (t *TwitchProvider) GetName() string {
@@ -0,0 +1,283 @@
+package twitch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/gempir/go-twitch-irc/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func parsePrivateMessage(t *testing.T, line string) twitch.PrivateMessage {
+	message, ok := twitch.ParseMessage(line).(*twitch.PrivateMessage)
+	if !ok {
+		t.Fatalf("not a private message: %s", line)
+	}
+	return *message
+}
+
+func TestTwitchProvider_NewChatMessage(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	message := parsePrivateMessage(t, "@badge-info=subscriber/14;badges=moderator/1,subscriber/12,partner/1;color=#FF4500;display-name=SomeMod;id=b34ccfc7-4977-403a-8a94-33c6bac34fb8;mod=1;room-id=1337;subscriber=1;tmi-sent-ts=1507246572675;user-id=9876 :somemod!somemod@somemod.tmi.twitch.tv PRIVMSG #streamer :Hello chat")
+
+	chatMessage := provider.newChatMessage(message)
+
+	assert.Equal(t, "b34ccfc7-4977-403a-8a94-33c6bac34fb8", chatMessage.ID)
+	assert.Equal(t, "Twitch", chatMessage.Provider)
+	assert.Equal(t, "Tw", chatMessage.ProviderShortName)
+	assert.Equal(t, "streamer", chatMessage.Channel)
+	assert.Equal(t, int64(1507246572675), chatMessage.Timestamp.UnixMilli())
+	assert.Equal(t, "Hello chat", chatMessage.Content)
+	assert.Equal(t, "9876", chatMessage.AuthorID)
+	assert.Equal(t, "SomeMod", chatMessage.AuthorName)
+	assert.Equal(t, "#FF4500", chatMessage.AuthorColor)
+	assert.Equal(t, []chatmodels.Role{chatmodels.RoleModerator, chatmodels.RoleSubscriber, chatmodels.RoleVerified}, chatMessage.Roles)
+	assert.Equal(t, []chatmodels.Badge{{Name: "moderator", Version: "1"}, {Name: "partner", Version: "1"}, {Name: "subscriber", Version: "12"}}, chatMessage.Badges)
+	assert.Equal(t, "1337", chatMessage.Raw["room-id"])
+	assert.Nil(t, chatMessage.ReplyTo)
+	assert.True(t, chatMessage.HasRole(chatmodels.RoleModerator))
+	assert.False(t, chatMessage.HasRole(chatmodels.RoleBroadcaster))
+}
+
+func TestTwitchProvider_NewChatMessage_Reply(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	message := parsePrivateMessage(t, "@badges=broadcaster/1;display-name=Streamer;id=abc;reply-parent-display-name=Viewer;reply-parent-msg-body=first\\sline;reply-parent-msg-id=parent-id;reply-parent-user-id=42;reply-parent-user-login=viewer;tmi-sent-ts=1507246572675;user-id=1 :streamer!streamer@streamer.tmi.twitch.tv PRIVMSG #streamer :@Viewer hi")
+
+	chatMessage := provider.newChatMessage(message)
+
+	assert.Equal(t, []chatmodels.Role{chatmodels.RoleBroadcaster}, chatMessage.Roles)
+	assert.Equal(t, &chatmodels.ReplyReference{MessageID: "parent-id", AuthorID: "42", AuthorName: "Viewer", Content: "first line"}, chatMessage.ReplyTo)
+}
+
+func TestFragments_Emotes(t *testing.T) {
+	message := parsePrivateMessage(t, "@display-name=Viewer;emotes=25:0-4,12-16/1902:6-10;id=abc;tmi-sent-ts=1507246572675;user-id=2 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #streamer :Kappa Keepo Kappa hi @Streamer")
+
+	result := fragments(message)
+
+	assert.Len(t, result, 7)
+	assert.Equal(t, chatmodels.FragmentEmote, result[0].Type)
+	assert.Equal(t, "Kappa", result[0].Text)
+	assert.Equal(t, "25", result[0].EmoteID)
+	assert.Equal(t, "https://static-cdn.jtvnw.net/emoticons/v2/25/default/dark/1.0", result[0].Images[0].URL)
+	assert.Equal(t, chatmodels.Fragment{Type: chatmodels.FragmentText, Text: " "}, result[1])
+	assert.Equal(t, "Keepo", result[2].Text)
+	assert.Equal(t, "1902", result[2].EmoteID)
+	assert.Equal(t, "Kappa", result[4].Text)
+	assert.Equal(t, chatmodels.Fragment{Type: chatmodels.FragmentText, Text: " hi "}, result[5])
+	assert.Equal(t, chatmodels.Fragment{Type: chatmodels.FragmentMention, Text: "@Streamer", Mention: "Streamer"}, result[6])
+	assert.Equal(t, message.Message, chatmodels.FragmentsText(result))
+}
+
+func TestFragments_MultiByteText(t *testing.T) {
+	message := parsePrivateMessage(t, "@display-name=Viewer;emotes=25:3-7;id=abc;tmi-sent-ts=1507246572675;user-id=2 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #streamer :é✓ Kappa")
+
+	result := fragments(message)
+
+	assert.Equal(t, []string{"é✓ ", "Kappa"}, []string{result[0].Text, result[1].Text})
+	assert.Equal(t, chatmodels.FragmentEmote, result[1].Type)
+}
+
+func TestFragments_Cheermotes(t *testing.T) {
+	message := parsePrivateMessage(t, "@bits=1100;display-name=Viewer;id=abc;tmi-sent-ts=1507246572675;user-id=2 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #streamer :Cheer1000 great stream Kappa100")
+
+	result := fragments(message)
+
+	assert.Len(t, result, 3)
+	assert.Equal(t, chatmodels.FragmentCheermote, result[0].Type)
+	assert.Equal(t, 1000, result[0].Bits)
+	assert.Equal(t, "cheer", result[0].EmoteID)
+	assert.Equal(t, "https://d3aqoihi2n8ty8.cloudfront.net/actions/cheer/dark/animated/1000/1.gif", result[0].Images[0].URL)
+	assert.Equal(t, chatmodels.Fragment{Type: chatmodels.FragmentText, Text: " great stream "}, result[1])
+	assert.Equal(t, 100, result[2].Bits)
+}
+
+func TestFragments_NoBitsKeepsCheerText(t *testing.T) {
+	message := parsePrivateMessage(t, "@display-name=Viewer;id=abc;tmi-sent-ts=1507246572675;user-id=2 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #streamer :Cheer100")
+
+	assert.Equal(t, []chatmodels.Fragment{{Type: chatmodels.FragmentText, Text: "Cheer100"}}, fragments(message))
+}
+
+func parseUserNotice(t *testing.T, line string) twitch.UserNoticeMessage {
+	message, ok := twitch.ParseMessage(line).(*twitch.UserNoticeMessage)
+	if !ok {
+		t.Fatalf("not a user notice: %s", line)
+	}
+	return *message
+}
+
+func TestTwitchProvider_NewUserNoticeEvent_Resub(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	message := parseUserNotice(t, "@badges=subscriber/12;display-name=Loyal;id=ev-1;msg-id=resub;msg-param-cumulative-months=12;msg-param-sub-plan=1000;tmi-sent-ts=1507246572675;user-id=7 :tmi.twitch.tv USERNOTICE #streamer :still here")
+
+	event, ok := provider.newUserNoticeEvent(message)
+
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventResubscription, event.Kind)
+	assert.Equal(t, "ev-1", event.ID)
+	assert.Equal(t, "Loyal", event.AuthorName)
+	assert.Equal(t, "7", event.AuthorID)
+	assert.Equal(t, 12, event.Months)
+	assert.Equal(t, "Tier 1", event.Tier)
+	assert.Equal(t, "still here", event.Message)
+	assert.Equal(t, "streamer", event.Channel)
+}
+
+func TestTwitchProvider_NewUserNoticeEvent_Gifts(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+
+	event, ok := provider.newUserNoticeEvent(parseUserNotice(t, "@display-name=Gifter;id=ev-2;msg-id=subgift;msg-param-months=1;msg-param-recipient-display-name=Lucky;msg-param-sub-plan=2000;tmi-sent-ts=1507246572675;user-id=8 :tmi.twitch.tv USERNOTICE #streamer"))
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventGiftSubscription, event.Kind)
+	assert.Equal(t, "Gifter", event.Gifter)
+	assert.Equal(t, "Lucky", event.Recipient)
+	assert.Equal(t, 1, event.RecipientCount)
+	assert.Equal(t, "Tier 2", event.Tier)
+
+	event, ok = provider.newUserNoticeEvent(parseUserNotice(t, "@display-name=Generous;id=ev-3;msg-id=submysterygift;msg-param-mass-gift-count=20;msg-param-sub-plan=1000;tmi-sent-ts=1507246572675;user-id=9 :tmi.twitch.tv USERNOTICE #streamer"))
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventCommunityGift, event.Kind)
+	assert.Equal(t, 20, event.RecipientCount)
+}
+
+func TestTwitchProvider_NewUserNoticeEvent_Raid(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	event, ok := provider.newUserNoticeEvent(parseUserNotice(t, "@display-name=partner;id=ev-4;msg-id=raid;msg-param-displayName=Partner;msg-param-viewerCount=150;tmi-sent-ts=1507246572675;user-id=10 :tmi.twitch.tv USERNOTICE #streamer"))
+
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventRaid, event.Kind)
+	assert.Equal(t, "Partner", event.AuthorName)
+	assert.Equal(t, 150, event.Viewers)
+}
+
+func TestTwitchProvider_NewUserNoticeEvent_IgnoresAnnouncements(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	_, ok := provider.newUserNoticeEvent(parseUserNotice(t, "@display-name=Mod;id=ev-5;msg-id=announcement;tmi-sent-ts=1507246572675;user-id=11 :tmi.twitch.tv USERNOTICE #streamer :hello"))
+	assert.False(t, ok)
+}
+
+func TestTwitchProvider_NewBitsEvent(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	event := provider.newBitsEvent(parsePrivateMessage(t, "@bits=100;display-name=Fan;id=abc;tmi-sent-ts=1507246572675;user-id=2 :fan!fan@fan.tmi.twitch.tv PRIVMSG #streamer :Cheer100 gg"))
+
+	assert.Equal(t, chatmodels.EventBits, event.Kind)
+	assert.Equal(t, float64(100), event.Amount)
+	assert.Equal(t, "bits", event.Currency)
+	assert.Equal(t, "Cheer100 gg", event.Message)
+}
+
+func TestTwitchProvider_NewClearChatEvent(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+
+	message := twitch.ParseMessage("@ban-duration=600;room-id=1;target-user-id=42;tmi-sent-ts=1507246572675 :tmi.twitch.tv CLEARCHAT #streamer :troll").(*twitch.ClearChatMessage)
+	event := provider.newClearChatEvent(*message)
+	assert.Equal(t, chatmodels.ModerationTimeout, event.Action)
+	assert.Equal(t, "42", event.AuthorID)
+	assert.Equal(t, "troll", event.AuthorName)
+	assert.Equal(t, 10*time.Minute, event.Duration)
+	assert.Equal(t, "streamer", event.Channel)
+
+	message = twitch.ParseMessage("@room-id=1;target-user-id=42;tmi-sent-ts=1507246572675 :tmi.twitch.tv CLEARCHAT #streamer :troll").(*twitch.ClearChatMessage)
+	assert.Equal(t, chatmodels.ModerationBan, provider.newClearChatEvent(*message).Action)
+
+	message = twitch.ParseMessage("@room-id=1;tmi-sent-ts=1507246572675 :tmi.twitch.tv CLEARCHAT #streamer").(*twitch.ClearChatMessage)
+	assert.Equal(t, chatmodels.ModerationClearChat, provider.newClearChatEvent(*message).Action)
+}
+
+func TestTwitchProvider_NewClearMessageEvent(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	message := twitch.ParseMessage("@login=troll;room-id=;target-msg-id=abc-123;tmi-sent-ts=1507246572675 :tmi.twitch.tv CLEARMSG #streamer :bad words").(*twitch.ClearMessage)
+
+	event := provider.newClearMessageEvent(*message)
+
+	assert.Equal(t, chatmodels.ModerationDeleteMessage, event.Action)
+	assert.Equal(t, "abc-123", event.MessageID)
+	assert.Equal(t, "troll", event.AuthorName)
+	assert.Equal(t, int64(1507246572675), event.Timestamp.UnixMilli())
+}
+
+func TestTwitchProvider_BanUser(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/validate" {
+			fmt.Fprint(w, `{"client_id":"client","login":"moderator","user_id":"99"}`)
+			return
+		}
+		fmt.Fprint(w, `{"data":[]}`)
+	}))
+	defer server.Close()
+
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	provider.helix = newHelixClient("oauth:token")
+	provider.helix.baseURL = server.URL
+	provider.helix.validateURL = server.URL + "/validate"
+	provider.setRoomID("1337")
+
+	assert.NoError(t, provider.TimeoutUser(context.Background(), "42", 10*time.Minute, "spam"))
+	assert.NoError(t, provider.DeleteMessage(context.Background(), "abc"))
+
+	assert.Len(t, requests, 3)
+	assert.Equal(t, "OAuth token", requests[0].Header.Get("Authorization"))
+
+	assert.Equal(t, http.MethodPost, requests[1].Method)
+	assert.Equal(t, "/moderation/bans", requests[1].URL.Path)
+	assert.Equal(t, "1337", requests[1].URL.Query().Get("broadcaster_id"))
+	assert.Equal(t, "99", requests[1].URL.Query().Get("moderator_id"))
+	assert.Equal(t, "Bearer token", requests[1].Header.Get("Authorization"))
+	assert.Equal(t, "client", requests[1].Header.Get("Client-Id"))
+	assert.JSONEq(t, `{"data":{"user_id":"42","duration":600,"reason":"spam"}}`, bodies[1])
+
+	assert.Equal(t, http.MethodDelete, requests[2].Method)
+	assert.Equal(t, "/moderation/chat", requests[2].URL.Path)
+	assert.Equal(t, "abc", requests[2].URL.Query().Get("message_id"))
+}
+
+func TestTwitchProvider_UnbanUser_HelixError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/validate" {
+			fmt.Fprint(w, `{"client_id":"client","user_id":"99"}`)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":"Bad Request","status":400,"message":"The user is not banned."}`)
+	}))
+	defer server.Close()
+
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	provider.helix = newHelixClient("token")
+	provider.helix.baseURL = server.URL
+	provider.helix.validateURL = server.URL + "/validate"
+	provider.setRoomID("1337")
+
+	err := provider.UnbanUser(context.Background(), "42")
+	assert.EqualError(t, err, "error calling twitch /moderation/bans: 400 Bad Request The user is not banned.")
+}
+
+func TestTwitchProvider_Moderation_Anonymous(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{})
+	provider.setRoomID("1337")
+
+	err := provider.BanUser(context.Background(), "42", "")
+	assert.EqualError(t, err, "twitch provider is anonymous, set TWITCH_USERNAME and TWITCH_OAUTH_TOKEN to moderate")
+}
+
+func TestTwitchProvider_InstanceLabels(t *testing.T) {
+	provider := NewTwitchProvider(config.ProviderConfig{Name: "Partner", ShortName: "Pa", Twitch: config.TwitchConfig{Channel: "partner"}})
+	message := parsePrivateMessage(t, "@display-name=Viewer;id=abc;tmi-sent-ts=1507246572675;user-id=2 :viewer!viewer@viewer.tmi.twitch.tv PRIVMSG #partner :hi")
+
+	chatMessage := provider.newChatMessage(message)
+
+	assert.Equal(t, "Partner", chatMessage.Provider)
+	assert.Equal(t, "Pa", chatMessage.ProviderShortName)
+	assert.Equal(t, "partner", chatMessage.Channel)
+}
@@ -0,0 +1,96 @@
+package youtube
+
+import (
+	"strconv"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"google.golang.org/api/youtube/v3"
+)
+
+// newChatEvent converts Super Chats, Super Stickers and membership messages into a ChatEvent.
+// Any other message type returns false and is handled as chat.
+func (y *YoutubeProvider) newChatEvent(item *youtube.LiveChatMessage) (chatmodels.ChatEvent, bool) {
+	if item.Snippet == nil {
+		return chatmodels.ChatEvent{}, false
+	}
+
+	// The author fields are shared with chat messages
+	message := y.newChatMessage(item)
+	event := chatmodels.ChatEvent{
+		ID:                message.ID,
+		Provider:          message.Provider,
+		ProviderShortName: message.ProviderShortName,
+		Channel:           message.Channel,
+		Timestamp:         message.Timestamp,
+		AuthorID:          message.AuthorID,
+		AuthorName:        message.AuthorName,
+		Raw:               message.Raw,
+	}
+	event.Raw["displayMessage"] = item.Snippet.DisplayMessage
+
+	snippet := item.Snippet
+	switch {
+	case snippet.SuperChatDetails != nil:
+		details := snippet.SuperChatDetails
+		event.Kind = chatmodels.EventSuperChat
+		event.Message = details.UserComment
+		event.Amount = float64(details.AmountMicros) / 1e6
+		event.Currency = details.Currency
+		event.Tier = strconv.FormatInt(details.Tier, 10)
+		event.Raw["amountDisplayString"] = details.AmountDisplayString
+	case snippet.SuperStickerDetails != nil:
+		details := snippet.SuperStickerDetails
+		event.Kind = chatmodels.EventSuperSticker
+		event.Amount = float64(details.AmountMicros) / 1e6
+		event.Currency = details.Currency
+		event.Tier = strconv.FormatInt(details.Tier, 10)
+		event.Raw["amountDisplayString"] = details.AmountDisplayString
+		if details.SuperStickerMetadata != nil {
+			event.Message = details.SuperStickerMetadata.AltText
+			event.Raw["stickerId"] = details.SuperStickerMetadata.StickerId
+		}
+	case snippet.NewSponsorDetails != nil:
+		event.Kind = chatmodels.EventSubscription
+		event.Tier = snippet.NewSponsorDetails.MemberLevelName
+		event.Months = 1
+	case snippet.MemberMilestoneChatDetails != nil:
+		details := snippet.MemberMilestoneChatDetails
+		event.Kind = chatmodels.EventResubscription
+		event.Message = details.UserComment
+		event.Tier = details.MemberLevelName
+		event.Months = int(details.MemberMonth)
+	case snippet.MembershipGiftingDetails != nil:
+		details := snippet.MembershipGiftingDetails
+		event.Kind = chatmodels.EventCommunityGift
+		event.Gifter = event.AuthorName
+		event.RecipientCount = int(details.GiftMembershipsCount)
+		event.Tier = details.GiftMembershipsLevelName
+		y.rememberGifter(event.AuthorID, event.AuthorName)
+	case snippet.GiftMembershipReceivedDetails != nil:
+		details := snippet.GiftMembershipReceivedDetails
+		event.Kind = chatmodels.EventGiftSubscription
+		event.Recipient = event.AuthorName
+		event.Gifter = y.gifters[details.GifterChannelId]
+		event.RecipientCount = 1
+		event.Tier = details.MemberLevelName
+		event.Raw["gifterChannelId"] = details.GifterChannelId
+	default:
+		return chatmodels.ChatEvent{}, false
+	}
+
+	return event, true
+}
+
+// maxGifters is the number of gifters whose names are kept for the memberships they gift
+const maxGifters = 100
+
+// rememberGifter keeps the name of a gifter for the gift received messages that follow.
+func (y *YoutubeProvider) rememberGifter(channelID, name string) {
+	if channelID == "" || name == "" {
+		return
+	}
+	if y.gifters == nil || len(y.gifters) >= maxGifters {
+		y.gifters = make(map[string]string)
+	}
+	y.gifters[channelID] = name
+}
@@ -0,0 +1,136 @@
+package youtube
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"google.golang.org/api/youtube/v3"
+)
+
+// newModerationEvent converts deleted, retracted and user banned messages into a ModerationEvent.
+// Any other message type returns false.
+func (y *YoutubeProvider) newModerationEvent(item *youtube.LiveChatMessage) (chatmodels.ModerationEvent, bool) {
+	if item.Snippet == nil {
+		return chatmodels.ModerationEvent{}, false
+	}
+
+	message := y.newChatMessage(item)
+	event := chatmodels.ModerationEvent{
+		Provider:          message.Provider,
+		ProviderShortName: message.ProviderShortName,
+		Channel:           message.Channel,
+		Timestamp:         message.Timestamp,
+		Raw:               message.Raw,
+	}
+	// The author of these messages is the moderator, not the moderated user
+	event.Raw["moderatorChannelId"] = message.AuthorID
+	event.Raw["moderatorName"] = message.AuthorName
+
+	snippet := item.Snippet
+	switch {
+	case snippet.MessageDeletedDetails != nil:
+		event.Action = chatmodels.ModerationDeleteMessage
+		event.MessageID = snippet.MessageDeletedDetails.DeletedMessageId
+	case snippet.MessageRetractedDetails != nil:
+		event.Action = chatmodels.ModerationDeleteMessage
+		event.MessageID = snippet.MessageRetractedDetails.RetractedMessageId
+	case snippet.UserBannedDetails != nil:
+		details := snippet.UserBannedDetails
+		event.Action = chatmodels.ModerationBan
+		if details.BanType == "temporary" {
+			event.Action = chatmodels.ModerationTimeout
+			event.Duration = time.Duration(details.BanDurationSeconds) * time.Second
+		}
+		if details.BannedUserDetails != nil {
+			event.AuthorID = details.BannedUserDetails.ChannelId
+			event.AuthorName = details.BannedUserDetails.DisplayName
+		}
+	default:
+		return chatmodels.ModerationEvent{}, false
+	}
+
+	return event, true
+}
+
+// DeleteMessage deletes a live chat message with the OAuth account, which must be a moderator.
+func (y *YoutubeProvider) DeleteMessage(ctx context.Context, messageID string) error {
+	service, _, err := y.oauthService("moderate")
+	if err != nil {
+		return err
+	}
+	if err := service.LiveChatMessages.Delete(messageID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("error deleting live chat message: %v", err)
+	}
+	return nil
+}
+
+// TimeoutUser bans a channel from the live chat for the given duration.
+// YouTube does not support a reason, it is ignored.
+func (y *YoutubeProvider) TimeoutUser(ctx context.Context, userID string, duration time.Duration, reason string) error {
+	if duration < time.Second {
+		return fmt.Errorf("youtube timeouts must last at least one second")
+	}
+	return y.ban(ctx, userID, "temporary", duration)
+}
+
+// BanUser permanently bans a channel from the live chat.
+// YouTube does not support a reason, it is ignored.
+func (y *YoutubeProvider) BanUser(ctx context.Context, userID string, reason string) error {
+	return y.ban(ctx, userID, "permanent", 0)
+}
+
+// UnbanUser lifts a ban or a timeout. The API removes bans by their ID, so only bans issued
+// by this provider can be lifted.
+func (y *YoutubeProvider) UnbanUser(ctx context.Context, userID string) error {
+	service, _, err := y.oauthService("moderate")
+	if err != nil {
+		return err
+	}
+
+	y.bansMux.Lock()
+	banID, ok := y.bans[userID]
+	y.bansMux.Unlock()
+	if !ok {
+		return fmt.Errorf("no ban issued by this client for youtube channel %s", userID)
+	}
+
+	if err := service.LiveChatBans.Delete(banID).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("error removing live chat ban: %v", err)
+	}
+
+	y.bansMux.Lock()
+	delete(y.bans, userID)
+	y.bansMux.Unlock()
+	return nil
+}
+
+func (y *YoutubeProvider) ban(ctx context.Context, userID string, banType string, duration time.Duration) error {
+	service, liveChatId, err := y.oauthService("moderate")
+	if err != nil {
+		return err
+	}
+
+	ban, err := service.LiveChatBans.Insert([]string{"snippet"}, &youtube.LiveChatBan{
+		Snippet: &youtube.LiveChatBanSnippet{
+			LiveChatId:         liveChatId,
+			Type:               banType,
+			BanDurationSeconds: uint64(duration.Seconds()),
+			BannedUserDetails: &youtube.ChannelProfileDetails{
+				ChannelId: userID,
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error banning from live chat: %v", err)
+	}
+
+	y.bansMux.Lock()
+	if y.bans == nil {
+		y.bans = make(map[string]string)
+	}
+	y.bans[userID] = ban.Id
+	y.bansMux.Unlock()
+	return nil
+}
@@ -3,83 +3,121 @@ package youtube
 import (
 	"context"
 	"fmt"
-	"log"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/SergioCurto/ChatClient/config"
 	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/endpoints"
 	"google.golang.org/api/option"
 	"google.golang.org/api/youtube/v3"
 )
 
 type YoutubeProvider struct {
-	Name          string
-	ShortName     string
-	apiKey        string
-	channelId     string
-	queriesPerDay int
-	service       *youtube.Service
-	liveChatId    string
-	nextPage      string
-	nextPoll      time.Duration
+	Name       string
+	ShortName  string
+	cfg        config.YoutubeConfig
+	service    *youtube.Service
+	liveChatId string
+	nextPage   string
+	nextPoll   time.Duration
+	// authenticated is true when the service uses OAuth and can send messages
+	authenticated bool
+	serviceMux    sync.RWMutex
+	// bans maps the banned channel IDs to the ban IDs returned by the API, needed to unban
+	bans       map[string]string
+	bansMux    sync.Mutex
+	events     chan<- chatmodels.ChatEvent
+	moderation chan<- chatmodels.ModerationEvent
+	// gifters maps the channel IDs of the recent gifters to their names, the memberships they
+	// gift are received in later messages that only have the channel ID of the gifter
+	gifters map[string]string
 }
 
-func NewYoutubeProvider() *YoutubeProvider {
+// defaultQueriesPerDay is the daily quota of a Youtube API project.
+const defaultQueriesPerDay = 10000
 
-	return &YoutubeProvider{
+// NewYoutubeProvider creates a provider for the channel of the instance configuration.
+// The instance name and short name default to Youtube and Yt.
+func NewYoutubeProvider(instance config.ProviderConfig) *YoutubeProvider {
+	provider := &YoutubeProvider{
 		Name:      "Youtube",
 		ShortName: "Yt",
+		cfg:       instance.Youtube,
 	}
+	if instance.Name != "" {
+		provider.Name = instance.Name
+	}
+	if instance.ShortName != "" {
+		provider.ShortName = instance.ShortName
+	}
+	if provider.cfg.QueriesPerDay <= 0 {
+		provider.cfg.QueriesPerDay = defaultQueriesPerDay
+	}
+	return provider
 }
 
-func (y *YoutubeProvider) Connect(cfx *config.Config) error {
-	fmt.Println("Connecting to Youtube with API Key...")
+func (y *YoutubeProvider) Connect(ctx context.Context) error {
+	// OAuth is required to send messages, when configured it is used for every call
+	authenticated := y.cfg.OAuthClientId != "" && y.cfg.OAuthClientSecret != "" && y.cfg.OAuthRefreshToken != ""
 
-	y.apiKey = cfx.YoutubeApiKey
-	y.channelId = cfx.YoutubeChannelId
-	y.queriesPerDay = cfx.YoutubeQueriesPerDay
+	if (y.cfg.ApiKey == "" && !authenticated) || y.cfg.ChannelId == "" {
+		return fmt.Errorf("missing youtube API key or channel ID for provider %s", y.Name)
+	}
 
-	if y.apiKey == "" || y.channelId == "" {
-		return fmt.Errorf("missing YOUTUBE_API_KEY or YOUTUBE_CHANNEL_ID in environment variables")
+	clientOption := option.WithAPIKey(y.cfg.ApiKey)
+	if authenticated {
+		fmt.Printf("Connecting to Youtube (%s) with OAuth...\n", y.Name)
+		oauthConfig := &oauth2.Config{
+			ClientID:     y.cfg.OAuthClientId,
+			ClientSecret: y.cfg.OAuthClientSecret,
+			Endpoint:     endpoints.Google,
+			Scopes:       []string{youtube.YoutubeForceSslScope},
+		}
+		clientOption = option.WithTokenSource(oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: y.cfg.OAuthRefreshToken}))
+	} else {
+		fmt.Printf("Connecting to Youtube (%s) with API Key...\n", y.Name)
 	}
 
 	// Create a new YouTube service client.
-	ctx := context.Background()
 	service, err := youtube.NewService(
 		ctx,
-		option.WithAPIKey(y.apiKey),
+		clientOption,
 	)
 	if err != nil {
 		return fmt.Errorf("error creating YouTube service: %v", err)
 	}
-	y.service = service
 
 	// Step 1: Find the Active Live Broadcast
-	searchCall := y.service.Search.List([]string{"id", "snippet"}).
-		ChannelId(y.channelId).
+	searchCall := service.Search.List([]string{"id", "snippet"}).
+		ChannelId(y.cfg.ChannelId).
 		EventType("live").
 		Type("video").
 		MaxResults(1)
 
-	searchResponse, err := searchCall.Do()
+	searchResponse, err := searchCall.Context(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("error searching for live broadcasts: %v", err)
 	}
 
 	if len(searchResponse.Items) == 0 {
-		return fmt.Errorf("no active live broadcasts found for channel %s", y.channelId)
+		return fmt.Errorf("no active live broadcasts found for channel %s", y.cfg.ChannelId)
 	}
 
 	liveVideoId := searchResponse.Items[0].Id.VideoId
 	if liveVideoId == "" {
-		return fmt.Errorf("no live video id found for channel %s", y.channelId)
+		return fmt.Errorf("no live video id found for channel %s", y.cfg.ChannelId)
 	}
 
 	// Step 2: Get the Live Chat ID using the Live Video ID
-	videoCall := y.service.Videos.List([]string{"liveStreamingDetails"}).
+	videoCall := service.Videos.List([]string{"liveStreamingDetails"}).
 		Id(liveVideoId)
 
-	videoResponse, err := videoCall.Do()
+	videoResponse, err := videoCall.Context(ctx).Do()
 	if err != nil {
 		return fmt.Errorf("error getting live video details: %v", err)
 	}
@@ -92,12 +130,21 @@ func (y *YoutubeProvider) Connect(cfx *config.Config) error {
 		return fmt.Errorf("no live streaming details found for video %s", liveVideoId)
 	}
 
-	y.liveChatId = videoResponse.Items[0].LiveStreamingDetails.ActiveLiveChatId
-	if y.liveChatId == "" {
+	liveChatId := videoResponse.Items[0].LiveStreamingDetails.ActiveLiveChatId
+	if liveChatId == "" {
 		return fmt.Errorf("no live chat found for video %s", liveVideoId)
 	}
 
-	fmt.Println("Live Chat ID:", y.liveChatId)
+	fmt.Println("Live Chat ID:", liveChatId)
+
+	y.serviceMux.Lock()
+	y.service = service
+	y.liveChatId = liveChatId
+	y.authenticated = authenticated
+	y.serviceMux.Unlock()
+
+	// A new connection may be on a different broadcast, start reading from its beginning
+	y.nextPage = ""
 
 	return nil
 }
@@ -108,49 +155,215 @@ func (y *YoutubeProvider) Disconnect() error {
 	return nil
 }
 
-func (y *YoutubeProvider) Listen(messages chan<- chatmodels.ChatMessage) error {
-	// Start listening for messages in a goroutine.
-	go func(y *YoutubeProvider) {
-		for {
-			// Get the live chat messages.
-			call := y.service.LiveChatMessages.List(y.liveChatId, []string{"snippet", "authorDetails"}).MaxResults(2000)
-			if y.nextPage != "" {
-				call = call.PageToken(y.nextPage)
-			}
-			response, err := call.Do()
-			if err != nil {
-				log.Printf("Error getting live chat messages: %v", err)
-				return
+// SetEventsChannel sets where Super Chats, Super Stickers and memberships are published.
+func (y *YoutubeProvider) SetEventsChannel(events chan<- chatmodels.ChatEvent) {
+	y.events = events
+}
+
+// SetModerationChannel sets where deleted messages and banned users are published.
+func (y *YoutubeProvider) SetModerationChannel(moderation chan<- chatmodels.ModerationEvent) {
+	y.moderation = moderation
+}
+
+// SendMessage posts a message to the live chat with the OAuth account.
+func (y *YoutubeProvider) SendMessage(ctx context.Context, text string) error {
+	service, liveChatId, err := y.oauthService("send messages")
+	if err != nil {
+		return err
+	}
+
+	_, err = service.LiveChatMessages.Insert([]string{"snippet"}, &youtube.LiveChatMessage{
+		Snippet: &youtube.LiveChatMessageSnippet{
+			LiveChatId: liveChatId,
+			Type:       "textMessageEvent",
+			TextMessageDetails: &youtube.LiveChatTextMessageDetails{
+				MessageText: text,
+			},
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("error sending live chat message: %v", err)
+	}
+	return nil
+}
+
+// oauthService returns the connected service and live chat, failing when OAuth is not configured.
+func (y *YoutubeProvider) oauthService(action string) (*youtube.Service, string, error) {
+	y.serviceMux.RLock()
+	defer y.serviceMux.RUnlock()
+
+	if y.service == nil || y.liveChatId == "" {
+		return nil, "", fmt.Errorf("youtube provider is not connected")
+	}
+	if !y.authenticated {
+		return nil, "", fmt.Errorf("youtube provider uses an API key, set the YOUTUBE_OAUTH_* variables to %s", action)
+	}
+	return y.service, y.liveChatId, nil
+}
+
+func (y *YoutubeProvider) Listen(ctx context.Context, messages chan<- chatmodels.ChatMessage) error {
+	y.serviceMux.RLock()
+	service, liveChatId := y.service, y.liveChatId
+	y.serviceMux.RUnlock()
+
+	if service == nil || liveChatId == "" {
+		return fmt.Errorf("youtube provider is not connected")
+	}
+
+	for {
+		// Get the live chat messages.
+		call := service.LiveChatMessages.List(liveChatId, []string{"snippet", "authorDetails"}).MaxResults(2000)
+		if y.nextPage != "" {
+			call = call.PageToken(y.nextPage)
+		}
+		response, err := call.Context(ctx).Do()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
 			}
+			return fmt.Errorf("error getting live chat messages: %v", err)
+		}
 
-			y.nextPage = response.NextPageToken
-			pollingInterval := time.Duration(response.PollingIntervalMillis) * time.Millisecond
+		y.nextPage = response.NextPageToken
+		pollingInterval := time.Duration(response.PollingIntervalMillis) * time.Millisecond
 
-			/* Youtube API is bad, and for multiple years did not implement a push based messaging system.
-			   To overcome this we need to reduce the pooling rate based on the limits that the API key has.
-			   See https://issuetracker.google.com/issues/35205195 */
-			// Calculate the minimum polling interval based on queriesPerDay
-			minPollingInterval := 24 * time.Hour / time.Duration(y.queriesPerDay)
-			if pollingInterval < minPollingInterval {
-				pollingInterval = minPollingInterval
+		/* Youtube API is bad, and for multiple years did not implement a push based messaging system.
+		   To overcome this we need to reduce the pooling rate based on the limits that the API key has.
+		   See https://issuetracker.google.com/issues/35205195 */
+		// Calculate the minimum polling interval based on queriesPerDay
+		minPollingInterval := 24 * time.Hour / time.Duration(y.cfg.QueriesPerDay)
+		if pollingInterval < minPollingInterval {
+			pollingInterval = minPollingInterval
+		}
+		y.nextPoll = pollingInterval
+
+		// Process the messages.
+		for _, item := range response.Items {
+			if event, ok := y.newChatEvent(item); ok {
+				chatmodels.Publish(ctx, y.events, event)
+			} else if moderation, ok := y.newModerationEvent(item); ok {
+				chatmodels.Publish(ctx, y.moderation, moderation)
+			} else {
+				chatmodels.Publish(ctx, messages, y.newChatMessage(item))
 			}
-			y.nextPoll = pollingInterval
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+
+		// Wait for the next poll interval.
+		select {
+		case <-time.After(y.nextPoll):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
 
-			// Process the messages.
-			for _, item := range response.Items {
+// newChatMessage converts a live chat message, with its author details, into a ChatMessage.
+func (y *YoutubeProvider) newChatMessage(item *youtube.LiveChatMessage) chatmodels.ChatMessage {
+	message := chatmodels.ChatMessage{
+		ID:                item.Id,
+		Provider:          y.GetName(),
+		ProviderShortName: y.GetShortName(),
+		Channel:           y.cfg.ChannelId,
+		Raw:               map[string]string{},
+	}
+
+	if item.Snippet != nil {
+		message.Content = item.Snippet.DisplayMessage
+		message.Fragments = fragments(item.Snippet.DisplayMessage)
+		message.AuthorID = item.Snippet.AuthorChannelId
+		message.Raw["type"] = item.Snippet.Type
+		message.Raw["liveChatId"] = item.Snippet.LiveChatId
+
+		publishedAt, err := time.Parse(time.RFC3339Nano, item.Snippet.PublishedAt)
+		if err == nil {
+			message.Timestamp = publishedAt
+		}
+	}
+	if message.Timestamp.IsZero() {
+		message.Timestamp = time.Now()
+	}
+
+	if item.AuthorDetails != nil {
+		author := item.AuthorDetails
+		message.AuthorName = author.DisplayName
+		if message.AuthorID == "" {
+			message.AuthorID = author.ChannelId
+		}
+		message.Raw["authorChannelUrl"] = author.ChannelUrl
+		message.Raw["authorProfileImageUrl"] = author.ProfileImageUrl
 
-				messages <- chatmodels.ChatMessage{
-					Provider:          y.GetName(),
-					ProviderShortName: y.GetShortName(),
-					Content:           item.Snippet.DisplayMessage,
-					AuthorName:        item.AuthorDetails.DisplayName,
-				}
+		// YouTube has no badge list, the author flags are what the chat shows as badges
+		for _, flag := range []struct {
+			set   bool
+			badge string
+			role  chatmodels.Role
+		}{
+			{author.IsChatOwner, "owner", chatmodels.RoleBroadcaster},
+			{author.IsChatModerator, "moderator", chatmodels.RoleModerator},
+			{author.IsChatSponsor, "member", chatmodels.RoleMember},
+			{author.IsVerified, "verified", chatmodels.RoleVerified},
+		} {
+			if flag.set {
+				message.Roles = append(message.Roles, flag.role)
+				message.Badges = append(message.Badges, chatmodels.Badge{Name: flag.badge, Version: "1"})
 			}
-			// Wait for the next poll interval.
-			time.Sleep(y.nextPoll)
 		}
-	}(y)
-	return nil
+	}
+
+	return message
+}
+
+// emojiShortcodes matches the :shortcode: text YouTube uses for standard and channel emojis,
+// e.g. :face-blue-smiling: or :_channelEmoji:.
+var emojiShortcodes = regexp.MustCompile(`:[A-Za-z_][A-Za-z0-9_-]*:`)
+
+// fragments splits the display message into links, mentions and emoji runs.
+// The Data API does not list the emojis of a message, only their shortcodes in the text, so a
+// shortcode is an emoji when it stands alone: between spaces, other shortcodes or the ends of
+// the text. Links are split out first, so the colons in them are kept. The emoji fragments
+// have no image and consumers show their text.
+func fragments(text string) []chatmodels.Fragment {
+	var result []chatmodels.Fragment
+	for _, fragment := range chatmodels.ParseTextFragments(text) {
+		if fragment.Type == chatmodels.FragmentText {
+			result = appendEmojis(result, fragment.Text)
+		} else {
+			result = append(result, fragment)
+		}
+	}
+	return result
+}
+
+// appendEmojis adds the text to the fragments, with its emoji shortcodes as emote fragments.
+func appendEmojis(result []chatmodels.Fragment, text string) []chatmodels.Fragment {
+	cursor := 0
+	for _, match := range emojiShortcodes.FindAllStringIndex(text, -1) {
+		// Times like 10:30:45 and words like a:b:c are text
+		if !isEmojiBoundary(text, match[0]-1) || !isEmojiBoundary(text, match[1]) {
+			continue
+		}
+		result = chatmodels.AppendText(result, text[cursor:match[0]])
+		shortcode := text[match[0]:match[1]]
+		result = append(result, chatmodels.Fragment{
+			Type:    chatmodels.FragmentEmote,
+			Text:    shortcode,
+			EmoteID: strings.Trim(shortcode, ":"),
+		})
+		cursor = match[1]
+	}
+	return chatmodels.AppendText(result, text[cursor:])
+}
+
+// isEmojiBoundary reports whether the byte at i can be next to a shortcode: a space, the colon
+// of another shortcode or outside the text.
+func isEmojiBoundary(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return true
+	}
+	return text[i] == ':' || unicode.IsSpace(rune(text[i]))
 }
 
 func (y *YoutubeProvider) GetName() string {
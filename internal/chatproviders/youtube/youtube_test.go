@@ -0,0 +1,268 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/config"
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+	"google.golang.org/api/youtube/v3"
+)
+
+func TestYoutubeProvider_NewChatMessage(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{Name: "Youtube", Youtube: config.YoutubeConfig{ChannelId: "UC123"}})
+
+	message := provider.newChatMessage(&youtube.LiveChatMessage{
+		Id: "msg-1",
+		Snippet: &youtube.LiveChatMessageSnippet{
+			Type:            "textMessageEvent",
+			LiveChatId:      "chat-1",
+			AuthorChannelId: "UCauthor",
+			DisplayMessage:  "Hello from YouTube",
+			PublishedAt:     "2025-03-20T18:30:15.123+00:00",
+		},
+		AuthorDetails: &youtube.LiveChatMessageAuthorDetails{
+			ChannelId:       "UCauthor",
+			DisplayName:     "Viewer",
+			IsChatModerator: true,
+			IsChatSponsor:   true,
+			ProfileImageUrl: "https://yt.example/avatar.jpg",
+		},
+	})
+
+	assert.Equal(t, "msg-1", message.ID)
+	assert.Equal(t, "Youtube", message.Provider)
+	assert.Equal(t, "Yt", message.ProviderShortName)
+	assert.Equal(t, "UC123", message.Channel)
+	assert.Equal(t, time.Date(2025, 3, 20, 18, 30, 15, 123000000, time.UTC), message.Timestamp.UTC())
+	assert.Equal(t, "Hello from YouTube", message.Content)
+	assert.Equal(t, "UCauthor", message.AuthorID)
+	assert.Equal(t, "Viewer", message.AuthorName)
+	assert.Equal(t, []chatmodels.Role{chatmodels.RoleModerator, chatmodels.RoleMember}, message.Roles)
+	assert.Equal(t, []chatmodels.Badge{{Name: "moderator", Version: "1"}, {Name: "member", Version: "1"}}, message.Badges)
+	assert.Equal(t, "textMessageEvent", message.Raw["type"])
+	assert.Equal(t, "https://yt.example/avatar.jpg", message.Raw["authorProfileImageUrl"])
+}
+
+func TestYoutubeProvider_NewChatMessage_MissingDetails(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+
+	before := time.Now()
+	message := provider.newChatMessage(&youtube.LiveChatMessage{Id: "msg-2"})
+
+	assert.Equal(t, "msg-2", message.ID)
+	assert.False(t, message.Timestamp.Before(before))
+	assert.Empty(t, message.Roles)
+}
+
+func TestFragments_EmojiRuns(t *testing.T) {
+	result := fragments("hi :face-blue-smiling: @Host https://youtu.be/x")
+
+	assert.Equal(t, []chatmodels.Fragment{
+		{Type: chatmodels.FragmentText, Text: "hi "},
+		{Type: chatmodels.FragmentEmote, Text: ":face-blue-smiling:", EmoteID: "face-blue-smiling"},
+		{Type: chatmodels.FragmentText, Text: " "},
+		{Type: chatmodels.FragmentMention, Text: "@Host", Mention: "Host"},
+		{Type: chatmodels.FragmentText, Text: " "},
+		{Type: chatmodels.FragmentURL, Text: "https://youtu.be/x", URL: "https://youtu.be/x"},
+	}, result)
+
+	// Adjacent shortcodes are emojis, the colons of times, words and links are text
+	tests := []struct {
+		text     string
+		expected []chatmodels.Fragment
+	}{
+		{":yt::_channelEmoji:", []chatmodels.Fragment{
+			{Type: chatmodels.FragmentEmote, Text: ":yt:", EmoteID: "yt"},
+			{Type: chatmodels.FragmentEmote, Text: ":_channelEmoji:", EmoteID: "_channelEmoji"},
+		}},
+		{"starts at 10:30:45", []chatmodels.Fragment{{Type: chatmodels.FragmentText, Text: "starts at 10:30:45"}}},
+		{"a:b:c", []chatmodels.Fragment{{Type: chatmodels.FragmentText, Text: "a:b:c"}}},
+		{"see https://example.com/:clip:x :wave:", []chatmodels.Fragment{
+			{Type: chatmodels.FragmentText, Text: "see "},
+			{Type: chatmodels.FragmentURL, Text: "https://example.com/:clip:x", URL: "https://example.com/:clip:x"},
+			{Type: chatmodels.FragmentText, Text: " "},
+			{Type: chatmodels.FragmentEmote, Text: ":wave:", EmoteID: "wave"},
+		}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, fragments(test.text), test.text)
+	}
+}
+
+func TestYoutubeProvider_NewChatEvent_SuperChat(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+	event, ok := provider.newChatEvent(&youtube.LiveChatMessage{
+		Id: "sc-1",
+		Snippet: &youtube.LiveChatMessageSnippet{
+			Type:           "superChatEvent",
+			DisplayMessage: "$5.00 from Fan: great stream",
+			PublishedAt:    "2025-03-20T18:30:15Z",
+			SuperChatDetails: &youtube.LiveChatSuperChatDetails{
+				AmountMicros:        5000000,
+				AmountDisplayString: "$5.00",
+				Currency:            "USD",
+				Tier:                2,
+				UserComment:         "great stream",
+			},
+		},
+		AuthorDetails: &youtube.LiveChatMessageAuthorDetails{ChannelId: "UCfan", DisplayName: "Fan"},
+	})
+
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventSuperChat, event.Kind)
+	assert.Equal(t, "sc-1", event.ID)
+	assert.Equal(t, "Fan", event.AuthorName)
+	assert.Equal(t, 5.0, event.Amount)
+	assert.Equal(t, "USD", event.Currency)
+	assert.Equal(t, "2", event.Tier)
+	assert.Equal(t, "great stream", event.Message)
+	assert.Equal(t, "$5.00", event.Raw["amountDisplayString"])
+}
+
+func TestYoutubeProvider_NewChatEvent_Memberships(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+
+	event, ok := provider.newChatEvent(&youtube.LiveChatMessage{Snippet: &youtube.LiveChatMessageSnippet{
+		Type:                       "memberMilestoneChatEvent",
+		MemberMilestoneChatDetails: &youtube.LiveChatMemberMilestoneChatDetails{MemberLevelName: "Gold", MemberMonth: 6, UserComment: "half a year"},
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventResubscription, event.Kind)
+	assert.Equal(t, 6, event.Months)
+	assert.Equal(t, "Gold", event.Tier)
+
+	event, ok = provider.newChatEvent(&youtube.LiveChatMessage{
+		Snippet:       &youtube.LiveChatMessageSnippet{Type: "newSponsorEvent", NewSponsorDetails: &youtube.LiveChatNewSponsorDetails{MemberLevelName: "Silver"}},
+		AuthorDetails: &youtube.LiveChatMessageAuthorDetails{DisplayName: "Newbie"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventSubscription, event.Kind)
+	assert.Equal(t, "Newbie", event.AuthorName)
+
+	event, ok = provider.newChatEvent(&youtube.LiveChatMessage{
+		Snippet:       &youtube.LiveChatMessageSnippet{Type: "membershipGiftingEvent", MembershipGiftingDetails: &youtube.LiveChatMembershipGiftingDetails{GiftMembershipsCount: 10}},
+		AuthorDetails: &youtube.LiveChatMessageAuthorDetails{DisplayName: "Generous"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.EventCommunityGift, event.Kind)
+	assert.Equal(t, "Generous", event.Gifter)
+	assert.Equal(t, 10, event.RecipientCount)
+}
+
+func TestYoutubeProvider_NewChatEvent_GiftReceived(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+	received := func(gifterChannelID string) chatmodels.ChatEvent {
+		event, ok := provider.newChatEvent(&youtube.LiveChatMessage{
+			Snippet: &youtube.LiveChatMessageSnippet{
+				Type:                          "giftMembershipReceivedEvent",
+				GiftMembershipReceivedDetails: &youtube.LiveChatGiftMembershipReceivedDetails{GifterChannelId: gifterChannelID, MemberLevelName: "Gold"},
+			},
+			AuthorDetails: &youtube.LiveChatMessageAuthorDetails{ChannelId: "UClucky", DisplayName: "Lucky"},
+		})
+		assert.True(t, ok)
+		assert.Equal(t, chatmodels.EventGiftSubscription, event.Kind)
+		return event
+	}
+
+	// The gifter is not known before its gifting message
+	event := received("UCgenerous")
+	assert.Empty(t, event.Gifter)
+	assert.Equal(t, "UCgenerous", event.Raw["gifterChannelId"])
+	assert.Equal(t, "Lucky received a gifted subscription (Gold)", event.Describe())
+
+	provider.newChatEvent(&youtube.LiveChatMessage{
+		Snippet:       &youtube.LiveChatMessageSnippet{Type: "membershipGiftingEvent", MembershipGiftingDetails: &youtube.LiveChatMembershipGiftingDetails{GiftMembershipsCount: 5}},
+		AuthorDetails: &youtube.LiveChatMessageAuthorDetails{ChannelId: "UCgenerous", DisplayName: "Generous"},
+	})
+	event = received("UCgenerous")
+	assert.Equal(t, "Generous", event.Gifter)
+	assert.Equal(t, "Lucky", event.Recipient)
+	assert.Equal(t, "Generous gifted a subscription to Lucky (Gold)", event.Describe())
+}
+
+func TestYoutubeProvider_NewChatEvent_TextMessage(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+	_, ok := provider.newChatEvent(&youtube.LiveChatMessage{Snippet: &youtube.LiveChatMessageSnippet{Type: "textMessageEvent", DisplayMessage: "hi"}})
+	assert.False(t, ok)
+}
+
+func TestYoutubeProvider_NewModerationEvent(t *testing.T) {
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+
+	event, ok := provider.newModerationEvent(&youtube.LiveChatMessage{
+		Snippet:       &youtube.LiveChatMessageSnippet{Type: "messageDeletedEvent", MessageDeletedDetails: &youtube.LiveChatMessageDeletedDetails{DeletedMessageId: "msg-9"}},
+		AuthorDetails: &youtube.LiveChatMessageAuthorDetails{ChannelId: "UCmod", DisplayName: "Mod"},
+	})
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.ModerationDeleteMessage, event.Action)
+	assert.Equal(t, "msg-9", event.MessageID)
+	assert.Equal(t, "Mod", event.Raw["moderatorName"])
+
+	event, ok = provider.newModerationEvent(&youtube.LiveChatMessage{Snippet: &youtube.LiveChatMessageSnippet{
+		Type: "userBannedEvent",
+		UserBannedDetails: &youtube.LiveChatUserBannedMessageDetails{
+			BanType:            "temporary",
+			BanDurationSeconds: 300,
+			BannedUserDetails:  &youtube.ChannelProfileDetails{ChannelId: "UCtroll", DisplayName: "Troll"},
+		},
+	}})
+	assert.True(t, ok)
+	assert.Equal(t, chatmodels.ModerationTimeout, event.Action)
+	assert.Equal(t, 5*time.Minute, event.Duration)
+	assert.Equal(t, "UCtroll", event.AuthorID)
+	assert.Equal(t, "Troll", event.AuthorName)
+
+	_, ok = provider.newModerationEvent(&youtube.LiveChatMessage{Snippet: &youtube.LiveChatMessageSnippet{Type: "textMessageEvent"}})
+	assert.False(t, ok)
+}
+
+func TestYoutubeProvider_BanAndUnbanUser(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.URL.Query().Get("id"))
+		if r.Method == http.MethodPost {
+			var ban youtube.LiveChatBan
+			json.NewDecoder(r.Body).Decode(&ban)
+			assert.Equal(t, "chat-id", ban.Snippet.LiveChatId)
+			assert.Equal(t, "temporary", ban.Snippet.Type)
+			assert.Equal(t, uint64(300), ban.Snippet.BanDurationSeconds)
+			assert.Equal(t, "UC42", ban.Snippet.BannedUserDetails.ChannelId)
+			fmt.Fprint(w, `{"id":"ban-id"}`)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service, err := youtube.NewService(context.Background(), option.WithEndpoint(server.URL+"/"), option.WithoutAuthentication())
+	assert.NoError(t, err)
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+	provider.service = service
+	provider.liveChatId = "chat-id"
+	provider.authenticated = true
+
+	assert.NoError(t, provider.TimeoutUser(context.Background(), "UC42", 5*time.Minute, "ignored"))
+	assert.NoError(t, provider.UnbanUser(context.Background(), "UC42"))
+	assert.EqualError(t, provider.UnbanUser(context.Background(), "UC42"), "no ban issued by this client for youtube channel UC42")
+
+	assert.Equal(t, []string{"POST /youtube/v3/liveChat/bans ", "DELETE /youtube/v3/liveChat/bans ban-id"}, requests)
+}
+
+func TestYoutubeProvider_Moderation_RequiresOAuth(t *testing.T) {
+	service, err := youtube.NewService(context.Background(), option.WithAPIKey("key"))
+	assert.NoError(t, err)
+	provider := NewYoutubeProvider(config.ProviderConfig{})
+	provider.service = service
+	provider.liveChatId = "chat-id"
+
+	err = provider.DeleteMessage(context.Background(), "message-id")
+	assert.EqualError(t, err, "youtube provider uses an API key, set the YOUTUBE_OAUTH_* variables to moderate")
+}
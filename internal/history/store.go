@@ -0,0 +1,263 @@
+package history
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	_ "modernc.org/sqlite"
+)
+
+// migrations create and update the database schema, the schema version is the number of
+// migrations applied and is kept in the user_version pragma.
+var migrations = []string{
+	`CREATE TABLE messages (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		channel TEXT NOT NULL,
+		timestamp INTEGER NOT NULL,
+		author_id TEXT NOT NULL,
+		author_name TEXT NOT NULL,
+		content TEXT NOT NULL,
+		deleted INTEGER NOT NULL DEFAULT 0,
+		message TEXT NOT NULL
+	);
+	CREATE INDEX messages_timestamp ON messages (timestamp);
+	CREATE INDEX messages_provider ON messages (provider, timestamp);
+	CREATE INDEX messages_author ON messages (author_name COLLATE NOCASE, timestamp);`,
+}
+
+// Store persists the chat messages in an embedded SQLite database, so the history survives
+// restarts and can be searched. It is safe for concurrent use.
+type Store struct {
+	db *sql.DB
+}
+
+// Entry is a stored message. ID increases with every message saved, it is used as a cursor.
+type Entry struct {
+	ID      int64
+	Message chatmodels.ChatMessage
+}
+
+// Open opens the database at path, creating it when missing, and updates its schema.
+// The ":memory:" path opens a database that is lost on Close.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)")
+	if err != nil {
+		return nil, fmt.Errorf("error opening history: %w", err)
+	}
+	// A single connection serializes the writes and keeps in memory databases alive
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error opening history %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func migrate(db *sql.DB) error {
+	var version int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than this application", version)
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("error updating schema to version %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save stores a message and returns its entry ID.
+func (s *Store) Save(ctx context.Context, message chatmodels.ChatMessage) (int64, error) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO messages (message_id, provider, channel, timestamp, author_id, author_name, content, message)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		message.ID, message.Provider, message.Channel, message.Timestamp.UnixNano(),
+		message.AuthorID, message.AuthorName, message.Content, string(data))
+	if err != nil {
+		return 0, fmt.Errorf("error saving message: %w", err)
+	}
+	return result.LastInsertId()
+}
+
+// ApplyModeration marks the messages removed by the moderation event as deleted, following
+// the rules of ModerationEvent.Matches. It returns the number of messages deleted.
+func (s *Store) ApplyModeration(ctx context.Context, event chatmodels.ModerationEvent) (int64, error) {
+	conditions := []string{"deleted = 0", "provider = ?"}
+	args := []any{event.Provider}
+	if event.Channel != "" {
+		conditions = append(conditions, "(channel = '' OR channel = ?)")
+		args = append(args, event.Channel)
+	}
+
+	switch event.Action {
+	case chatmodels.ModerationDeleteMessage:
+		if event.MessageID == "" {
+			return 0, nil
+		}
+		conditions = append(conditions, "message_id = ?")
+		args = append(args, event.MessageID)
+	case chatmodels.ModerationTimeout, chatmodels.ModerationBan:
+		if event.AuthorID != "" {
+			conditions = append(conditions, "author_id = ?")
+			args = append(args, event.AuthorID)
+		} else if event.AuthorName != "" {
+			conditions = append(conditions, "author_name = ?")
+			args = append(args, event.AuthorName)
+		} else {
+			return 0, nil
+		}
+	case chatmodels.ModerationClearChat:
+	default:
+		return 0, nil
+	}
+
+	result, err := s.db.ExecContext(ctx, "UPDATE messages SET deleted = 1 WHERE "+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return 0, fmt.Errorf("error applying moderation: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// Query selects stored messages, the zero value fields are not used.
+type Query struct {
+	Provider string
+	// Author is the author name, case insensitive
+	Author string
+	// Text is searched in the content, case insensitive for ASCII letters
+	Text string
+	// From and To limit the message timestamps, To is exclusive
+	From time.Time
+	To   time.Time
+	// Before and After are entry IDs the results are older or newer than
+	Before int64
+	After  int64
+	// Limit is the maximum number of results, the most recent matches are returned unless
+	// Oldest is set
+	Limit  int
+	Oldest bool
+	// IncludeDeleted also returns the messages removed by the moderators
+	IncludeDeleted bool
+}
+
+// Query returns the messages matching the query, in the order they were saved.
+func (s *Store) Query(ctx context.Context, query Query) ([]Entry, error) {
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		conditions = append(conditions, condition)
+		args = append(args, arg)
+	}
+
+	if !query.IncludeDeleted {
+		conditions = append(conditions, "deleted = 0")
+	}
+	if query.Provider != "" {
+		where("provider = ?", query.Provider)
+	}
+	if query.Author != "" {
+		where("author_name = ? COLLATE NOCASE", query.Author)
+	}
+	if query.Text != "" {
+		where(`content LIKE ? ESCAPE '\'`, "%"+escapeLike(query.Text)+"%")
+	}
+	if !query.From.IsZero() {
+		where("timestamp >= ?", query.From.UnixNano())
+	}
+	if !query.To.IsZero() {
+		where("timestamp < ?", query.To.UnixNano())
+	}
+	if query.Before > 0 {
+		where("id < ?", query.Before)
+	}
+	if query.After > 0 {
+		where("id > ?", query.After)
+	}
+
+	statement := "SELECT id, message FROM messages"
+	if len(conditions) > 0 {
+		statement += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	if query.Oldest {
+		statement += " ORDER BY id"
+	} else {
+		statement += " ORDER BY id DESC"
+	}
+	if query.Limit > 0 {
+		statement += " LIMIT ?"
+		args = append(args, query.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error querying history: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var entry Entry
+		var data string
+		if err := rows.Scan(&entry.ID, &data); err != nil {
+			return nil, fmt.Errorf("error querying history: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &entry.Message); err != nil {
+			return nil, fmt.Errorf("error reading message %d: %w", entry.ID, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error querying history: %w", err)
+	}
+
+	if !query.Oldest {
+		slices.Reverse(entries)
+	}
+	return entries, nil
+}
+
+// Prune deletes the messages older than the given time and returns how many were deleted.
+func (s *Store) Prune(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM messages WHERE timestamp < ?", before.UnixNano())
+	if err != nil {
+		return 0, fmt.Errorf("error pruning history: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// escapeLike escapes the LIKE wildcards, so the text is searched as is.
+func escapeLike(text string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+}
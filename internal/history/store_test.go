@@ -0,0 +1,141 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SergioCurto/ChatClient/internal/chatmodels"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2025, 3, 20, 18, 30, 0, 0, time.UTC)
+
+func newStore(t *testing.T) *Store {
+	store, err := Open(":memory:")
+	assert.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	for i, message := range []chatmodels.ChatMessage{
+		{ID: "1", Provider: "Twitch", Channel: "streamer", AuthorID: "u1", AuthorName: "Viewer", Content: "Hello chat"},
+		{ID: "2", Provider: "Youtube", AuthorID: "UC2", AuthorName: "Fan", Content: "hello from youtube"},
+		{ID: "3", Provider: "Twitch", Channel: "streamer", AuthorID: "u1", AuthorName: "viewer", Content: "100% hype_train"},
+		{ID: "4", Provider: "Twitch", Channel: "streamer", AuthorID: "u3", AuthorName: "Lurker", Content: "gg"},
+	} {
+		message.Timestamp = start.Add(time.Duration(i) * time.Minute)
+		_, err := store.Save(context.Background(), message)
+		assert.NoError(t, err)
+	}
+	return store
+}
+
+func messageIDs(entries []Entry) []string {
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.Message.ID)
+	}
+	return ids
+}
+
+func TestStore_Query(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	for name, test := range map[string]struct {
+		query    Query
+		expected []string
+	}{
+		"all":        {Query{}, []string{"1", "2", "3", "4"}},
+		"provider":   {Query{Provider: "Youtube"}, []string{"2"}},
+		"author":     {Query{Author: "VIEWER"}, []string{"1", "3"}},
+		"text":       {Query{Text: "hello"}, []string{"1", "2"}},
+		"wildcards":  {Query{Text: "0% hype_"}, []string{"3"}},
+		"no match":   {Query{Text: "100 % hype"}, []string{}},
+		"time range": {Query{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)}, []string{"2", "3"}},
+		"latest":     {Query{Limit: 2}, []string{"3", "4"}},
+		"before":     {Query{Before: 3, Limit: 1}, []string{"2"}},
+		"after":      {Query{After: 1, Limit: 2}, []string{"3", "4"}},
+		"oldest":     {Query{After: 1, Limit: 2, Oldest: true}, []string{"2", "3"}},
+		"combined":   {Query{Provider: "Twitch", Author: "viewer", Text: "hype"}, []string{"3"}},
+	} {
+		entries, err := store.Query(ctx, test.query)
+		assert.NoError(t, err, name)
+		assert.Equal(t, test.expected, messageIDs(entries), name)
+	}
+}
+
+func TestStore_KeepsTheMessage(t *testing.T) {
+	store, err := Open(":memory:")
+	assert.NoError(t, err)
+	defer store.Close()
+
+	message := chatmodels.ChatMessage{
+		ID: "1", Provider: "Twitch", Timestamp: start, Content: "Kappa",
+		Fragments: []chatmodels.Fragment{{Type: chatmodels.FragmentEmote, Text: "Kappa", EmoteID: "25"}},
+		Roles:     []chatmodels.Role{chatmodels.RoleModerator},
+	}
+	id, err := store.Save(context.Background(), message)
+	assert.NoError(t, err)
+
+	entries, err := store.Query(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, id, entries[0].ID)
+	assert.Equal(t, message.Fragments, entries[0].Message.Fragments)
+	assert.Equal(t, message.Roles, entries[0].Message.Roles)
+	assert.True(t, message.Timestamp.Equal(entries[0].Message.Timestamp))
+}
+
+func TestStore_ApplyModeration(t *testing.T) {
+	store := newStore(t)
+	ctx := context.Background()
+
+	deleted, err := store.ApplyModeration(ctx, chatmodels.ModerationEvent{Provider: "Twitch", Action: chatmodels.ModerationDeleteMessage, MessageID: "4"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	deleted, err = store.ApplyModeration(ctx, chatmodels.ModerationEvent{Provider: "Twitch", Channel: "streamer", Action: chatmodels.ModerationBan, AuthorID: "u1"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	// The moderation of a provider does not remove the messages of the others
+	deleted, err = store.ApplyModeration(ctx, chatmodels.ModerationEvent{Provider: "Kick", Action: chatmodels.ModerationClearChat})
+	assert.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	entries, err := store.Query(ctx, Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2"}, messageIDs(entries))
+
+	entries, err = store.Query(ctx, Query{IncludeDeleted: true})
+	assert.NoError(t, err)
+	assert.Len(t, entries, 4)
+}
+
+func TestStore_Prune(t *testing.T) {
+	store := newStore(t)
+
+	pruned, err := store.Prune(context.Background(), start.Add(2*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), pruned)
+
+	entries, err := store.Query(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3", "4"}, messageIDs(entries))
+}
+
+func TestStore_PersistsAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path)
+	assert.NoError(t, err)
+	_, err = store.Save(context.Background(), chatmodels.ChatMessage{ID: "1", Timestamp: start})
+	assert.NoError(t, err)
+	assert.NoError(t, store.Close())
+
+	store, err = Open(path)
+	assert.NoError(t, err)
+	defer store.Close()
+	entries, err := store.Query(context.Background(), Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, messageIDs(entries))
+}
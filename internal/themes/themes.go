@@ -0,0 +1,44 @@
+// Package themes holds the built-in themes of the simple page, so the configuration validation
+// and the page agree on the theme names.
+package themes
+
+import (
+	"embed"
+	"io/fs"
+	"regexp"
+)
+
+// builtin holds a directory for every built-in theme
+//
+//go:embed builtin
+var builtin embed.FS
+
+// NamePattern matches the theme names, they are directory names used in URLs
+var NamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Builtin returns the names of the built-in themes, sorted.
+func Builtin() []string {
+	entries, _ := builtin.ReadDir("builtin")
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// Files returns the files of a built-in theme.
+func Files(name string) (fs.FS, bool) {
+	if !NamePattern.MatchString(name) {
+		return nil, false
+	}
+	files, err := fs.Sub(builtin, "builtin/"+name)
+	if err != nil {
+		return nil, false
+	}
+	if _, err := fs.Stat(files, "."); err != nil {
+		return nil, false
+	}
+	return files, true
+}
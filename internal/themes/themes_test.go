@@ -0,0 +1,24 @@
+package themes
+
+import (
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltin(t *testing.T) {
+	assert.Equal(t, []string{"bubbles", "classic", "marquee", "ticker"}, Builtin())
+}
+
+func TestFiles(t *testing.T) {
+	files, ok := Files("classic")
+	assert.True(t, ok)
+	_, err := fs.Stat(files, "theme.css")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"neon", "../builtin", ""} {
+		_, ok := Files(name)
+		assert.False(t, ok, name)
+	}
+}